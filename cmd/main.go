@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"go.uber.org/zap"
 	"log"
@@ -10,6 +12,8 @@ import (
 	"path/filepath"
 	"product-catalog/internal/config"
 	"product-catalog/internal/dependencies"
+	"product-catalog/internal/jobs"
+	"product-catalog/internal/transport/problem"
 )
 
 func main() {
@@ -22,8 +26,16 @@ func main() {
 	defer d.DBPool.Close()
 	defer d.Logger.Sync()
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	jobs.Start(ctx, d.Logger, d.Jobs...)
+
 	r := chi.NewRouter()
+	r.NotFound(problem.NotFound)
+	r.MethodNotAllowed(problem.MethodNotAllowed)
+	r.Use(middleware.RequestID)
 	r.Use(d.LoggingMiddleware.LoggingMiddleware)
+	r.Use(d.TenantMiddleware.TenantMiddleware)
 
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -32,12 +44,18 @@ func main() {
 
 	r.Mount("/users", d.UserHandler.Routes())
 	r.Mount("/products", d.ProductHandler.Routes())
+	r.Mount("/api-keys", d.APIKeyHandler.Routes())
+	r.Mount("/roles", d.RoleHandler.Routes())
+	r.Mount("/auth/oidc", d.SSOHandler.Routes())
+	r.Mount("/sessions", d.SessionHandler.Routes())
+	r.Mount("/impersonations", d.ImpersonationHandler.Routes())
+	r.Mount("/invitations", d.InvitationHandler.Routes())
 
 	r.Route("/docs", func(r chi.Router) {
 		r.Use(cors.Handler(cors.Options{
 			AllowedOrigins:   []string{"http://localhost:8080"},
 			AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-API-Key"},
 			ExposedHeaders:   []string{"Link"},
 			AllowCredentials: true,
 			MaxAge:           300,
@@ -0,0 +1,137 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"product-catalog/internal/adapters/oidc"
+	"product-catalog/internal/adapters/oidc/oidctest"
+)
+
+const redirectURL = "http://localhost:1488/auth/oidc/test/callback"
+
+func newProvider(srv *oidctest.Server) *oidc.Provider {
+	return oidc.NewProvider(oidc.Config{
+		Issuer:      srv.Issuer(),
+		ClientID:    srv.ClientID,
+		RedirectURL: redirectURL,
+	}, srv.Client())
+}
+
+// authorize проходит шаг авторизации у мок-провайдера и возвращает code из редиректа.
+func authorize(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d", resp.StatusCode)
+	}
+	loc, _ := url.Parse(resp.Header.Get("Location"))
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+func TestAuthorizationCodeFlowWithPKCE(t *testing.T) {
+	ctx := context.Background()
+	srv := oidctest.NewServer(t, "catalog")
+	p := newProvider(srv)
+
+	verifier, _ := oidc.RandomToken()
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", oidc.CodeChallenge(verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	if !strings.HasPrefix(authURL, srv.URL+"/authorize?") {
+		t.Fatalf("unexpected auth url %s", authURL)
+	}
+
+	code, state := authorize(t, authURL)
+	if state != "state-1" {
+		t.Fatalf("state = %q, want state-1", state)
+	}
+
+	rawIDToken, err := p.Exchange(ctx, code, verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	profile, err := p.VerifyIDToken(ctx, rawIDToken, "nonce-1")
+	if err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	if profile.Subject != srv.User.Subject || profile.Email != srv.User.Email || !profile.EmailVerified {
+		t.Errorf("unexpected profile %+v", profile)
+	}
+
+	if _, err = p.VerifyIDToken(ctx, rawIDToken, "other-nonce"); err == nil {
+		t.Error("expected nonce mismatch to be rejected")
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	ctx := context.Background()
+	srv := oidctest.NewServer(t, "catalog")
+	p := newProvider(srv)
+
+	verifier, _ := oidc.RandomToken()
+	authURL, _ := p.AuthCodeURL(ctx, "s", "n", oidc.CodeChallenge(verifier))
+	code, _ := authorize(t, authURL)
+
+	if _, err := p.Exchange(ctx, code, "wrong-verifier"); err == nil {
+		t.Fatal("expected exchange with wrong code_verifier to fail")
+	}
+}
+
+func TestVerifyIDTokenRejectsBadClaims(t *testing.T) {
+	ctx := context.Background()
+	srv := oidctest.NewServer(t, "catalog")
+	p := newProvider(srv)
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   srv.Issuer(),
+			"sub":   "sub-1",
+			"aud":   "catalog",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": "n",
+		}
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(jwt.MapClaims)
+	}{
+		{"wrong audience", func(c jwt.MapClaims) { c["aud"] = "someone-else" }},
+		{"wrong issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{"no subject", func(c jwt.MapClaims) { delete(c, "sub") }},
+	}
+
+	if _, err := p.VerifyIDToken(ctx, srv.SignIDToken(valid()), "n"); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.mutate(c)
+			if _, err := p.VerifyIDToken(ctx, srv.SignIDToken(c), "n"); err == nil {
+				t.Error("expected token to be rejected")
+			}
+		})
+	}
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, valid())
+	raw, _ := forged.SignedString([]byte("secret"))
+	if _, err := p.VerifyIDToken(ctx, raw, "n"); err == nil {
+		t.Error("expected HS256 token to be rejected")
+	}
+}
@@ -108,3 +108,35 @@ func (m *MinioStorage) GetPresignedURL(ctx context.Context, key string, expiry t
 	}
 	return presignedURL.String(), nil
 }
+
+func (m *MinioStorage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := m.client.GetObject(ctx, m.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+	// GetObject ленивый: ошибки вроде NoSuchKey всплывают только при первом обращении
+	if _, err = obj.Stat(); err != nil {
+		obj.Close()
+		return nil, fmt.Errorf("failed to stat object: %w", err)
+	}
+	return obj, nil
+}
+
+// Exists сообщает, есть ли объект в бакете; отсутствие ключа ошибкой не считается
+func (m *MinioStorage) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := m.client.StatObject(ctx, m.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to stat object: %w", err)
+	}
+	return true, nil
+}
+
+func (m *MinioStorage) Delete(ctx context.Context, key string) error {
+	if err := m.client.RemoveObject(ctx, m.bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to remove object: %w", err)
+	}
+	return nil
+}
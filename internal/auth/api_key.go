@@ -0,0 +1,18 @@
+package auth
+
+import (
+	"context"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+type APIKeyIdentity struct {
+	KeyID       int
+	UserID      int
+	Role        Role
+	Permissions Permissions
+}
+
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*APIKeyIdentity, error)
+}
@@ -0,0 +1,75 @@
+package auth_test
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"product-catalog/internal/auth"
+)
+
+// Слабые параметры, чтобы тесты выполнялись быстро.
+var testParams = auth.Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2HashAndCompare(t *testing.T) {
+	h := auth.NewArgon2Hasher(testParams)
+
+	hash, err := h.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("unexpected PHC string %q", hash)
+	}
+	if err = h.Compare(hash, "correct horse battery staple"); err != nil {
+		t.Fatalf("Compare valid: %v", err)
+	}
+	if err = h.Compare(hash, "wrong"); err == nil {
+		t.Fatal("Compare accepted wrong password")
+	}
+	if h.NeedsRehash(hash) {
+		t.Fatal("fresh hash reported as outdated")
+	}
+
+	other, _ := h.Hash("correct horse battery staple")
+	if other == hash {
+		t.Fatal("hashes must use random salt")
+	}
+}
+
+func TestArgon2NeedsRehash(t *testing.T) {
+	current := auth.NewArgon2Hasher(testParams)
+	stronger := testParams
+	stronger.Iterations = 2
+	upgraded := auth.NewArgon2Hasher(stronger)
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("legacy-password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+	oldHash, _ := current.Hash("s3cret-pass")
+
+	tests := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		{"bcrypt", string(legacy), true},
+		{"weaker params", oldHash, true},
+		{"garbage", "$argon2id$v=19$broken", true},
+	}
+	for _, tt := range tests {
+		if got := upgraded.NeedsRehash(tt.hash); got != tt.want {
+			t.Errorf("%s: NeedsRehash = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Старые хэши по-прежнему проверяются, чтобы их можно было обновить при входе.
+	if err = upgraded.Compare(string(legacy), "legacy-password"); err != nil {
+		t.Fatalf("bcrypt compare: %v", err)
+	}
+	if err = upgraded.Compare(oldHash, "s3cret-pass"); err != nil {
+		t.Fatalf("old params compare: %v", err)
+	}
+}
@@ -4,8 +4,21 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const PurposeTwoFactorChallenge = "2fa_challenge"
+
 type JWTClaims struct {
-	UserID int  `json:"user_id"`
-	Role   Role `json:"role"`
+	UserID      int         `json:"user_id"`
+	Role        Role        `json:"role"`
+	Permissions Permissions `json:"perms,omitempty"`
+	Purpose     string      `json:"purpose,omitempty"`
+	SessionID   string      `json:"sid,omitempty"`
+	// TenantID — каталог, в котором выдан токен; вне его токен недействителен
+	TenantID int `json:"tid"`
+	// Actor заполнен у токенов имперсонации: это администратор, действующий от имени UserID
+	Actor *Actor `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
+
+type Actor struct {
+	UserID int `json:"user_id"`
+}
@@ -0,0 +1,14 @@
+package auth
+
+import (
+	"net"
+	"net/http"
+)
+
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
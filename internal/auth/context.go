@@ -5,16 +5,47 @@ import "context"
 type ctxKey string
 
 const (
-	RoleCtxKey   ctxKey = "role"
-	UserIDCtxKey ctxKey = "userID"
+	RoleCtxKey        ctxKey = "role"
+	UserIDCtxKey      ctxKey = "userID"
+	PermissionsCtxKey ctxKey = "permissions"
+	APIKeyIDCtxKey    ctxKey = "apiKeyID"
+	SessionIDCtxKey   ctxKey = "sessionID"
+	ActorIDCtxKey     ctxKey = "actorID"
+	TenantIDCtxKey    ctxKey = "tenantID"
+	HostTenantCtxKey  ctxKey = "hostTenant"
 )
 
-func WithUserContext(ctx context.Context, userID int, role Role) context.Context {
+func WithUserContext(ctx context.Context, userID int, role Role, perms Permissions) context.Context {
 	ctx = context.WithValue(ctx, UserIDCtxKey, userID)
 	ctx = context.WithValue(ctx, RoleCtxKey, role)
+	ctx = context.WithValue(ctx, PermissionsCtxKey, perms)
 	return ctx
 }
 
+func WithAPIKey(ctx context.Context, keyID int) context.Context {
+	return context.WithValue(ctx, APIKeyIDCtxKey, keyID)
+}
+
+func WithSession(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, SessionIDCtxKey, sessionID)
+}
+
+func WithActor(ctx context.Context, actorID int) context.Context {
+	return context.WithValue(ctx, ActorIDCtxKey, actorID)
+}
+
+// WithTenant задаёт арендатора, в рамках которого выполняются запросы к БД.
+func WithTenant(ctx context.Context, tenantID int) context.Context {
+	return context.WithValue(ctx, TenantIDCtxKey, tenantID)
+}
+
+// WithHostTenant задаёт арендатора, определённого по заголовку Host. Такой
+// арендатор закреплён за запросом: токен другого арендатора на этом хосте не примется.
+func WithHostTenant(ctx context.Context, tenantID int) context.Context {
+	ctx = context.WithValue(ctx, HostTenantCtxKey, tenantID)
+	return WithTenant(ctx, tenantID)
+}
+
 func RoleFromContext(ctx context.Context) (Role, bool) {
 	role, ok := ctx.Value(RoleCtxKey).(Role)
 	return role, ok
@@ -24,3 +55,38 @@ func UserIDFromContext(ctx context.Context) (int, bool) {
 	id, ok := ctx.Value(UserIDCtxKey).(int)
 	return id, ok
 }
+
+func PermissionsFromContext(ctx context.Context) (Permissions, bool) {
+	perms, ok := ctx.Value(PermissionsCtxKey).(Permissions)
+	return perms, ok
+}
+
+// APIKeyIDFromContext возвращает ID API-ключа; ok == false для запросов с JWT.
+func APIKeyIDFromContext(ctx context.Context) (int, bool) {
+	id, ok := ctx.Value(APIKeyIDCtxKey).(int)
+	return id, ok
+}
+
+// SessionIDFromContext возвращает ID сессии текущего JWT; ok == false для API-ключей.
+func SessionIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(SessionIDCtxKey).(string)
+	return id, ok
+}
+
+// ActorIDFromContext возвращает ID администратора, если запрос выполняется под имперсонацией.
+func ActorIDFromContext(ctx context.Context) (int, bool) {
+	id, ok := ctx.Value(ActorIDCtxKey).(int)
+	return id, ok
+}
+
+// TenantIDFromContext возвращает арендатора запроса; ok == false, если он не определён.
+func TenantIDFromContext(ctx context.Context) (int, bool) {
+	id, ok := ctx.Value(TenantIDCtxKey).(int)
+	return id, ok
+}
+
+// HostTenantIDFromContext возвращает арендатора, закреплённого за хостом запроса.
+func HostTenantIDFromContext(ctx context.Context) (int, bool) {
+	id, ok := ctx.Value(HostTenantCtxKey).(int)
+	return id, ok
+}
@@ -1,29 +0,0 @@
-package auth
-
-import (
-	"fmt"
-	"golang.org/x/crypto/bcrypt"
-)
-
-type BcryptHasher struct{}
-
-func NewHasher() *BcryptHasher {
-	return &BcryptHasher{}
-}
-
-func (h *BcryptHasher) Hash(password string) (string, error) {
-	const cost = 12
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
-	if err != nil {
-		return "", fmt.Errorf("failed to hash password: %w", err)
-	}
-	return string(hash), nil
-}
-
-func (h *BcryptHasher) Compare(hashedPassword, password string) error {
-	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
-	if err != nil {
-		return fmt.Errorf("invalid password: %w", err)
-	}
-	return nil
-}
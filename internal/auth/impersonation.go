@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"product-catalog/internal/errors"
+	"product-catalog/internal/transport/problem"
+	"strconv"
+)
+
+// ImpersonatedByHeader выставляется в ответах на запросы под имперсонацией,
+// чтобы их было видно в логах и на клиенте.
+const ImpersonatedByHeader = "X-Impersonated-By"
+
+type ImpersonationEvent struct {
+	ActorID   int
+	UserID    int
+	SessionID string
+	Method    string
+	Path      string
+	IP        string
+}
+
+// ImpersonationAuditor фиксирует каждый запрос под имперсонацией. Запись создаётся
+// до выполнения запроса: если аудит недоступен, запрос не выполняется.
+type ImpersonationAuditor interface {
+	RecordImpersonatedRequest(ctx context.Context, event ImpersonationEvent) (int, error)
+	CompleteImpersonatedRequest(ctx context.Context, recordID, status int) error
+}
+
+func (m *Middleware) serveImpersonated(w http.ResponseWriter, r *http.Request, claims *JWTClaims, next http.Handler) {
+	ctx := WithActor(r.Context(), claims.Actor.UserID)
+	w.Header().Set(ImpersonatedByHeader, strconv.Itoa(claims.Actor.UserID))
+
+	recordID, err := m.auditor.RecordImpersonatedRequest(ctx, ImpersonationEvent{
+		ActorID:   claims.Actor.UserID,
+		UserID:    claims.UserID,
+		SessionID: claims.SessionID,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		IP:        ClientIP(r),
+	})
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	next.ServeHTTP(rec, r.WithContext(ctx))
+
+	// Ответ уже отправлен, поэтому ошибку фиксации статуса не возвращаем клиенту.
+	_ = m.auditor.CompleteImpersonatedRequest(context.WithoutCancel(ctx), recordID, rec.status)
+}
+
+// DenyImpersonation запрещает операцию для токенов имперсонации.
+func DenyImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, impersonating := ActorIDFromContext(r.Context()); impersonating {
+			problem.Error(w, r, errors.ErrForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
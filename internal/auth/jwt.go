@@ -7,6 +7,8 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const ChallengeTokenTTL = 5 * time.Minute
+
 type Manager struct {
 	secret   string
 	tokenTTL time.Duration
@@ -19,10 +21,13 @@ func NewJWTManager(secret string, ttl time.Duration) *Manager {
 	}
 }
 
-func (m *Manager) GenerateToken(userID int, role Role) (string, error) {
+func (m *Manager) GenerateToken(userID, tenantID int, role Role, perms Permissions, sessionID string) (string, error) {
 	claims := &JWTClaims{
-		UserID: userID,
-		Role:   role,
+		UserID:      userID,
+		TenantID:    tenantID,
+		Role:        role,
+		Permissions: perms,
+		SessionID:   sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.tokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -33,7 +38,68 @@ func (m *Manager) GenerateToken(userID int, role Role) (string, error) {
 	return token.SignedString([]byte(m.secret))
 }
 
+// GenerateImpersonationToken выдаёт короткоживущий токен пользователя userID
+// с claim act, указывающим на администратора actorID.
+func (m *Manager) GenerateImpersonationToken(userID, tenantID int, role Role, perms Permissions, sessionID string, actorID int, ttl time.Duration) (string, error) {
+	claims := &JWTClaims{
+		UserID:      userID,
+		TenantID:    tenantID,
+		Role:        role,
+		Permissions: perms,
+		SessionID:   sessionID,
+		Actor:       &Actor{UserID: actorID},
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(m.secret))
+}
+
+func (m *Manager) GenerateChallengeToken(userID, tenantID int) (string, error) {
+	claims := &JWTClaims{
+		UserID:   userID,
+		TenantID: tenantID,
+		Purpose:  PurposeTwoFactorChallenge,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ChallengeTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(m.secret))
+}
+
 func (m *Manager) ParseToken(tokenStr string) (*JWTClaims, error) {
+	claims, err := m.parse(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, fmt.Errorf("invalid token purpose")
+	}
+	if claims.TenantID == 0 {
+		return nil, fmt.Errorf("token has no tenant")
+	}
+	return claims, nil
+}
+
+// ParseChallengeToken возвращает пользователя и арендатора, для которых выдан challenge-токен.
+func (m *Manager) ParseChallengeToken(tokenStr string) (int, int, error) {
+	claims, err := m.parse(tokenStr)
+	if err != nil {
+		return 0, 0, err
+	}
+	if claims.Purpose != PurposeTwoFactorChallenge || claims.TenantID == 0 {
+		return 0, 0, fmt.Errorf("invalid token purpose")
+	}
+	return claims.UserID, claims.TenantID, nil
+}
+
+func (m *Manager) parse(tokenStr string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenStr, &JWTClaims{}, func(t *jwt.Token) (interface{}, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method")
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LockoutPolicy описывает, сколько неудачных попыток входа допускается
+// и как растёт блокировка после превышения лимита.
+type LockoutPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Window      time.Duration
+}
+
+// LockFor возвращает длительность блокировки для заданного числа неудач:
+// BaseDelay удваивается с каждой попыткой сверх MaxAttempts, но не больше MaxDelay.
+func (p LockoutPolicy) LockFor(failures int) time.Duration {
+	if failures < p.MaxAttempts {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := p.MaxAttempts; i < failures; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return delay
+}
+
+type attemptState struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// MemoryLimiter хранит счётчики в памяти процесса. Ключи, по которым больше
+// не приходят запросы, удаляет только Sweep: его нужно запускать периодически.
+type MemoryLimiter struct {
+	policy LockoutPolicy
+	mu     sync.Mutex
+	state  map[string]*attemptState
+	now    func() time.Time
+}
+
+func NewMemoryLimiter(policy LockoutPolicy) *MemoryLimiter {
+	return &MemoryLimiter{
+		policy: policy,
+		state:  make(map[string]*attemptState),
+		now:    time.Now,
+	}
+}
+
+func (l *MemoryLimiter) RetryAfter(_ context.Context, key string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	st, ok := l.state[key]
+	if !ok {
+		return 0, nil
+	}
+	now := l.now()
+	if now.Before(st.lockedUntil) {
+		return st.lockedUntil.Sub(now), nil
+	}
+	if now.Sub(st.lastFailure) > l.policy.Window {
+		delete(l.state, key)
+	}
+	return 0, nil
+}
+
+func (l *MemoryLimiter) RegisterFailure(_ context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	st, ok := l.state[key]
+	if !ok || now.Sub(st.lastFailure) > l.policy.Window {
+		st = &attemptState{}
+		l.state[key] = st
+	}
+	st.failures++
+	st.lastFailure = now
+	if d := l.policy.LockFor(st.failures); d > 0 {
+		st.lockedUntil = now.Add(d)
+	}
+	return nil
+}
+
+func (l *MemoryLimiter) Reset(_ context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.state, key)
+	return nil
+}
+
+// Sweep удаляет состояния, которые уже ни на что не влияют: блокировка истекла,
+// а последняя неудача вышла за окно. Возвращает число удалённых ключей.
+func (l *MemoryLimiter) Sweep(_ context.Context) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	removed := 0
+	for key, st := range l.state {
+		if !now.Before(st.lockedUntil) && now.Sub(st.lastFailure) > l.policy.Window {
+			delete(l.state, key)
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,83 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"product-catalog/internal/auth"
+)
+
+func TestLockoutPolicyLockFor(t *testing.T) {
+	p := auth.LockoutPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: 10 * time.Second, Window: time.Minute}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{6, 8 * time.Second},
+		{7, 10 * time.Second},
+		{50, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := p.LockFor(tt.failures); got != tt.want {
+			t.Errorf("LockFor(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := auth.NewMemoryLimiter(auth.LockoutPolicy{MaxAttempts: 2, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour})
+
+	_ = l.RegisterFailure(ctx, "a@example.com")
+	if d, _ := l.RetryAfter(ctx, "a@example.com"); d != 0 {
+		t.Fatalf("expected no lockout after first failure, got %s", d)
+	}
+
+	_ = l.RegisterFailure(ctx, "a@example.com")
+	if d, _ := l.RetryAfter(ctx, "a@example.com"); d <= 0 {
+		t.Fatal("expected lockout after reaching max attempts")
+	}
+	if d, _ := l.RetryAfter(ctx, "b@example.com"); d != 0 {
+		t.Fatalf("expected other keys to be unaffected, got %s", d)
+	}
+
+	_ = l.Reset(ctx, "a@example.com")
+	if d, _ := l.RetryAfter(ctx, "a@example.com"); d != 0 {
+		t.Fatalf("expected reset to clear lockout, got %s", d)
+	}
+}
+
+func TestMemoryLimiterSweep(t *testing.T) {
+	ctx := context.Background()
+	window := 20 * time.Millisecond
+	l := auth.NewMemoryLimiter(auth.LockoutPolicy{MaxAttempts: 2, BaseDelay: time.Hour, MaxDelay: time.Hour, Window: window})
+
+	_ = l.RegisterFailure(ctx, "locked")
+	_ = l.RegisterFailure(ctx, "locked")
+	_ = l.RegisterFailure(ctx, "stale")
+	if n, _ := l.Sweep(ctx); n != 0 {
+		t.Fatalf("swept %d keys inside the window, want 0", n)
+	}
+
+	time.Sleep(2 * window)
+	_ = l.RegisterFailure(ctx, "fresh")
+	// Истёк только stale: у locked ещё действует блокировка, fresh в окне
+	if n, _ := l.Sweep(ctx); n != 1 {
+		t.Fatalf("swept %d keys, want 1", n)
+	}
+	if d, _ := l.RetryAfter(ctx, "locked"); d <= 0 {
+		t.Fatal("sweep dropped an active lockout")
+	}
+	_ = l.RegisterFailure(ctx, "fresh")
+	if d, _ := l.RetryAfter(ctx, "fresh"); d <= 0 {
+		t.Fatal("sweep dropped failures inside the window")
+	}
+}
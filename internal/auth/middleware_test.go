@@ -0,0 +1,213 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"product-catalog/internal/auth"
+)
+
+func TestRequirePermission(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	h := auth.RequirePermission(auth.PermProductWrite)(ok)
+
+	tests := []struct {
+		name  string
+		perms auth.Permissions
+		set   bool
+		want  int
+	}{
+		{"no auth context", nil, false, http.StatusUnauthorized},
+		{"missing permission", auth.Permissions{auth.PermUserRead}, true, http.StatusForbidden},
+		{"granted", auth.Permissions{auth.PermUserRead, auth.PermProductWrite}, true, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/products", nil)
+			if tt.set {
+				req = req.WithContext(auth.WithUserContext(req.Context(), 1, auth.RoleUser, tt.perms))
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireAnyPermission(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	h := auth.RequireAnyPermission(auth.PermProductWrite, auth.PermProductManage)(ok)
+
+	tests := []struct {
+		name  string
+		perms auth.Permissions
+		want  int
+	}{
+		{"neither", auth.Permissions{auth.PermUserRead}, http.StatusForbidden},
+		{"write only", auth.Permissions{auth.PermProductWrite}, http.StatusOK},
+		{"manage only", auth.Permissions{auth.PermProductManage}, http.StatusOK},
+		{"both", auth.Permissions{auth.PermProductWrite, auth.PermProductManage}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/products/1", nil)
+			req = req.WithContext(auth.WithUserContext(req.Context(), 1, auth.RoleUser, tt.perms))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+type allowSessions struct{}
+
+func (allowSessions) ValidateSession(context.Context, string, int, string, string) error { return nil }
+
+type fakeAuditor struct {
+	events   []auth.ImpersonationEvent
+	statuses map[int]int
+}
+
+func (a *fakeAuditor) RecordImpersonatedRequest(_ context.Context, event auth.ImpersonationEvent) (int, error) {
+	a.events = append(a.events, event)
+	return len(a.events), nil
+}
+
+func (a *fakeAuditor) CompleteImpersonatedRequest(_ context.Context, recordID, status int) error {
+	a.statuses[recordID] = status
+	return nil
+}
+
+func TestImpersonationIsAuditedAndRestricted(t *testing.T) {
+	jwtM := auth.NewJWTManager("secret", time.Hour)
+	auditor := &fakeAuditor{statuses: map[int]int{}}
+	m := auth.NewMiddleware(jwtM, nil, allowSessions{}, auditor)
+
+	var gotActor int
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotActor, _ = auth.ActorIDFromContext(r.Context())
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	token, err := jwtM.GenerateImpersonationToken(7, 1, auth.RoleUser, nil, "sid-1", 1, time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateImpersonationToken: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/products", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	m.AuthMiddleware(ok).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted || gotActor != 1 {
+		t.Fatalf("status = %d, actor = %d; want 202 and actor 1", rec.Code, gotActor)
+	}
+	if rec.Header().Get(auth.ImpersonatedByHeader) != "1" {
+		t.Fatalf("%s header = %q, want 1", auth.ImpersonatedByHeader, rec.Header().Get(auth.ImpersonatedByHeader))
+	}
+	if len(auditor.events) != 1 || auditor.events[0].UserID != 7 || auditor.events[0].Path != "/products" {
+		t.Fatalf("audit events = %+v", auditor.events)
+	}
+	if auditor.statuses[1] != http.StatusAccepted {
+		t.Fatalf("recorded status = %d, want 202", auditor.statuses[1])
+	}
+
+	rec = httptest.NewRecorder()
+	m.AuthMiddleware(auth.DenyImpersonation(ok)).ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("DenyImpersonation status = %d, want 403", rec.Code)
+	}
+}
+
+func TestTokenIsBoundToTenant(t *testing.T) {
+	jwtM := auth.NewJWTManager("secret", time.Hour)
+	m := auth.NewMiddleware(jwtM, nil, allowSessions{}, nil)
+
+	var gotTenant int
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTenant, _ = auth.TenantIDFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+
+	token, err := jwtM.GenerateToken(7, 2, auth.RoleUser, nil, "sid-1")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		ctx        func(context.Context) context.Context
+		want       int
+		wantTenant int
+	}{
+		{"default host uses token tenant", func(ctx context.Context) context.Context { return auth.WithTenant(ctx, 1) }, http.StatusOK, 2},
+		{"host of the same tenant", func(ctx context.Context) context.Context { return auth.WithHostTenant(ctx, 2) }, http.StatusOK, 2},
+		{"host of another tenant", func(ctx context.Context) context.Context { return auth.WithHostTenant(ctx, 1) }, http.StatusUnauthorized, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotTenant = 0
+			req := httptest.NewRequest(http.MethodGet, "/products", nil)
+			req = req.WithContext(tt.ctx(req.Context()))
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			m.AuthMiddleware(ok).ServeHTTP(rec, req)
+			if rec.Code != tt.want || gotTenant != tt.wantTenant {
+				t.Fatalf("status = %d, tenant = %d; want %d and %d", rec.Code, gotTenant, tt.want, tt.wantTenant)
+			}
+		})
+	}
+}
+
+func TestOptionalAuth(t *testing.T) {
+	jwtM := auth.NewJWTManager("secret", time.Hour)
+	m := auth.NewMiddleware(jwtM, nil, allowSessions{}, nil)
+
+	var gotUser int
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser, _ = auth.UserIDFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+
+	token, err := jwtM.GenerateToken(7, 1, auth.RoleUser, nil, "sid-1")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+		wantUser      int
+	}{
+		{"anonymous", "", http.StatusOK, 0},
+		{"valid token", "Bearer " + token, http.StatusOK, 7},
+		{"invalid token", "Bearer garbage", http.StatusUnauthorized, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUser = 0
+			req := httptest.NewRequest(http.MethodGet, "/products", nil)
+			req = req.WithContext(auth.WithTenant(req.Context(), 1))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			m.OptionalAuth(ok).ServeHTTP(rec, req)
+			if rec.Code != tt.want || gotUser != tt.wantUser {
+				t.Fatalf("status = %d, user = %d; want %d and %d", rec.Code, gotUser, tt.want, tt.wantUser)
+			}
+		})
+	}
+}
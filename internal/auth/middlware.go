@@ -1,36 +1,133 @@
 package auth
 
 import (
+	"context"
+	stderrors "errors"
 	"net/http"
 	"product-catalog/internal/errors"
+	"product-catalog/internal/transport/problem"
 	"strings"
 )
 
+// SessionValidator проверяет, что сессия токена не отозвана и не истекла,
+// и обновляет время последней активности.
+type SessionValidator interface {
+	ValidateSession(ctx context.Context, sessionID string, userID int, ip, userAgent string) error
+}
+
 type Middleware struct {
 	jwtManager *Manager
+	apiKeys    APIKeyAuthenticator
+	sessions   SessionValidator
+	auditor    ImpersonationAuditor
 }
 
-func NewMiddleware(jwtManager *Manager) *Middleware {
-	return &Middleware{jwtManager: jwtManager}
+func NewMiddleware(jwtManager *Manager, apiKeys APIKeyAuthenticator, sessions SessionValidator, auditor ImpersonationAuditor) *Middleware {
+	return &Middleware{jwtManager: jwtManager, apiKeys: apiKeys, sessions: sessions, auditor: auditor}
 }
 
 func (m *Middleware) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiKey := r.Header.Get(APIKeyHeader); apiKey != "" {
+			identity, err := m.apiKeys.AuthenticateAPIKey(r.Context(), apiKey)
+			if err != nil {
+				problem.Error(w, r, errors.ErrUnauthorized)
+				return
+			}
+
+			ctx := WithUserContext(r.Context(), identity.UserID, identity.Role, identity.Permissions)
+			ctx = WithAPIKey(ctx, identity.KeyID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			http.Error(w, errors.ErrUnauthorized.Error(), http.StatusUnauthorized)
+			problem.Error(w, r, errors.ErrUnauthorized)
 			return
 		}
 
 		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
 
 		claims, err := m.jwtManager.ParseToken(tokenStr)
+		if err != nil || claims.SessionID == "" {
+			problem.Error(w, r, errors.ErrUnauthorized)
+			return
+		}
+
+		// Токен действует только в своём каталоге: на хосте другого арендатора он отклоняется
+		if hostTenant, pinned := HostTenantIDFromContext(r.Context()); pinned && hostTenant != claims.TenantID {
+			problem.Error(w, r, errors.ErrUnauthorized)
+			return
+		}
+		ctx := WithTenant(r.Context(), claims.TenantID)
+
+		err = m.sessions.ValidateSession(ctx, claims.SessionID, claims.UserID, ClientIP(r), r.UserAgent())
 		if err != nil {
-			http.Error(w, errors.ErrUnauthorized.Error(), http.StatusUnauthorized)
+			if stderrors.Is(err, errors.ErrUnauthorized) {
+				problem.Error(w, r, errors.ErrUnauthorized)
+				return
+			}
+			problem.Error(w, r, err)
 			return
 		}
 
-		ctx := WithUserContext(r.Context(), claims.UserID, claims.Role)
+		ctx = WithUserContext(ctx, claims.UserID, claims.Role, claims.Permissions)
+		ctx = WithSession(ctx, claims.SessionID)
+		if claims.Actor != nil {
+			m.serveImpersonated(w, r.WithContext(ctx), claims, next)
+			return
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// OptionalAuth аутентифицирует запрос, только если он несёт учётные данные, и
+// пропускает анонимный как есть: публичный маршрут может показать больше
+// аутентифицированным. Неверные учётные данные отклоняются, а не понижаются до анонимных.
+func (m *Middleware) OptionalAuth(next http.Handler) http.Handler {
+	authenticated := m.AuthMiddleware(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(APIKeyHeader) == "" && r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		authenticated.ServeHTTP(w, r)
+	})
+}
+
+// RequirePermission пропускает запрос дальше, только если у субъекта
+// (пользователя по JWT или API-ключа) есть указанное разрешение.
+func RequirePermission(perm Permission) func(http.Handler) http.Handler {
+	return RequireAnyPermission(perm)
+}
+
+// RequireAnyPermission пропускает запрос, если у субъекта есть хотя бы одно из
+// разрешений; какое из них чего позволяет, решает политика сервиса.
+func RequireAnyPermission(perms ...Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			granted, ok := PermissionsFromContext(r.Context())
+			if !ok {
+				problem.Error(w, r, errors.ErrUnauthorized)
+				return
+			}
+			if !granted.HasAny(perms...) {
+				problem.Error(w, r, errors.ErrForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// DenyAPIKeys запрещает доступ по API-ключу, например к управлению самими ключами.
+func DenyAPIKeys(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, viaAPIKey := APIKeyIDFromContext(r.Context()); viaAPIKey {
+			problem.Error(w, r, errors.ErrForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// PasswordPolicy проверяет длину пароля, совпадение с данными пользователя
+// и наличие в локальном списке утёкших паролей.
+type PasswordPolicy struct {
+	MinLength   int
+	MaxLength   int
+	blocked     map[string]struct{}
+	blockedSHA1 map[string]struct{}
+}
+
+// NewPasswordPolicy создаёт политику; blocklistPath может быть пустым.
+// Строки файла — пароли в открытом виде либо SHA-1 в формате HIBP ("HASH" или "HASH:count").
+// Пустые строки и строки, начинающиеся с #, пропускаются.
+func NewPasswordPolicy(minLength, maxLength int, blocklistPath string) (*PasswordPolicy, error) {
+	p := &PasswordPolicy{
+		MinLength:   minLength,
+		MaxLength:   maxLength,
+		blocked:     map[string]struct{}{},
+		blockedSHA1: map[string]struct{}{},
+	}
+	if blocklistPath == "" {
+		return p, nil
+	}
+
+	f, err := os.Open(blocklistPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open password blocklist: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		p.Block(scanner.Text())
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read password blocklist: %w", err)
+	}
+	return p, nil
+}
+
+// Block добавляет строку в список запрещённых паролей.
+func (p *PasswordPolicy) Block(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+	if hash, _, _ := strings.Cut(line, ":"); isSHA1Hex(hash) {
+		p.blockedSHA1[strings.ToUpper(hash)] = struct{}{}
+		return
+	}
+	p.blocked[strings.ToLower(line)] = struct{}{}
+}
+
+// Validate возвращает описание первого нарушения политики или nil.
+// related — данные пользователя (имя, email), которые не должны входить в пароль.
+func (p *PasswordPolicy) Validate(password string, related ...string) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return fmt.Errorf("password must be at most %d characters", p.MaxLength)
+	}
+
+	lower := strings.ToLower(password)
+	for _, value := range related {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if local, _, found := strings.Cut(value, "@"); found {
+			value = local
+		}
+		if len(value) >= 3 && strings.Contains(lower, value) {
+			return fmt.Errorf("password must not contain your username or email")
+		}
+	}
+
+	if _, ok := p.blocked[lower]; ok {
+		return fmt.Errorf("password is too common or appeared in a data breach")
+	}
+	if len(p.blockedSHA1) > 0 {
+		sum := sha1.Sum([]byte(password))
+		if _, ok := p.blockedSHA1[strings.ToUpper(hex.EncodeToString(sum[:]))]; ok {
+			return fmt.Errorf("password is too common or appeared in a data breach")
+		}
+	}
+	return nil
+}
+
+func isSHA1Hex(s string) bool {
+	if len(s) != sha1.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,53 @@
+package auth_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"product-catalog/internal/auth"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "blocklist.txt")
+	blocklist := "# comment\nQwerty123\n\n" +
+		// SHA-1 от "P@ssword1" в формате HIBP
+		"1F3C53AE14626035383B39C207564D32D083E8FD:42\n"
+	if err := os.WriteFile(path, []byte(blocklist), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := auth.NewPasswordPolicy(8, 64, path)
+	if err != nil {
+		t.Fatalf("NewPasswordPolicy: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		related  []string
+		ok       bool
+	}{
+		{"valid", "long enough passphrase", nil, true},
+		{"too short", "short", nil, false},
+		{"too long", string(make([]byte, 65)), nil, false},
+		{"unicode counted in runes", "пароль12", nil, true},
+		{"blocked plain, case insensitive", "qwerty123", nil, false},
+		{"blocked sha1", "P@ssword1", nil, false},
+		{"contains username", "xx-alice-2024", []string{"alice"}, false},
+		{"contains email local part", "bob.smith!2024", []string{"bob.smith@example.com"}, false},
+	}
+	for _, tt := range tests {
+		err := p.Validate(tt.password, tt.related...)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: Validate err = %v, want ok=%v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestPasswordPolicyMissingBlocklist(t *testing.T) {
+	if _, err := auth.NewPasswordPolicy(8, 64, filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Fatal("expected error for missing blocklist file")
+	}
+}
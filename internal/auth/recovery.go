@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	RecoveryCodeCount  = 10
+	recoveryCodeLength = 10
+)
+
+var recoveryAlphabet = []byte("abcdefghjkmnpqrstuvwxyz23456789")
+
+// GenerateRecoveryCodes выдаёт одноразовые коды вида "xxxxx-xxxxx".
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	buf := make([]byte, recoveryCodeLength)
+	for i := 0; i < n; i++ {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		for j := range buf {
+			buf[j] = recoveryAlphabet[int(buf[j])%len(recoveryAlphabet)]
+		}
+		half := recoveryCodeLength / 2
+		codes = append(codes, string(buf[:half])+"-"+string(buf[half:]))
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode нормализует код (регистр, дефисы, пробелы) и возвращает его SHA-256.
+// Коды случайные и длинные, поэтому медленный хэш здесь не нужен.
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
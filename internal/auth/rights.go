@@ -1,8 +1,6 @@
 package auth
 
-import (
-	"product-catalog/internal/errors"
-)
+import "slices"
 
 type Role string
 
@@ -11,9 +9,50 @@ const (
 	RoleUser  Role = "user"
 )
 
-func (r Role) CanDoAdminAction() error {
-	if r != RoleAdmin {
-		return errors.ErrForbidden
+type Permission string
+
+const (
+	PermProductWrite    Permission = "product:write"
+	PermProductManage   Permission = "product:manage"
+	PermCategoryWrite   Permission = "category:write"
+	PermUserRead        Permission = "user:read"
+	PermUserManage      Permission = "user:manage"
+	PermRoleManage      Permission = "role:manage"
+	PermUserImpersonate Permission = "user:impersonate"
+)
+
+var AllPermissions = Permissions{
+	PermProductWrite,
+	PermProductManage,
+	PermCategoryWrite,
+	PermUserRead,
+	PermUserManage,
+	PermRoleManage,
+	PermUserImpersonate,
+}
+
+func IsKnownPermission(p Permission) bool {
+	return slices.Contains(AllPermissions, p)
+}
+
+type Permissions []Permission
+
+func (p Permissions) Has(perm Permission) bool {
+	return slices.Contains(p, perm)
+}
+
+// HasAny сообщает, есть ли в наборе хотя бы одно из разрешений.
+func (p Permissions) HasAny(perms ...Permission) bool {
+	return slices.ContainsFunc(perms, p.Has)
+}
+
+// Intersect возвращает только те разрешения, которые есть в обоих наборах.
+func (p Permissions) Intersect(other Permissions) Permissions {
+	res := make(Permissions, 0, len(p))
+	for _, perm := range p {
+		if other.Has(perm) {
+			res = append(res, perm)
+		}
 	}
-	return nil
+	return res
 }
@@ -0,0 +1,80 @@
+package auth_test
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"product-catalog/internal/auth"
+)
+
+// Тестовые векторы RFC 6238 (SHA1), усечённые до 6 цифр.
+func TestTOTPCodeRFC6238(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := auth.TOTPCode(secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("TOTPCode: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / auth.TOTPPeriod
+
+	tests := []struct {
+		name   string
+		offset int64
+		wantOK bool
+	}{
+		{"two periods ago", -2, false},
+		{"previous period", -1, true},
+		{"current period", 0, true},
+		{"next period", 1, true},
+		{"two periods ahead", 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := auth.TOTPCode(secret, now.Add(time.Duration(tt.offset*auth.TOTPPeriod)*time.Second))
+			if err != nil {
+				t.Fatalf("TOTPCode: %v", err)
+			}
+			gotStep, ok := auth.ValidateTOTP(secret, code, now)
+			if ok != tt.wantOK {
+				t.Fatalf("ValidateTOTP(%s) ok = %v, want %v", code, ok, tt.wantOK)
+			}
+			if ok && gotStep != step+tt.offset {
+				t.Errorf("step = %d, want %d", gotStep, step+tt.offset)
+			}
+		})
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := auth.TOTPURI("Product Catalog", "john@example.com", "ABC")
+	if !strings.HasPrefix(uri, "otpauth://totp/Product%20Catalog:john@example.com?") {
+		t.Errorf("unexpected uri: %s", uri)
+	}
+	if !strings.Contains(uri, "secret=ABC") {
+		t.Errorf("uri must contain secret: %s", uri)
+	}
+}
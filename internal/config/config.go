@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,11 +14,21 @@ import (
 )
 
 type Config struct {
-	App      AppConfig      `yaml:"app"`
-	Server   ServerConfig   `yaml:"server"`
-	JWT      JWTConfig      `yaml:"jwt"`
-	Database DatabaseConfig `yaml:"database"`
-	Storage  StorageConfig  `yaml:"storage"`
+	App         AppConfig         `yaml:"app"`
+	Server      ServerConfig      `yaml:"server"`
+	JWT         JWTConfig         `yaml:"jwt"`
+	Database    DatabaseConfig    `yaml:"database"`
+	Storage     StorageConfig     `yaml:"storage"`
+	Login       LoginConfig       `yaml:"login_protection"`
+	TwoFactor   TwoFactorConfig   `yaml:"two_factor"`
+	OIDC        OIDCConfig        `yaml:"oidc"`
+	Passwords   PasswordConfig    `yaml:"passwords"`
+	Accounts    AccountsConfig    `yaml:"accounts"`
+	Tenants     TenantsConfig     `yaml:"tenants"`
+	HTTPCache   HTTPCacheConfig   `yaml:"http_cache"`
+	Cache       CacheConfig       `yaml:"product_cache"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Products    ProductsConfig    `yaml:"products"`
 }
 
 type AppConfig struct {
@@ -32,8 +43,9 @@ type ServerConfig struct {
 }
 
 type JWTConfig struct {
-	TokenTTLSeconds int    `yaml:"token_ttl_seconds"`
-	Secret          string `yaml:"-"`
+	TokenTTLSeconds         int    `yaml:"token_ttl_seconds"`
+	ImpersonationTTLSeconds int    `yaml:"impersonation_ttl_seconds"`
+	Secret                  string `yaml:"-"`
 }
 
 type DatabaseConfig struct {
@@ -42,6 +54,7 @@ type DatabaseConfig struct {
 	User    string `yaml:"user"`
 	Name    string `yaml:"name"`
 	SSLMode string `yaml:"sslmode"`
+	AppRole string `yaml:"app_role"`
 	Pass    string `yaml:"-"`
 }
 
@@ -55,6 +68,88 @@ type StorageConfig struct {
 	Region         string `yaml:"region"`
 }
 
+type LoginConfig struct {
+	Backend            string `yaml:"backend"`
+	AccountMaxAttempts int    `yaml:"account_max_attempts"`
+	IPMaxAttempts      int    `yaml:"ip_max_attempts"`
+	BaseLockoutSeconds int    `yaml:"base_lockout_seconds"`
+	MaxLockoutSeconds  int    `yaml:"max_lockout_seconds"`
+	WindowSeconds      int    `yaml:"window_seconds"`
+}
+
+type TwoFactorConfig struct {
+	Issuer           string `yaml:"issuer"`
+	RequireForAdmins bool   `yaml:"require_for_admins"`
+}
+
+type PasswordConfig struct {
+	MinLength     int          `yaml:"min_length"`
+	MaxLength     int          `yaml:"max_length"`
+	BlocklistFile string       `yaml:"blocklist_file"`
+	Argon2        Argon2Config `yaml:"argon2"`
+}
+
+type Argon2Config struct {
+	MemoryKiB   uint32 `yaml:"memory_kib"`
+	Iterations  uint32 `yaml:"iterations"`
+	Parallelism uint8  `yaml:"parallelism"`
+}
+
+type OIDCConfig struct {
+	StateTTLSeconds int                  `yaml:"state_ttl_seconds"`
+	Providers       []OIDCProviderConfig `yaml:"providers"`
+}
+
+type OIDCProviderConfig struct {
+	Name         string   `yaml:"name"`
+	Issuer       string   `yaml:"issuer"`
+	ClientID     string   `yaml:"client_id"`
+	RedirectURL  string   `yaml:"redirect_url"`
+	Scopes       []string `yaml:"scopes"`
+	ClientSecret string   `yaml:"-"`
+}
+
+type AccountsConfig struct {
+	RestoreWindowHours   int `yaml:"restore_window_hours"`
+	PurgeIntervalSeconds int `yaml:"purge_interval_seconds"`
+	ExportLinkTTLHours   int `yaml:"export_link_ttl_hours"`
+	InvitationTTLHours   int `yaml:"invitation_ttl_hours"`
+}
+
+type TenantsConfig struct {
+	DefaultSlug     string `yaml:"default_slug"`
+	CacheTTLSeconds int    `yaml:"cache_ttl_seconds"`
+}
+
+type HTTPCacheConfig struct {
+	SurrogateKeyHeader string            `yaml:"surrogate_key_header"`
+	ProductList        CachePolicyConfig `yaml:"product_list"`
+	Product            CachePolicyConfig `yaml:"product"`
+}
+
+type CachePolicyConfig struct {
+	CacheControl     string `yaml:"cache_control"`
+	SurrogateControl string `yaml:"surrogate_control"`
+}
+
+type CacheConfig struct {
+	Enabled            bool `yaml:"enabled"`
+	Size               int  `yaml:"size"`
+	TTLSeconds         int  `yaml:"ttl_seconds"`
+	NegativeTTLSeconds int  `yaml:"negative_ttl_seconds"`
+	ReconnectSeconds   int  `yaml:"reconnect_seconds"`
+}
+
+type ProductsConfig struct {
+	TrashRetentionHours int `yaml:"trash_retention_hours"`
+	// ScheduleIntervalSeconds — как часто применяются publish_at и unpublish_at
+	ScheduleIntervalSeconds int `yaml:"schedule_interval_seconds"`
+}
+
+type IdempotencyConfig struct {
+	TTLHours int `yaml:"ttl_hours"`
+}
+
 var (
 	cfg  *Config
 	once sync.Once
@@ -88,6 +183,11 @@ func Load() *Config {
 		cfg.JWT.Secret = os.Getenv("JWT_SECRET")
 		cfg.Database.Pass = os.Getenv("DB_PASSWORD")
 
+		for i := range cfg.OIDC.Providers {
+			p := &cfg.OIDC.Providers[i]
+			p.ClientSecret = os.Getenv("OIDC_" + strings.ToUpper(p.Name) + "_CLIENT_SECRET")
+		}
+
 		if envEndpoint := os.Getenv("MINIO_ENDPOINT"); envEndpoint != "" {
 			cfg.Storage.Endpoint = envEndpoint
 		}
@@ -125,6 +225,9 @@ func (c *Config) validate() error {
 	if c.JWT.TokenTTLSeconds <= 0 {
 		return errors.New("jwt.token_ttl_seconds must be positive")
 	}
+	if c.JWT.ImpersonationTTLSeconds <= 0 {
+		return errors.New("jwt.impersonation_ttl_seconds must be positive")
+	}
 	if c.Storage.AccessKey == "" || c.Storage.SecretKey == "" {
 		return errors.New("minio access key and secret key are required")
 	}
@@ -134,9 +237,121 @@ func (c *Config) validate() error {
 	if c.Storage.Bucket == "" {
 		return errors.New("minio bucket is required")
 	}
+	if c.Login.Backend != "memory" && c.Login.Backend != "postgres" {
+		return errors.New("login_protection.backend must be memory or postgres")
+	}
+	if c.Login.AccountMaxAttempts <= 0 || c.Login.IPMaxAttempts <= 0 {
+		return errors.New("login_protection max attempts must be positive")
+	}
+	if c.Login.BaseLockoutSeconds <= 0 || c.Login.MaxLockoutSeconds < c.Login.BaseLockoutSeconds {
+		return errors.New("login_protection lockout durations are invalid")
+	}
+	if c.Login.WindowSeconds <= 0 {
+		return errors.New("login_protection.window_seconds must be positive")
+	}
+	if c.TwoFactor.Issuer == "" {
+		return errors.New("two_factor.issuer is required")
+	}
+	if c.Passwords.MinLength < 8 || c.Passwords.MaxLength < c.Passwords.MinLength {
+		return errors.New("passwords.min_length must be at least 8 and not exceed max_length")
+	}
+	if c.Passwords.Argon2.MemoryKiB < 19*1024 || c.Passwords.Argon2.Iterations == 0 || c.Passwords.Argon2.Parallelism == 0 {
+		return errors.New("passwords.argon2 parameters are too weak")
+	}
+	if c.Accounts.RestoreWindowHours <= 0 || c.Accounts.PurgeIntervalSeconds <= 0 {
+		return errors.New("accounts.restore_window_hours and accounts.purge_interval_seconds must be positive")
+	}
+	if c.Accounts.ExportLinkTTLHours <= 0 || c.Accounts.ExportLinkTTLHours > 7*24 {
+		return errors.New("accounts.export_link_ttl_hours must be between 1 and 168")
+	}
+	if c.Accounts.InvitationTTLHours <= 0 {
+		return errors.New("accounts.invitation_ttl_hours must be positive")
+	}
+	if c.Tenants.DefaultSlug == "" {
+		return errors.New("tenants.default_slug is required")
+	}
+	if c.Tenants.CacheTTLSeconds <= 0 {
+		return errors.New("tenants.cache_ttl_seconds must be positive")
+	}
+	if c.HTTPCache.ProductList.CacheControl == "" || c.HTTPCache.Product.CacheControl == "" {
+		return errors.New("http_cache.product_list.cache_control and http_cache.product.cache_control are required")
+	}
+	if c.Cache.Enabled && (c.Cache.Size <= 0 || c.Cache.TTLSeconds <= 0 || c.Cache.NegativeTTLSeconds <= 0 || c.Cache.ReconnectSeconds <= 0) {
+		return errors.New("product_cache size, ttl_seconds, negative_ttl_seconds and reconnect_seconds must be positive")
+	}
+	if c.Products.TrashRetentionHours <= 0 {
+		return errors.New("products.trash_retention_hours must be positive")
+	}
+	if c.Products.ScheduleIntervalSeconds <= 0 {
+		return errors.New("products.schedule_interval_seconds must be positive")
+	}
+	if c.Idempotency.TTLHours <= 0 {
+		return errors.New("idempotency.ttl_hours must be positive")
+	}
+	if len(c.OIDC.Providers) > 0 && c.OIDC.StateTTLSeconds <= 0 {
+		return errors.New("oidc.state_ttl_seconds must be positive")
+	}
+	seen := make(map[string]bool)
+	for _, p := range c.OIDC.Providers {
+		if p.Name == "" || p.Issuer == "" || p.ClientID == "" || p.RedirectURL == "" {
+			return errors.New("oidc provider name, issuer, client_id and redirect_url are required")
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("oidc provider %q is defined twice", p.Name)
+		}
+		seen[p.Name] = true
+	}
 	return nil
 }
 
 func (c *Config) TokenTTL() time.Duration {
 	return time.Duration(c.JWT.TokenTTLSeconds) * time.Second
 }
+
+func (c *Config) ImpersonationTTL() time.Duration {
+	return time.Duration(c.JWT.ImpersonationTTLSeconds) * time.Second
+}
+
+func (c *Config) RestoreWindow() time.Duration {
+	return time.Duration(c.Accounts.RestoreWindowHours) * time.Hour
+}
+
+func (c *Config) PurgeInterval() time.Duration {
+	return time.Duration(c.Accounts.PurgeIntervalSeconds) * time.Second
+}
+
+func (c *Config) ExportLinkTTL() time.Duration {
+	return time.Duration(c.Accounts.ExportLinkTTLHours) * time.Hour
+}
+
+func (c *Config) InvitationTTL() time.Duration {
+	return time.Duration(c.Accounts.InvitationTTLHours) * time.Hour
+}
+
+func (c *Config) TenantCacheTTL() time.Duration {
+	return time.Duration(c.Tenants.CacheTTLSeconds) * time.Second
+}
+
+func (c *Config) CacheTTL() time.Duration {
+	return time.Duration(c.Cache.TTLSeconds) * time.Second
+}
+
+func (c *Config) CacheNegativeTTL() time.Duration {
+	return time.Duration(c.Cache.NegativeTTLSeconds) * time.Second
+}
+
+func (c *Config) CacheReconnectInterval() time.Duration {
+	return time.Duration(c.Cache.ReconnectSeconds) * time.Second
+}
+
+func (c *Config) IdempotencyTTL() time.Duration {
+	return time.Duration(c.Idempotency.TTLHours) * time.Hour
+}
+
+func (c *Config) TrashRetention() time.Duration {
+	return time.Duration(c.Products.TrashRetentionHours) * time.Hour
+}
+
+func (c *Config) ScheduleInterval() time.Duration {
+	return time.Duration(c.Products.ScheduleIntervalSeconds) * time.Second
+}
@@ -3,18 +3,31 @@ package dependencies
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 
+	"product-catalog/internal/adapters/oidc"
 	"product-catalog/internal/adapters/storage"
 	"product-catalog/internal/auth"
 	"product-catalog/internal/config"
+	"product-catalog/internal/infra/cache"
 	"product-catalog/internal/infra/db/pg"
+	"product-catalog/internal/jobs"
 	l "product-catalog/internal/logger"
+	"product-catalog/internal/service/apikey"
+	"product-catalog/internal/service/export"
 	"product-catalog/internal/service/file"
+	"product-catalog/internal/service/idempotency"
+	"product-catalog/internal/service/impersonation"
+	"product-catalog/internal/service/invitation"
 	"product-catalog/internal/service/product"
+	"product-catalog/internal/service/role"
+	"product-catalog/internal/service/session"
+	"product-catalog/internal/service/sso"
+	"product-catalog/internal/service/tenant"
 	"product-catalog/internal/service/user"
 	h "product-catalog/internal/transport/http"
 )
@@ -26,13 +39,32 @@ type Deps struct {
 
 	AuthMiddleware    *auth.Middleware
 	LoggingMiddleware *h.LoggingMiddleware
+	TenantMiddleware  *h.TenantMiddleware
 
 	UserService    *user.Service
 	ProductService *product.Service
 	FileService    *file.FileService
+	APIKeyService  *apikey.Service
+	RoleService    *role.Service
+	SSOService     *sso.Service
+	SessionService *session.Service
+	ExportService  *export.Service
+	TenantService  *tenant.Service
+
+	ImpersonationService *impersonation.Service
+	InvitationService    *invitation.Service
 
 	UserHandler    *h.UserHandler
 	ProductHandler *h.ProductHandler
+	APIKeyHandler  *h.APIKeyHandler
+	RoleHandler    *h.RoleHandler
+	SSOHandler     *h.SSOHandler
+	SessionHandler *h.SessionHandler
+
+	ImpersonationHandler *h.ImpersonationHandler
+	InvitationHandler    *h.InvitationHandler
+
+	Jobs []jobs.Job
 }
 
 func New(cfg *config.Config) (*Deps, error) {
@@ -40,25 +72,66 @@ func New(cfg *config.Config) (*Deps, error) {
 	l.Init(cfg.App.Env, cfg.App.LogLevel)
 	logger := l.Log
 
-	// 2. Подключение к БД
-	pool, err := pgxpool.New(context.Background(), cfg.DSN())
+	// 2. Подключение к БД (под ролью приложения, чтобы действовал RLS)
+	pool, err := pg.NewPool(context.Background(), cfg.DSN(), cfg.Database.AppRole)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to DB: %w", err)
 	}
 
-	// 3. JWT менеджер и middlewares
-	jwtM := auth.NewJWTManager(cfg.JWT.Secret, time.Duration(cfg.JWT.TokenTTLSeconds)*time.Second)
-	authM := auth.NewMiddleware(jwtM)
-	loggingM := h.NewLoggingMiddleware(logger)
-
-	// 4. Репозитории
+	// 3. Репозитории
 	userRepo := pg.NewUserRepo(pool)
 	productRepo := pg.NewProductRepo(pool)
+	twoFactorRepo := pg.NewTwoFactorRepo(pool)
+	apiKeyRepo := pg.NewAPIKeyRepo(pool)
+	roleRepo := pg.NewRoleRepo(pool)
+	identityRepo := pg.NewIdentityRepo(pool)
+	sessionRepo := pg.NewSessionRepo(pool)
+	impersonationAuditRepo := pg.NewImpersonationAuditRepo(pool)
+	dataExportRepo := pg.NewDataExportRepo(pool)
+	invitationRepo := pg.NewInvitationRepo(pool)
+	tenantRepo := pg.NewTenantRepo(pool)
+	idempotencyRepo := pg.NewIdempotencyRepo(pool)
+
+	// 4. JWT менеджер, API-ключи, сессии, аудит имперсонации и middlewares
+	jwtM := auth.NewJWTManager(cfg.JWT.Secret, cfg.TokenTTL())
+	apiKeySvc := apikey.NewAPIKeyService(apiKeyRepo, roleRepo)
+	sessionSvc := session.NewSessionService(sessionRepo, cfg.TokenTTL())
+	impersonationSvc := impersonation.NewImpersonationService(impersonationAuditRepo, userRepo, roleRepo, sessionSvc, jwtM, cfg.ImpersonationTTL())
+	authM := auth.NewMiddleware(jwtM, apiKeySvc, sessionSvc, impersonationSvc)
+	loggingM := h.NewLoggingMiddleware(logger)
+	tenantSvc := tenant.NewTenantService(tenantRepo, cfg.Tenants.DefaultSlug, cfg.TenantCacheTTL())
+	tenantM := h.NewTenantMiddleware(tenantSvc, logger)
 
 	// 5. Сервисы
-	hasher := auth.NewHasher()
-	userSvc := user.NewUserService(userRepo, hasher, jwtM)
-	prodSvc := product.NewProductService(productRepo)
+	accountLimiter, ipLimiter, limiterJobs := newLoginLimiters(cfg, pool)
+	hasher := auth.NewArgon2Hasher(auth.Argon2Params{
+		Memory:      cfg.Passwords.Argon2.MemoryKiB,
+		Iterations:  cfg.Passwords.Argon2.Iterations,
+		Parallelism: cfg.Passwords.Argon2.Parallelism,
+		SaltLength:  auth.DefaultArgon2Params.SaltLength,
+		KeyLength:   auth.DefaultArgon2Params.KeyLength,
+	})
+	passwordPolicy, err := auth.NewPasswordPolicy(cfg.Passwords.MinLength, cfg.Passwords.MaxLength, cfg.Passwords.BlocklistFile)
+	if err != nil {
+		return nil, err
+	}
+	twoFactorCfg := user.TwoFactorConfig{Issuer: cfg.TwoFactor.Issuer, RequireForAdmins: cfg.TwoFactor.RequireForAdmins}
+	userSvc := user.NewUserService(userRepo, roleRepo, hasher, jwtM, accountLimiter, ipLimiter, twoFactorRepo, twoFactorCfg, sessionSvc, passwordPolicy, cfg.RestoreWindow())
+	invitationSvc := invitation.NewInvitationService(invitationRepo, userRepo, roleRepo, hasher, passwordPolicy, cfg.InvitationTTL())
+	var productStore product.Repository = productRepo
+	var productCache *cache.ProductRepo
+	if cfg.Cache.Enabled {
+		productCache = cache.NewProductRepo(productRepo, cache.ProductConfig{
+			Size:        cfg.Cache.Size,
+			TTL:         cfg.CacheTTL(),
+			NegativeTTL: cfg.CacheNegativeTTL(),
+		})
+		productStore = productCache
+	}
+	idempotencySvc := idempotency.NewIdempotencyService(idempotencyRepo, cfg.IdempotencyTTL())
+	roleSvc := role.NewRoleService(roleRepo, sessionSvc)
+	flowTTL := time.Duration(cfg.OIDC.StateTTLSeconds) * time.Second
+	ssoSvc := sso.NewSSOService(newOIDCProviders(cfg), identityRepo, userRepo, hasher, userSvc, cfg.JWT.Secret, flowTTL)
 
 	// 6. Storage (MinIO)
 	storageCfg := &storage.MinioConfig{
@@ -75,21 +148,173 @@ func New(cfg *config.Config) (*Deps, error) {
 		return nil, fmt.Errorf("failed to init MinIO storage: %w", err)
 	}
 	fileSvc := file.NewFileService(minioStorage)
+	prodSvc := product.NewProductService(productStore, minioStorage, cfg.TrashRetention())
+	exportSvc := export.NewExportService(dataExportRepo, userRepo, sessionRepo, identityRepo, apiKeySvc, productRepo, impersonationAuditRepo, minioStorage, cfg.ExportLinkTTL())
 
 	// 7. Хендлеры
-	userH := h.NewUserHandler(userSvc, logger, authM)
-	productH := h.NewProductHandler(prodSvc, fileSvc, logger, authM)
+	userH := h.NewUserHandler(userSvc, exportSvc, logger, authM)
+	idempotencyM := h.NewIdempotencyMiddleware(idempotencySvc, logger)
+	productH := h.NewProductHandler(prodSvc, fileSvc, logger, authM, idempotencyM, h.CacheConfig{
+		SurrogateKeyHeader: cfg.HTTPCache.SurrogateKeyHeader,
+		ProductList:        h.CachePolicy(cfg.HTTPCache.ProductList),
+		Product:            h.CachePolicy(cfg.HTTPCache.Product),
+	})
+	apiKeyH := h.NewAPIKeyHandler(apiKeySvc, logger, authM)
+	roleH := h.NewRoleHandler(roleSvc, logger, authM)
+	ssoH := h.NewSSOHandler(ssoSvc, logger, authM, "/auth/oidc", flowTTL)
+	sessionH := h.NewSessionHandler(sessionSvc, logger, authM)
+	impersonationH := h.NewImpersonationHandler(impersonationSvc, logger, authM)
+	invitationH := h.NewInvitationHandler(invitationSvc, logger, authM)
+
+	// 8. Фоновые задачи (данные арендаторов видны только в их контексте, поэтому задачи обходят всех)
+	purgeJob := jobs.Job{
+		Name:     "purge_deleted_users",
+		Interval: cfg.PurgeInterval(),
+		Run: func(ctx context.Context) error {
+			return tenantSvc.ForEach(ctx, func(ctx context.Context) error {
+				n, err := userSvc.PurgeExpiredDeletions(ctx)
+				if n > 0 {
+					tenantID, _ := auth.TenantIDFromContext(ctx)
+					logger.Info("erased users after restore window", zap.Int("tenant_id", tenantID), zap.Int("count", n))
+				}
+				return err
+			})
+		},
+	}
+
+	exportPurgeJob := jobs.Job{
+		Name:     "purge_data_exports",
+		Interval: cfg.PurgeInterval(),
+		Run: func(ctx context.Context) error {
+			return tenantSvc.ForEach(ctx, func(ctx context.Context) error {
+				_, err := exportSvc.PurgeExpired(ctx)
+				return err
+			})
+		},
+	}
+
+	trashPurgeJob := jobs.Job{
+		Name:     "purge_product_trash",
+		Interval: cfg.PurgeInterval(),
+		Run: func(ctx context.Context) error {
+			return tenantSvc.ForEach(ctx, func(ctx context.Context) error {
+				_, err := prodSvc.PurgeTrash(ctx)
+				return err
+			})
+		},
+	}
+
+	productScheduleJob := jobs.Job{
+		Name:     "apply_product_schedule",
+		Interval: cfg.ScheduleInterval(),
+		Run: func(ctx context.Context) error {
+			return tenantSvc.ForEach(ctx, func(ctx context.Context) error {
+				_, _, err := prodSvc.ApplySchedule(ctx)
+				return err
+			})
+		},
+	}
+
+	// Ключи идемпотентности привязаны к пользователю, а не к арендатору: обход арендаторов не нужен
+	idempotencyPurgeJob := jobs.Job{
+		Name:     "purge_idempotency_keys",
+		Interval: cfg.PurgeInterval(),
+		Run: func(ctx context.Context) error {
+			_, err := idempotencySvc.PurgeExpired(ctx)
+			return err
+		},
+	}
+
+	backgroundJobs := []jobs.Job{purgeJob, exportPurgeJob, trashPurgeJob, productScheduleJob, idempotencyPurgeJob}
+	backgroundJobs = append(backgroundJobs, limiterJobs...)
+	if productCache != nil {
+		// Пока подписки нет, изменения других реплик теряются, поэтому после
+		// (пере)подключения кэш сбрасывается целиком
+		listener := pg.NewProductListener(pool)
+		backgroundJobs = append(backgroundJobs, jobs.Job{
+			Name:     "product_cache_invalidation",
+			Interval: cfg.CacheReconnectInterval(),
+			Run: func(ctx context.Context) error {
+				return listener.Listen(ctx, productCache.Reset, productCache.Invalidate)
+			},
+		})
+	}
 
 	return &Deps{
-		Cfg:               cfg,
-		Logger:            logger,
-		DBPool:            pool,
-		AuthMiddleware:    authM,
-		LoggingMiddleware: loggingM,
-		UserService:       userSvc,
-		ProductService:    prodSvc,
-		FileService:       fileSvc,
-		UserHandler:       userH,
-		ProductHandler:    productH,
+		Cfg:                  cfg,
+		Logger:               logger,
+		DBPool:               pool,
+		AuthMiddleware:       authM,
+		LoggingMiddleware:    loggingM,
+		TenantMiddleware:     tenantM,
+		UserService:          userSvc,
+		ProductService:       prodSvc,
+		FileService:          fileSvc,
+		APIKeyService:        apiKeySvc,
+		RoleService:          roleSvc,
+		SSOService:           ssoSvc,
+		SessionService:       sessionSvc,
+		ExportService:        exportSvc,
+		TenantService:        tenantSvc,
+		ImpersonationService: impersonationSvc,
+		InvitationService:    invitationSvc,
+		UserHandler:          userH,
+		ProductHandler:       productH,
+		APIKeyHandler:        apiKeyH,
+		RoleHandler:          roleH,
+		SSOHandler:           ssoH,
+		SessionHandler:       sessionH,
+		ImpersonationHandler: impersonationH,
+		InvitationHandler:    invitationH,
+		Jobs:                 backgroundJobs,
 	}, nil
 }
+
+// newLoginLimiters возвращает счётчики неудачных входов по аккаунту и по IP и задачу
+// их очистки: ключи с перебором адресов иначе копятся бесконечно, в памяти или в login_attempts.
+func newLoginLimiters(cfg *config.Config, pool *pgxpool.Pool) (user.LoginLimiter, user.LoginLimiter, []jobs.Job) {
+	base := time.Duration(cfg.Login.BaseLockoutSeconds) * time.Second
+	maxDelay := time.Duration(cfg.Login.MaxLockoutSeconds) * time.Second
+	window := time.Duration(cfg.Login.WindowSeconds) * time.Second
+
+	accountPolicy := auth.LockoutPolicy{MaxAttempts: cfg.Login.AccountMaxAttempts, BaseDelay: base, MaxDelay: maxDelay, Window: window}
+	ipPolicy := auth.LockoutPolicy{MaxAttempts: cfg.Login.IPMaxAttempts, BaseDelay: base, MaxDelay: maxDelay, Window: window}
+
+	var accountLimiter, ipLimiter interface {
+		user.LoginLimiter
+		Sweep(ctx context.Context) (int, error)
+	}
+	if cfg.Login.Backend == "postgres" {
+		accountLimiter, ipLimiter = pg.NewLoginAttemptRepo(pool, "account", accountPolicy), pg.NewLoginAttemptRepo(pool, "ip", ipPolicy)
+	} else {
+		accountLimiter, ipLimiter = auth.NewMemoryLimiter(accountPolicy), auth.NewMemoryLimiter(ipPolicy)
+	}
+
+	sweepJob := jobs.Job{
+		Name:     "sweep_login_attempts",
+		Interval: window,
+		Run: func(ctx context.Context) error {
+			if _, err := accountLimiter.Sweep(ctx); err != nil {
+				return err
+			}
+			_, err := ipLimiter.Sweep(ctx)
+			return err
+		},
+	}
+	return accountLimiter, ipLimiter, []jobs.Job{sweepJob}
+}
+
+func newOIDCProviders(cfg *config.Config) map[string]sso.Provider {
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	providers := make(map[string]sso.Provider, len(cfg.OIDC.Providers))
+	for _, p := range cfg.OIDC.Providers {
+		providers[p.Name] = oidc.NewProvider(oidc.Config{
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		}, httpClient)
+	}
+	return providers
+}
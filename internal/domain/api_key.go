@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+type APIKey struct {
+	ID         int
+	UserID     int
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     []string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+type DataExportStatus string
+
+const (
+	DataExportPending DataExportStatus = "pending"
+	DataExportReady   DataExportStatus = "ready"
+	DataExportFailed  DataExportStatus = "failed"
+)
+
+// DataExport — архив с персональными данными пользователя (GDPR, право на доступ).
+type DataExport struct {
+	ID          int
+	UserID      int
+	Status      DataExportStatus
+	ObjectKey   string
+	Error       string
+	CreatedAt   time.Time
+	CompletedAt *time.Time
+	ExpiresAt   *time.Time
+}
@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// IdempotencyRecord — запрос с заголовком Idempotency-Key и ответ на него
+type IdempotencyRecord struct {
+	UserID int
+	Key    string
+	// Fingerprint — хэш метода, пути и тела: по нему отличается повтор от другого запроса
+	Fingerprint string
+	// StatusCode равен 0, пока первый запрос ещё выполняется
+	StatusCode int
+	Header     map[string]string
+	Body       []byte
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+}
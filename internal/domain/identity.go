@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// UserIdentity связывает пользователя с учётной записью во внешнем OIDC-провайдере.
+type UserIdentity struct {
+	ID          int
+	UserID      int
+	Provider    string
+	Subject     string
+	Email       string
+	CreatedAt   time.Time
+	LastLoginAt time.Time
+}
+
+// ExternalProfile — проверенные данные из ID token провайдера.
+type ExternalProfile struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Username      string
+}
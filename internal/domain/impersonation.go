@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+const (
+	ImpersonationActionStart   = "start"
+	ImpersonationActionRequest = "request"
+)
+
+// ImpersonationAuditRecord — запись журнала: выдача токена имперсонации (start)
+// или запрос, выполненный с этим токеном (request).
+type ImpersonationAuditRecord struct {
+	ID        int
+	ActorID   int
+	UserID    int
+	SessionID string
+	Action    string
+	Method    string
+	Path      string
+	Status    *int
+	IP        string
+	Reason    string
+	CreatedAt time.Time
+}
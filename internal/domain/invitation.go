@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"product-catalog/internal/auth"
+	"time"
+)
+
+// Invitation — приглашение, по которому приглашённый сам задаёт логин и пароль.
+// Роль назначает администратор при создании приглашения.
+type Invitation struct {
+	ID             int
+	Email          string
+	Role           auth.Role
+	TokenHash      string
+	InvitedBy      int
+	CreatedAt      time.Time
+	ExpiresAt      time.Time
+	AcceptedAt     *time.Time
+	AcceptedUserID *int
+	RevokedAt      *time.Time
+}
@@ -2,6 +2,17 @@ package domain
 
 import "time"
 
+type ProductStatus string
+
+const (
+	ProductStatusDraft ProductStatus = "draft"
+	// Отправлен продавцом на проверку перед публикацией
+	ProductStatusInReview  ProductStatus = "in_review"
+	ProductStatusPublished ProductStatus = "published"
+	// Снят с публикации; вернуть в каталог можно только через черновик
+	ProductStatusArchived ProductStatus = "archived"
+)
+
 type Product struct {
 	ID          int
 	Title       string
@@ -9,5 +20,42 @@ type Product struct {
 	Description string
 	Available   bool
 	ImageURL    string
-	CreatedAt   time.Time
+	CreatedBy   int
+	// В публичном каталоге видны только опубликованные товары
+	Status ProductStatus
+	// PublishAt и UnpublishAt — запланированные публикация и снятие с публикации
+	PublishAt   *time.Time
+	UnpublishAt *time.Time
+	// Version растёт при каждом изменении и служит ETag для If-Match
+	Version   int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// DeletedAt задан у товаров в корзине
+	DeletedAt *time.Time
+}
+
+// CatalogRevision меняется при любом изменении товаров арендатора; нулевое
+// значение — в каталоге ещё ничего не менялось
+type CatalogRevision struct {
+	Revision  int64
+	UpdatedAt time.Time
+}
+
+// ProductSchedule — расписание публикации товара; nil означает, что событие не запланировано
+type ProductSchedule struct {
+	PublishAt   *time.Time
+	UnpublishAt *time.Time
+}
+
+// ProductPatch — частичное изменение товара: nil означает, что колонка не меняется
+type ProductPatch struct {
+	Title       *string
+	Price       *int
+	Description *string
+	Available   *bool
+	ImageURL    *string
+}
+
+func (p ProductPatch) IsEmpty() bool {
+	return p.Title == nil && p.Price == nil && p.Description == nil && p.Available == nil && p.ImageURL == nil
 }
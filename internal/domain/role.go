@@ -0,0 +1,9 @@
+package domain
+
+import "product-catalog/internal/auth"
+
+type Role struct {
+	Name        auth.Role
+	Description string
+	Permissions auth.Permissions
+}
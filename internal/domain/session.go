@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// Session — активный вход пользователя с конкретного устройства.
+// Access-токены ссылаются на сессию через claim sid.
+type Session struct {
+	ID         string
+	UserID     int
+	Device     string
+	IP         string
+	UserAgent  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+	// ID администратора, если сессия открыта через имперсонацию
+	ImpersonatorID *int
+}
@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// Tenant — каталог отдельного бренда. Пользователи, товары и файлы принадлежат ровно одному арендатору.
+type Tenant struct {
+	ID        int
+	Slug      string
+	Name      string
+	Hosts     []string
+	CreatedAt time.Time
+}
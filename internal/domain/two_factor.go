@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+type TwoFactor struct {
+	UserID       int
+	Secret       string
+	Enabled      bool
+	Required     bool
+	LastUsedStep int64
+	EnabledAt    *time.Time
+}
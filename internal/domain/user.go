@@ -5,11 +5,60 @@ import (
 	"time"
 )
 
+type UserStatus string
+
+const (
+	UserStatusActive      UserStatus = "active"
+	UserStatusDeactivated UserStatus = "deactivated"
+	// Удалён, но может быть восстановлен до истечения окна восстановления
+	UserStatusDeleted UserStatus = "deleted"
+	// Персональные данные обезличены, восстановление невозможно
+	UserStatusErased UserStatus = "erased"
+)
+
 type User struct {
 	ID           int
 	Username     string
 	Email        string
 	PasswordHash string
 	Role         auth.Role
+	Status       UserStatus
+	DeletedAt    *time.Time
+	Version      int
 	CreatedAt    time.Time
 }
+
+// UserPatch — частичное изменение пользователя: nil означает, что колонка не меняется
+type UserPatch struct {
+	Username     *string
+	Email        *string
+	Role         *auth.Role
+	PasswordHash *string
+}
+
+func (p UserPatch) IsEmpty() bool {
+	return p.Username == nil && p.Email == nil && p.Role == nil && p.PasswordHash == nil
+}
+
+type UserSort string
+
+const (
+	UserSortID        UserSort = "id"
+	UserSortUsername  UserSort = "username"
+	UserSortEmail     UserSort = "email"
+	UserSortCreatedAt UserSort = "created_at"
+)
+
+// UserFilter — параметры выборки справочника пользователей.
+// Query ищет по префиксу username или email без учёта регистра.
+type UserFilter struct {
+	Query         string
+	Roles         []auth.Role
+	Statuses      []UserStatus
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Sort          UserSort
+	Desc          bool
+	Limit         int
+	Offset        int
+}
@@ -0,0 +1,25 @@
+package dto
+
+import "time"
+
+type CreateAPIKeyInput struct {
+	Name          string   `json:"name" validate:"required,min=3,max=50"`
+	Scopes        []string `json:"scopes" validate:"required"`
+	ExpiresInDays *int     `json:"expires_in_days,omitempty" validate:"omitempty,min=1,max=365"`
+}
+
+type APIKeyOutput struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreatedAPIKeyOutput struct {
+	APIKeyOutput
+	Key string `json:"key"`
+}
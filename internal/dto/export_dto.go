@@ -0,0 +1,34 @@
+package dto
+
+import "time"
+
+type DataExportOutput struct {
+	ID          int        `json:"id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+}
+
+// UserDataExport — содержимое export.json в архиве с персональными данными.
+type UserDataExport struct {
+	GeneratedAt        time.Time                  `json:"generated_at"`
+	Profile            UserOutput                 `json:"profile"`
+	Identities         []IdentityOutput           `json:"identities"`
+	Sessions           []SessionOutput            `json:"sessions"`
+	APIKeys            []APIKeyOutput             `json:"api_keys"`
+	Products           []ExportedProduct          `json:"products"`
+	ImpersonationAudit []ImpersonationAuditOutput `json:"impersonation_audit"`
+}
+
+type ExportedProduct struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	Price       int    `json:"price"`
+	Description string `json:"description"`
+	Available   bool   `json:"available"`
+	// Путь к изображению внутри архива; пустой, если файл недоступен
+	ImageFile string    `json:"image_file,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
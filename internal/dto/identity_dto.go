@@ -0,0 +1,10 @@
+package dto
+
+import "time"
+
+type IdentityOutput struct {
+	Provider    string    `json:"provider"`
+	Email       string    `json:"email"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
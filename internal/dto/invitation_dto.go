@@ -0,0 +1,34 @@
+package dto
+
+import (
+	"product-catalog/internal/auth"
+	"time"
+)
+
+type CreateInvitationInput struct {
+	Email string    `json:"email" validate:"required,email"`
+	Role  auth.Role `json:"role" validate:"required"`
+}
+
+type AcceptInvitationInput struct {
+	Token    string `json:"token" validate:"required"`
+	Username string `json:"username" validate:"required,min=3,max=12"`
+	Password string `json:"password" validate:"required,min=8,max=128"`
+}
+
+type InvitationOutput struct {
+	ID        int       `json:"id"`
+	Email     string    `json:"email"`
+	Role      auth.Role `json:"role"`
+	Status    string    `json:"status"`
+	InvitedBy int       `json:"invited_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreatedInvitationOutput содержит токен приглашения; он показывается только
+// при создании и повторной отправке, в базе хранится лишь его хэш.
+type CreatedInvitationOutput struct {
+	InvitationOutput
+	Token string `json:"token"`
+}
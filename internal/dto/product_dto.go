@@ -1,17 +1,53 @@
 package dto
 
+import (
+	"mime/multipart"
+	"time"
+)
+
 type CreateProductInput struct {
-	Title       string `validate:"required,min=3,max=12"`
-	Price       int    `validate:"required,min=1,max=100000"`
-	Description string `validate:"required,min=3,max=120"`
-	Available   bool
-	ImageURL    string `validate:"required,url"`
+	Title       string `json:"title" form:"title" validate:"required,min=3,max=12"`
+	Price       int    `json:"price" form:"price" validate:"required,min=1,max=100000"`
+	Description string `json:"description" form:"description" validate:"required,min=3,max=120"`
+	// Available не передан — товар в наличии, как было до появления поля
+	Available *bool `json:"available" form:"available"`
+	// Картинка передаётся файлом (multipart) или ключом ранее загруженного файла;
+	// ImageURL заполняется сервером
+	Image    *multipart.FileHeader `json:"-" form:"image" validate:"filesize"`
+	ImageKey string                `json:"image_key" form:"image_key" validate:"required_without=Image,max=255"`
+	ImageURL string                `json:"-"`
 }
 
 type UpdateProductInput struct {
-	Title       *string `validate:"min=3,max=12"`
-	Price       *int    `validate:"min=1,max=100000"`
-	Description *string `validate:"min=3,max=120"`
-	Available   *bool   `validate:"required"`
-	ImageURL    *string
+	Title       *string               `json:"title" form:"title" validate:"required,min=3,max=12"`
+	Price       *int                  `json:"price" form:"price" validate:"required,min=1,max=100000"`
+	Description *string               `json:"description" form:"description" validate:"required,min=3,max=120"`
+	Available   *bool                 `json:"available" form:"available" validate:"required"`
+	Image       *multipart.FileHeader `json:"-" form:"image" validate:"filesize"`
+	ImageKey    *string               `json:"image_key" form:"image_key" validate:"max=255"`
+	ImageURL    *string               `json:"-"`
+}
+
+type UploadImageInput struct {
+	Image *multipart.FileHeader `json:"-" form:"image" validate:"required,filesize"`
+}
+
+// PatchProductInput — документ товара, к которому применяется PATCH. Результат патча
+// должен оставаться полным товаром, поэтому основные поля обязательны.
+type PatchProductInput struct {
+	Title       *string `json:"title" validate:"required,min=3,max=12"`
+	Price       *int    `json:"price" validate:"required,min=1,max=100000"`
+	Description *string `json:"description" validate:"required,min=3,max=120"`
+	Available   *bool   `json:"available" validate:"required"`
+	ImageKey    *string `json:"image_key,omitempty" validate:"max=255"`
+}
+
+type ProductStatusInput struct {
+	Status string `json:"status" validate:"required,oneof=draft in_review published archived"`
+}
+
+// ProductScheduleInput заменяет расписание целиком: отсутствующее поле отменяет событие
+type ProductScheduleInput struct {
+	PublishAt   *time.Time `json:"publish_at"`
+	UnpublishAt *time.Time `json:"unpublish_at"`
 }
@@ -0,0 +1,14 @@
+package dto
+
+import "product-catalog/internal/auth"
+
+type SaveRoleInput struct {
+	Description string            `json:"description" validate:"max=200"`
+	Permissions []auth.Permission `json:"permissions"`
+}
+
+type RoleOutput struct {
+	Name        auth.Role         `json:"name"`
+	Description string            `json:"description"`
+	Permissions []auth.Permission `json:"permissions"`
+}
@@ -0,0 +1,20 @@
+package dto
+
+import "time"
+
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+type SessionOutput struct {
+	ID         string    `json:"id"`
+	Device     string    `json:"device"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+	// ID администратора, работающего в сессии от имени пользователя
+	ImpersonatedBy *int `json:"impersonated_by,omitempty"`
+}
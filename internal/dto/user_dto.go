@@ -1,21 +1,134 @@
 package dto
 
-import "product-catalog/internal/auth"
+import (
+	"product-catalog/internal/auth"
+	"time"
+)
 
 type CreateUserInput struct {
-	Username string `json:"username" validate:"jsonrequired,min=3,max=12"`
-	Password string `json:"password" validate:"required,min=6,max=12"`
+	Username string `json:"username" validate:"required,min=3,max=12"`
+	Password string `json:"password" validate:"required,min=8,max=128"`
 	Email    string `json:"email" validate:"required,email"`
 }
 
 type LoginInput struct {
-	Email    string `validate:"required,email"`
-	Password string `validate:"required,min=6,max=12"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,max=128"`
 }
 
 type UpdateUserInput struct {
-	Username *string    `json:"username" validate:"jsonrequired,min=3,max=12"`
-	Email    *string    `json:"email" validate:"required,email"`
-	Password *string    `json:"password" validate:"required,min=6,max=12"`
+	// Частичное обновление: проверяются только переданные поля
+	Username *string    `json:"username" validate:"min=3,max=12"`
+	Email    *string    `json:"email" validate:"email"`
+	Password *string    `json:"password" validate:"min=8,max=128"`
 	Role     *auth.Role `json:"role,omitempty"`
+	// Обязателен при смене собственных email или пароля
+	CurrentPassword *string `json:"current_password,omitempty"`
+}
+
+// PatchUserInput — документ пользователя, к которому применяется PATCH. Пароли
+// в документе не отдаются: их можно только добавить операцией патча.
+type PatchUserInput struct {
+	Username        *string    `json:"username" validate:"required,min=3,max=12"`
+	Email           *string    `json:"email" validate:"required,email"`
+	Role            *auth.Role `json:"role" validate:"required"`
+	Password        *string    `json:"password,omitempty" validate:"min=8,max=128"`
+	CurrentPassword *string    `json:"current_password,omitempty"`
+}
+
+type UserOutput struct {
+	ID        int        `json:"id"`
+	Username  string     `json:"username"`
+	Email     string     `json:"email"`
+	Role      auth.Role  `json:"role"`
+	Status    string     `json:"status"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type LoginOutput struct {
+	Token              string `json:"token,omitempty"`
+	ChallengeToken     string `json:"challenge_token,omitempty"`
+	TwoFactorRequired  bool   `json:"two_factor_required,omitempty"`
+	EnrollmentRequired bool   `json:"enrollment_required,omitempty"`
+}
+
+type ChallengeInput struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+}
+
+type TwoFactorLoginInput struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+type TwoFactorLoginOutput struct {
+	Token         string   `json:"token"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+type TwoFactorCodeInput struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type TwoFactorRequiredInput struct {
+	Required bool `json:"required"`
+}
+
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type RecoveryCodesOutput struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type ImpersonateInput struct {
+	UserID int    `json:"user_id" validate:"required"`
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+type ImpersonationOutput struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ImpersonationAuditOutput struct {
+	ID        int       `json:"id"`
+	ActorID   int       `json:"actor_id"`
+	UserID    int       `json:"user_id"`
+	SessionID string    `json:"session_id"`
+	Action    string    `json:"action"`
+	Method    string    `json:"method,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Status    *int      `json:"status,omitempty"`
+	IP        string    `json:"ip"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const (
+	BulkActionChangeRole = "change_role"
+	BulkActionDeactivate = "deactivate"
+
+	BulkStatusUpdated = "updated"
+	BulkStatusSkipped = "skipped"
+)
+
+type BulkUserActionInput struct {
+	Action  string     `json:"action" validate:"required,oneof=change_role deactivate"`
+	UserIDs []int      `json:"user_ids" validate:"required,min=1,max=500"`
+	Role    *auth.Role `json:"role,omitempty"`
+}
+
+type BulkUserActionResult struct {
+	UserID int    `json:"user_id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BulkUserActionOutput struct {
+	Updated int                    `json:"updated"`
+	Results []BulkUserActionResult `json:"results"`
 }
@@ -1,10 +1,39 @@
 package errors
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
-	ErrNotFound     = errors.New("not found")
-	ErrUnauthorized = errors.New("unauthorized")
-	ErrForbidden    = errors.New("forbidden")
-	ErrConflict     = errors.New("conflict")
+	ErrNotFound        = errors.New("not found")
+	ErrUnauthorized    = errors.New("unauthorized")
+	ErrForbidden       = errors.New("forbidden")
+	ErrConflict        = errors.New("conflict")
+	ErrTooManyRequests = errors.New("too many requests")
+	ErrInvalidInput    = errors.New("invalid input")
+	// ErrValidation — тело запроса не прошло проверку тегов validate
+	ErrValidation = errors.New("validation failed")
+	// ErrRateLimited — превышен лимит запросов (в отличие от блокировки после неудачных попыток)
+	ErrRateLimited = errors.New("rate limit exceeded")
+	// ErrUnsupportedMediaType — тело запроса пришло в неподдерживаемом формате
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	// ErrPreconditionFailed — версия из If-Match устарела: ресурс уже изменили
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrPreconditionRequired — изменение без If-Match не принимается
+	ErrPreconditionRequired = errors.New("precondition required")
+	// ErrIdempotencyKeyReused — Idempotency-Key уже использован с другим запросом
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused")
 )
+
+type RetryAfterError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return ErrTooManyRequests.Error()
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return ErrTooManyRequests
+}
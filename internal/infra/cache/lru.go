@@ -0,0 +1,76 @@
+// Package cache — кэши в памяти процесса поверх репозиториев.
+package cache
+
+import (
+	"container/list"
+	"time"
+)
+
+// LRU — кэш ограниченного размера с вытеснением давно не читанных записей и
+// сроком жизни каждой записи. Не потокобезопасен: блокировку держит владелец.
+type LRU[K comparable, V any] struct {
+	size  int
+	order *list.List
+	items map[K]*list.Element
+}
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+func NewLRU[K comparable, V any](size int) *LRU[K, V] {
+	return &LRU[K, V]{size: size, order: list.New(), items: make(map[K]*list.Element, size)}
+}
+
+// Get возвращает запись, если она есть и не истекла к моменту now
+func (c *LRU[K, V]) Get(key K, now time.Time) (V, bool) {
+	var zero V
+	el, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	e := el.Value.(*entry[K, V])
+	if !now.Before(e.expiresAt) {
+		c.removeElement(el)
+		return zero, false
+	}
+	c.order.MoveToFront(el)
+	return e.value, true
+}
+
+// Add записывает значение до expiresAt; при переполнении вытесняется самая
+// давняя запись
+func (c *LRU[K, V]) Add(key K, value V, expiresAt time.Time) {
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry[K, V])
+		e.value, e.expiresAt = value, expiresAt
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, expiresAt: expiresAt})
+	if c.order.Len() > c.size {
+		c.removeElement(c.order.Back())
+	}
+}
+
+func (c *LRU[K, V]) Remove(key K) {
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+func (c *LRU[K, V]) Purge() {
+	c.order.Init()
+	clear(c.items)
+}
+
+func (c *LRU[K, V]) Len() int {
+	return c.order.Len()
+}
+
+func (c *LRU[K, V]) removeElement(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*entry[K, V]).key)
+}
@@ -0,0 +1,66 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"product-catalog/internal/infra/cache"
+)
+
+func TestLRU(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Minute)
+
+	t.Run("evicts least recently used", func(t *testing.T) {
+		c := cache.NewLRU[string, int](2)
+		c.Add("a", 1, later)
+		c.Add("b", 2, later)
+		if _, ok := c.Get("a", now); !ok {
+			t.Fatal("a is missing")
+		}
+		c.Add("c", 3, later)
+		if _, ok := c.Get("b", now); ok {
+			t.Error("b survived eviction, want it evicted as least recently used")
+		}
+		if v, ok := c.Get("a", now); !ok || v != 1 {
+			t.Errorf("a = %d, %v; want 1, true", v, ok)
+		}
+		if c.Len() != 2 {
+			t.Errorf("len = %d, want 2", c.Len())
+		}
+	})
+
+	t.Run("expires", func(t *testing.T) {
+		c := cache.NewLRU[string, int](2)
+		c.Add("a", 1, later)
+		if _, ok := c.Get("a", later); ok {
+			t.Error("a is returned at its expiry time")
+		}
+		if c.Len() != 0 {
+			t.Errorf("len = %d, want expired entry removed", c.Len())
+		}
+	})
+
+	t.Run("overwrite refreshes value and expiry", func(t *testing.T) {
+		c := cache.NewLRU[string, int](2)
+		c.Add("a", 1, now)
+		c.Add("a", 2, later)
+		if v, ok := c.Get("a", now); !ok || v != 2 {
+			t.Errorf("a = %d, %v; want 2, true", v, ok)
+		}
+	})
+
+	t.Run("remove and purge", func(t *testing.T) {
+		c := cache.NewLRU[string, int](2)
+		c.Add("a", 1, later)
+		c.Add("b", 2, later)
+		c.Remove("a")
+		if _, ok := c.Get("a", now); ok {
+			t.Error("a survived Remove")
+		}
+		c.Purge()
+		if c.Len() != 0 {
+			t.Errorf("len after purge = %d", c.Len())
+		}
+	})
+}
@@ -0,0 +1,175 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/sync/singleflight"
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/service/product"
+	"sync"
+	"time"
+)
+
+type ProductConfig struct {
+	Size int
+	TTL  time.Duration
+	// NegativeTTL — сколько помнить, что товара нет
+	NegativeTTL time.Duration
+}
+
+type productKey struct {
+	tenantID  int
+	productID int
+}
+
+// ProductRepo кэширует GetByID поверх другого репозитория товаров. Одновременные
+// промахи по одному товару сводятся к одному запросу, отсутствие товара тоже
+// кэшируется. Свои изменения сбрасываются сразу, чужие (других реплик) — по
+// уведомлениям из БД через Invalidate.
+type ProductRepo struct {
+	product.Repository
+	cfg   ProductConfig
+	group singleflight.Group
+
+	mu    sync.Mutex
+	items *LRU[productKey, *domain.Product] // nil — товара нет
+	// gen растёт при каждом сбросе: загрузка, начатая до сброса, не попадёт в кэш
+	gen uint64
+}
+
+func NewProductRepo(inner product.Repository, cfg ProductConfig) *ProductRepo {
+	return &ProductRepo{Repository: inner, cfg: cfg, items: NewLRU[productKey, *domain.Product](cfg.Size)}
+}
+
+func (r *ProductRepo) GetByID(ctx context.Context, id int) (*domain.Product, error) {
+	tenantID, ok := auth.TenantIDFromContext(ctx)
+	if !ok {
+		return r.Repository.GetByID(ctx, id)
+	}
+	key := productKey{tenantID: tenantID, productID: id}
+
+	r.mu.Lock()
+	p, hit := r.items.Get(key, time.Now())
+	gen := r.gen
+	r.mu.Unlock()
+	if hit {
+		return found(p)
+	}
+
+	// Загрузку разделяют все ждущие, поэтому отмена запроса первого из них не
+	// должна её прерывать
+	v, err, _ := r.group.Do(fmt.Sprintf("%d:%d:%d", gen, tenantID, id), func() (any, error) {
+		p, err := r.Repository.GetByID(context.WithoutCancel(ctx), id)
+		switch {
+		case err == nil:
+			r.store(key, gen, p, r.cfg.TTL)
+		case errors.Is(err, custom.ErrNotFound):
+			r.store(key, gen, nil, r.cfg.NegativeTTL)
+		}
+		return p, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return found(v.(*domain.Product))
+}
+
+func (r *ProductRepo) store(key productKey, gen uint64, p *domain.Product, ttl time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.gen == gen {
+		r.items.Add(key, p, time.Now().Add(ttl))
+	}
+}
+
+// found отдаёт копию: вызывающий может менять товар, не портя кэш
+func found(p *domain.Product) (*domain.Product, error) {
+	if p == nil {
+		return nil, custom.ErrNotFound
+	}
+	cp := *p
+	return &cp, nil
+}
+
+func (r *ProductRepo) Create(ctx context.Context, p *domain.Product) (int, error) {
+	id, err := r.Repository.Create(ctx, p)
+	if err == nil {
+		r.invalidateCtx(ctx, id)
+	}
+	return id, err
+}
+
+// UpdateByID, Patch, DeleteByID и смена статуса сбрасывают запись и при ошибке: отказ по версии
+// означает, что в кэше, скорее всего, устаревший товар
+func (r *ProductRepo) UpdateByID(ctx context.Context, id int, p *domain.Product) error {
+	defer r.invalidateCtx(ctx, id)
+	return r.Repository.UpdateByID(ctx, id, p)
+}
+
+func (r *ProductRepo) Patch(ctx context.Context, id, version int, patch domain.ProductPatch) (*domain.Product, error) {
+	defer r.invalidateCtx(ctx, id)
+	return r.Repository.Patch(ctx, id, version, patch)
+}
+
+func (r *ProductRepo) DeleteByID(ctx context.Context, id, version int) error {
+	defer r.invalidateCtx(ctx, id)
+	return r.Repository.DeleteByID(ctx, id, version)
+}
+
+// Restore сбрасывает запись об отсутствии товара, пока он лежал в корзине
+func (r *ProductRepo) Restore(ctx context.Context, id int) (*domain.Product, error) {
+	defer r.invalidateCtx(ctx, id)
+	return r.Repository.Restore(ctx, id)
+}
+
+func (r *ProductRepo) UpdateStatus(ctx context.Context, id, version int, status domain.ProductStatus) (*domain.Product, error) {
+	defer r.invalidateCtx(ctx, id)
+	return r.Repository.UpdateStatus(ctx, id, version, status)
+}
+
+func (r *ProductRepo) UpdateSchedule(ctx context.Context, id, version int, schedule domain.ProductSchedule) (*domain.Product, error) {
+	defer r.invalidateCtx(ctx, id)
+	return r.Repository.UpdateSchedule(ctx, id, version, schedule)
+}
+
+// PublishDue и UnpublishDue меняют товары пачкой: сбрасываются все затронутые
+func (r *ProductRepo) PublishDue(ctx context.Context) ([]int, error) {
+	ids, err := r.Repository.PublishDue(ctx)
+	for _, id := range ids {
+		r.invalidateCtx(ctx, id)
+	}
+	return ids, err
+}
+
+func (r *ProductRepo) UnpublishDue(ctx context.Context) ([]int, error) {
+	ids, err := r.Repository.UnpublishDue(ctx)
+	for _, id := range ids {
+		r.invalidateCtx(ctx, id)
+	}
+	return ids, err
+}
+
+func (r *ProductRepo) invalidateCtx(ctx context.Context, id int) {
+	if tenantID, ok := auth.TenantIDFromContext(ctx); ok {
+		r.Invalidate(tenantID, id)
+	}
+}
+
+// Invalidate сбрасывает товар арендатора
+func (r *ProductRepo) Invalidate(tenantID, productID int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items.Remove(productKey{tenantID: tenantID, productID: productID})
+	r.gen++
+}
+
+// Reset сбрасывает весь кэш: нужен, когда уведомления могли быть потеряны
+func (r *ProductRepo) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items.Purge()
+	r.gen++
+}
@@ -0,0 +1,266 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/infra/cache"
+)
+
+// fakeRepo считает обращения к GetByID; release, если задан, задерживает ответ
+type fakeRepo struct {
+	mu       sync.Mutex
+	products map[int]domain.Product
+	gets     atomic.Int32
+	release  chan struct{}
+}
+
+func (r *fakeRepo) Create(context.Context, *domain.Product) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := len(r.products) + 1
+	r.products[id] = domain.Product{ID: id, Title: "new", Version: 1}
+	return id, nil
+}
+
+func (r *fakeRepo) GetByID(_ context.Context, id int) (*domain.Product, error) {
+	r.gets.Add(1)
+	if r.release != nil {
+		<-r.release
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.products[id]
+	if !ok {
+		return nil, custom.ErrNotFound
+	}
+	return &p, nil
+}
+
+func (r *fakeRepo) GetAll(context.Context, []domain.ProductStatus) ([]domain.Product, error) {
+	return nil, nil
+}
+
+func (r *fakeRepo) Revision(context.Context) (domain.CatalogRevision, error) {
+	return domain.CatalogRevision{}, nil
+}
+
+func (r *fakeRepo) UpdateByID(_ context.Context, id int, p *domain.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.products[id] = *p
+	return nil
+}
+
+func (r *fakeRepo) Patch(context.Context, int, int, domain.ProductPatch) (*domain.Product, error) {
+	return nil, custom.ErrPreconditionFailed
+}
+
+func (r *fakeRepo) DeleteByID(context.Context, int, int) error { return nil }
+
+func (r *fakeRepo) ListDeleted(context.Context, time.Duration) ([]domain.Product, error) {
+	return nil, nil
+}
+
+func (r *fakeRepo) Restore(_ context.Context, id int) (*domain.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p := domain.Product{ID: id, Title: "restored"}
+	r.products[id] = p
+	return &p, nil
+}
+
+func (r *fakeRepo) Purge(context.Context, int) error { return nil }
+
+func (r *fakeRepo) ImageInUse(context.Context, string, int) (bool, error) { return false, nil }
+
+func (r *fakeRepo) UpdateStatus(context.Context, int, int, domain.ProductStatus) (*domain.Product, error) {
+	return nil, custom.ErrPreconditionFailed
+}
+
+func (r *fakeRepo) UpdateSchedule(context.Context, int, int, domain.ProductSchedule) (*domain.Product, error) {
+	return nil, custom.ErrPreconditionFailed
+}
+
+// PublishDue публикует все черновики, как будто их время наступило
+func (r *fakeRepo) PublishDue(context.Context) ([]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var ids []int
+	for id, p := range r.products {
+		if p.Status == domain.ProductStatusDraft {
+			p.Status = domain.ProductStatusPublished
+			r.products[id] = p
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (r *fakeRepo) UnpublishDue(context.Context) ([]int, error) { return nil, nil }
+
+func newCache(inner *fakeRepo) *cache.ProductRepo {
+	return cache.NewProductRepo(inner, cache.ProductConfig{Size: 10, TTL: time.Minute, NegativeTTL: time.Minute})
+}
+
+func TestProductCacheHit(t *testing.T) {
+	inner := &fakeRepo{products: map[int]domain.Product{1: {ID: 1, Title: "lamp"}}}
+	repo := newCache(inner)
+	ctx := auth.WithTenant(context.Background(), 1)
+
+	first, err := repo.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	first.Title = "mutated by caller"
+	second, err := repo.GetByID(ctx, 1)
+	if err != nil || second.Title != "lamp" {
+		t.Fatalf("second get = %+v, %v; want cached lamp", second, err)
+	}
+	if n := inner.gets.Load(); n != 1 {
+		t.Errorf("inner gets = %d, want 1", n)
+	}
+
+	// Товары разных арендаторов с одним id не смешиваются
+	if _, err = repo.GetByID(auth.WithTenant(context.Background(), 2), 1); err != nil {
+		t.Fatalf("get in other tenant: %v", err)
+	}
+	if n := inner.gets.Load(); n != 2 {
+		t.Errorf("inner gets = %d, want a separate load for the other tenant", n)
+	}
+}
+
+func TestProductCacheNegative(t *testing.T) {
+	inner := &fakeRepo{products: map[int]domain.Product{}}
+	repo := newCache(inner)
+	ctx := auth.WithTenant(context.Background(), 1)
+
+	for range 2 {
+		if _, err := repo.GetByID(ctx, 1); !errors.Is(err, custom.ErrNotFound) {
+			t.Fatalf("err = %v, want ErrNotFound", err)
+		}
+	}
+	if n := inner.gets.Load(); n != 1 {
+		t.Errorf("inner gets = %d, want not-found cached", n)
+	}
+
+	// Созданный товар сбрасывает запись об отсутствии
+	id, _ := repo.Create(ctx, &domain.Product{})
+	if p, err := repo.GetByID(ctx, id); err != nil || p.ID != id {
+		t.Fatalf("get after create = %+v, %v", p, err)
+	}
+
+	// Как и восстановленный из корзины
+	if _, err := repo.GetByID(ctx, 5); !errors.Is(err, custom.ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+	if _, err := repo.Restore(ctx, 5); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if p, err := repo.GetByID(ctx, 5); err != nil || p.Title != "restored" {
+		t.Fatalf("get after restore = %+v, %v", p, err)
+	}
+}
+
+func TestProductCacheSingleflight(t *testing.T) {
+	inner := &fakeRepo{products: map[int]domain.Product{1: {ID: 1}}, release: make(chan struct{})}
+	repo := newCache(inner)
+	ctx := auth.WithTenant(context.Background(), 1)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := repo.GetByID(ctx, 1); err != nil {
+				t.Errorf("get: %v", err)
+			}
+		}()
+	}
+	// Даём горутинам встать в ожидание одной загрузки
+	time.Sleep(50 * time.Millisecond)
+	close(inner.release)
+	wg.Wait()
+
+	if n := inner.gets.Load(); n != 1 {
+		t.Errorf("inner gets = %d, want concurrent misses collapsed into 1", n)
+	}
+}
+
+func TestProductCacheInvalidation(t *testing.T) {
+	inner := &fakeRepo{products: map[int]domain.Product{1: {ID: 1, Title: "lamp"}}}
+	repo := newCache(inner)
+	ctx := auth.WithTenant(context.Background(), 1)
+
+	if _, err := repo.GetByID(ctx, 1); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if err := repo.UpdateByID(ctx, 1, &domain.Product{ID: 1, Title: "desk"}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if p, _ := repo.GetByID(ctx, 1); p.Title != "desk" {
+		t.Errorf("title after own update = %q, want desk", p.Title)
+	}
+
+	// Изменение с другой реплики приходит уведомлением
+	inner.products[1] = domain.Product{ID: 1, Title: "chair"}
+	repo.Invalidate(1, 1)
+	if p, _ := repo.GetByID(ctx, 1); p.Title != "chair" {
+		t.Errorf("title after notification = %q, want chair", p.Title)
+	}
+
+	// Отказ по версии тоже сбрасывает запись
+	inner.products[1] = domain.Product{ID: 1, Title: "sofa"}
+	if _, err := repo.Patch(ctx, 1, 1, domain.ProductPatch{}); !errors.Is(err, custom.ErrPreconditionFailed) {
+		t.Fatalf("patch err = %v", err)
+	}
+	if p, _ := repo.GetByID(ctx, 1); p.Title != "sofa" {
+		t.Errorf("title after rejected patch = %q, want sofa", p.Title)
+	}
+}
+
+func TestProductCacheDropsLoadStartedBeforeInvalidation(t *testing.T) {
+	inner := &fakeRepo{products: map[int]domain.Product{1: {ID: 1, Title: "old"}}, release: make(chan struct{})}
+	repo := newCache(inner)
+	ctx := auth.WithTenant(context.Background(), 1)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = repo.GetByID(ctx, 1)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	repo.Invalidate(1, 1)
+	close(inner.release)
+	<-done
+
+	inner.mu.Lock()
+	inner.products[1] = domain.Product{ID: 1, Title: "new"}
+	inner.mu.Unlock()
+	if p, _ := repo.GetByID(ctx, 1); p.Title != "new" {
+		t.Errorf("title = %q, want the load started before invalidation not cached", p.Title)
+	}
+}
+
+func TestProductCacheScheduledPublish(t *testing.T) {
+	inner := &fakeRepo{products: map[int]domain.Product{1: {ID: 1, Status: domain.ProductStatusDraft}}}
+	repo := newCache(inner)
+	ctx := auth.WithTenant(context.Background(), 1)
+
+	if _, err := repo.GetByID(ctx, 1); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if ids, err := repo.PublishDue(ctx); err != nil || len(ids) != 1 {
+		t.Fatalf("publish due = %v, %v", ids, err)
+	}
+	if p, _ := repo.GetByID(ctx, 1); p.Status != domain.ProductStatusPublished {
+		t.Errorf("status after scheduled publish = %q, want published", p.Status)
+	}
+}
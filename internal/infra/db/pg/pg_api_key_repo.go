@@ -0,0 +1,100 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+)
+
+type APIKeyRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewAPIKeyRepo(db *pgxpool.Pool) *APIKeyRepo {
+	return &APIKeyRepo{db: db}
+}
+
+func (r *APIKeyRepo) Create(ctx context.Context, key *domain.APIKey) (int, error) {
+	const query = `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+	var id int
+	err := r.db.QueryRow(ctx, query, key.UserID, key.Name, key.Prefix, key.KeyHash, key.Scopes, key.ExpiresAt, key.CreatedAt).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create api key: %w", err)
+	}
+	return id, nil
+}
+
+func (r *APIKeyRepo) ListByUserID(ctx context.Context, userID int) ([]domain.APIKey, error) {
+	const query = `
+		SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM api_keys WHERE user_id = $1 ORDER BY id
+	`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []domain.APIKey
+	for rows.Next() {
+		var k domain.APIKey
+		err = rows.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.Scopes, &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt, &k.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func (r *APIKeyRepo) Revoke(ctx context.Context, userID, id int) error {
+	const query = `UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+	tag, err := r.db.Exec(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return custom.ErrNotFound
+	}
+	return nil
+}
+
+// GetByHash возвращает ключ вместе с текущей ролью владельца. Владелец ищется
+// под RLS, поэтому ключ пользователя другого арендатора не найдётся.
+func (r *APIKeyRepo) GetByHash(ctx context.Context, hash string) (*domain.APIKey, auth.Role, error) {
+	const query = `
+		SELECT k.id, k.user_id, k.name, k.prefix, k.scopes, k.expires_at, k.last_used_at, k.revoked_at, k.created_at, u.role
+		FROM api_keys k JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = $1 AND u.status = 'active'
+	`
+	var k domain.APIKey
+	var role auth.Role
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, hash).Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.Scopes, &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt, &k.CreatedAt, &role)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, "", custom.ErrNotFound
+		}
+		return nil, "", fmt.Errorf("failed to get api key: %w", err)
+	}
+	return &k, role, nil
+}
+
+func (r *APIKeyRepo) TouchLastUsed(ctx context.Context, id int) error {
+	const query = `UPDATE api_keys SET last_used_at = NOW() WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to update api key last used: %w", err)
+	}
+	return nil
+}
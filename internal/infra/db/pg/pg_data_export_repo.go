@@ -0,0 +1,125 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+	"time"
+)
+
+type DataExportRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewDataExportRepo(db *pgxpool.Pool) *DataExportRepo {
+	return &DataExportRepo{db: db}
+}
+
+func (r *DataExportRepo) Create(ctx context.Context, export *domain.DataExport) (int, error) {
+	const query = `INSERT INTO data_exports (user_id, status, created_at) VALUES ($1, $2, $3) RETURNING id`
+	var id int
+	err := r.db.QueryRow(ctx, query, export.UserID, export.Status, export.CreatedAt).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return 0, custom.ErrConflict
+		}
+		return 0, fmt.Errorf("failed to create data export: %w", err)
+	}
+	return id, nil
+}
+
+func (r *DataExportRepo) GetLatestByUserID(ctx context.Context, userID int) (*domain.DataExport, error) {
+	const query = `
+		SELECT id, user_id, status, object_key, error, created_at, completed_at, expires_at
+		FROM data_exports WHERE user_id = $1
+		ORDER BY id DESC LIMIT 1
+	`
+	var e domain.DataExport
+	err := r.db.QueryRow(ctx, query, userID).Scan(&e.ID, &e.UserID, &e.Status, &e.ObjectKey, &e.Error, &e.CreatedAt, &e.CompletedAt, &e.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+	return &e, nil
+}
+
+func (r *DataExportRepo) MarkReady(ctx context.Context, id int, objectKey string, expiresAt time.Time) error {
+	const query = `
+		UPDATE data_exports SET status = 'ready', object_key = $2, completed_at = NOW(), expires_at = $3
+		WHERE id = $1 AND status = 'pending'
+	`
+	tag, err := r.db.Exec(ctx, query, id, objectKey, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to mark data export ready: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return custom.ErrNotFound
+	}
+	return nil
+}
+
+func (r *DataExportRepo) MarkFailed(ctx context.Context, id int, reason string) error {
+	const query = `
+		UPDATE data_exports SET status = 'failed', error = $2, completed_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+	`
+	_, err := r.db.Exec(ctx, query, id, reason)
+	if err != nil {
+		return fmt.Errorf("failed to mark data export failed: %w", err)
+	}
+	return nil
+}
+
+// ListExpired возвращает выгрузки, которые пора удалить: с истёкшей ссылкой,
+// неудачные, зависшие в pending дольше staleBefore и все выгрузки обезличенных пользователей.
+// Выгрузки берутся только для пользователей арендатора из контекста.
+func (r *DataExportRepo) ListExpired(ctx context.Context, staleBefore time.Time) ([]domain.DataExport, error) {
+	const query = `
+		SELECT e.id, e.user_id, e.status, e.object_key, e.error, e.created_at, e.completed_at, e.expires_at
+		FROM data_exports e
+		JOIN users u ON u.id = e.user_id
+		WHERE e.expires_at < NOW()
+		   OR (e.status = 'failed' AND e.completed_at < $1)
+		   OR (e.status = 'pending' AND e.created_at < $1)
+		   OR u.status = 'erased'
+	`
+	var exports []domain.DataExport
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, staleBefore)
+		if err != nil {
+			return fmt.Errorf("failed to list expired data exports: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var e domain.DataExport
+			err = rows.Scan(&e.ID, &e.UserID, &e.Status, &e.ObjectKey, &e.Error, &e.CreatedAt, &e.CompletedAt, &e.ExpiresAt)
+			if err != nil {
+				return fmt.Errorf("failed to scan data export: %w", err)
+			}
+			exports = append(exports, e)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+func (r *DataExportRepo) Delete(ctx context.Context, id int) error {
+	const query = `DELETE FROM data_exports WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete data export: %w", err)
+	}
+	return nil
+}
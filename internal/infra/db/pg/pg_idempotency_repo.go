@@ -0,0 +1,102 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+	"time"
+)
+
+type IdempotencyRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewIdempotencyRepo(db *pgxpool.Pool) *IdempotencyRepo {
+	return &IdempotencyRepo{db: db}
+}
+
+// Reserve занимает ключ за новым запросом. Истёкший ключ занимается заново;
+// false — ключ уже занят действующей записью.
+func (r *IdempotencyRepo) Reserve(ctx context.Context, rec *domain.IdempotencyRecord) (bool, error) {
+	const query = `
+		INSERT INTO idempotency_keys (user_id, key, fingerprint, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint, status_code = NULL, headers = '{}', body = '',
+			created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+		RETURNING true
+	`
+	var reserved bool
+	err := r.db.QueryRow(ctx, query, rec.UserID, rec.Key, rec.Fingerprint, rec.CreatedAt, rec.ExpiresAt).Scan(&reserved)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	return reserved, nil
+}
+
+func (r *IdempotencyRepo) Get(ctx context.Context, userID int, key string) (*domain.IdempotencyRecord, error) {
+	const query = `
+		SELECT user_id, key, fingerprint, COALESCE(status_code, 0), headers, body, created_at, expires_at
+		FROM idempotency_keys WHERE user_id = $1 AND key = $2
+	`
+	var rec domain.IdempotencyRecord
+	err := r.db.QueryRow(ctx, query, userID, key).
+		Scan(&rec.UserID, &rec.Key, &rec.Fingerprint, &rec.StatusCode, &rec.Header, &rec.Body, &rec.CreatedAt, &rec.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	return &rec, nil
+}
+
+// Complete сохраняет ответ на запрос, занявший ключ
+func (r *IdempotencyRepo) Complete(ctx context.Context, rec *domain.IdempotencyRecord) error {
+	const query = `
+		UPDATE idempotency_keys SET status_code = $3, headers = $4, body = $5
+		WHERE user_id = $1 AND key = $2 AND fingerprint = $6 AND status_code IS NULL
+	`
+	// nil pgx передаёт как NULL, а колонки NOT NULL
+	header, body := rec.Header, rec.Body
+	if header == nil {
+		header = map[string]string{}
+	}
+	if body == nil {
+		body = []byte{}
+	}
+	tag, err := r.db.Exec(ctx, query, rec.UserID, rec.Key, rec.StatusCode, header, body, rec.Fingerprint)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return custom.ErrNotFound
+	}
+	return nil
+}
+
+// Release освобождает ключ, если запрос так и не получил ответа, который стоит повторять
+func (r *IdempotencyRepo) Release(ctx context.Context, userID int, key string) error {
+	const query = `DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND status_code IS NULL`
+	if _, err := r.db.Exec(ctx, query, userID, key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (r *IdempotencyRepo) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	const query = `DELETE FROM idempotency_keys WHERE expires_at <= $1`
+	tag, err := r.db.Exec(ctx, query, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,124 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+)
+
+// IdentityRepo работает с user_identities под RLS: пара provider и subject
+// уникальна только в пределах арендатора.
+type IdentityRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewIdentityRepo(db *pgxpool.Pool) *IdentityRepo {
+	return &IdentityRepo{db: db}
+}
+
+func (r *IdentityRepo) GetByProviderSubject(ctx context.Context, provider, subject string) (*domain.UserIdentity, error) {
+	const query = `
+		SELECT id, user_id, provider, subject, email, created_at, last_login_at
+		FROM user_identities WHERE provider = $1 AND subject = $2
+	`
+	var i domain.UserIdentity
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, provider, subject).Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.CreatedAt, &i.LastLoginAt)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get identity: %w", err)
+	}
+	return &i, nil
+}
+
+func (r *IdentityRepo) ListByUserID(ctx context.Context, userID int) ([]domain.UserIdentity, error) {
+	const query = `
+		SELECT id, user_id, provider, subject, email, created_at, last_login_at
+		FROM user_identities WHERE user_id = $1 ORDER BY id
+	`
+	var identities []domain.UserIdentity
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var i domain.UserIdentity
+			if err = rows.Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.CreatedAt, &i.LastLoginAt); err != nil {
+				return fmt.Errorf("failed to scan identity: %w", err)
+			}
+			identities = append(identities, i)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	return identities, nil
+}
+
+func (r *IdentityRepo) Create(ctx context.Context, identity *domain.UserIdentity) error {
+	return inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return createIdentity(ctx, tx, identity)
+	})
+}
+
+// CreateUserWithIdentity создаёт пользователя и привязанную identity в одной транзакции.
+func (r *IdentityRepo) CreateUserWithIdentity(ctx context.Context, user *domain.User, identity *domain.UserIdentity) error {
+	return inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return createUserWithIdentity(ctx, tx, user, identity)
+	})
+}
+
+func createUserWithIdentity(ctx context.Context, tx pgx.Tx, user *domain.User, identity *domain.UserIdentity) error {
+	const userQuery = `INSERT INTO users (username, email, password_hash, role, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	err := tx.QueryRow(ctx, userQuery, user.Username, user.Email, user.PasswordHash, user.Role, user.CreatedAt).Scan(&user.ID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return custom.ErrConflict
+		}
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	identity.UserID = user.ID
+	return createIdentity(ctx, tx, identity)
+}
+
+func createIdentity(ctx context.Context, tx pgx.Tx, identity *domain.UserIdentity) error {
+	const query = `
+		INSERT INTO user_identities (user_id, provider, subject, email, created_at, last_login_at)
+		VALUES ($1, $2, $3, $4, $5, $5) RETURNING id
+	`
+	err := tx.QueryRow(ctx, query, identity.UserID, identity.Provider, identity.Subject, identity.Email, identity.CreatedAt).Scan(&identity.ID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return custom.ErrConflict
+		}
+		return fmt.Errorf("failed to create identity: %w", err)
+	}
+	return nil
+}
+
+func (r *IdentityRepo) TouchLastLogin(ctx context.Context, id int) error {
+	const query = `UPDATE user_identities SET last_login_at = NOW() WHERE id = $1`
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, query, id)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update identity last login: %w", err)
+	}
+	return nil
+}
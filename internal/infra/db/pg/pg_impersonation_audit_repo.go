@@ -0,0 +1,76 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"product-catalog/internal/domain"
+)
+
+type ImpersonationAuditRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewImpersonationAuditRepo(db *pgxpool.Pool) *ImpersonationAuditRepo {
+	return &ImpersonationAuditRepo{db: db}
+}
+
+func (r *ImpersonationAuditRepo) Create(ctx context.Context, record *domain.ImpersonationAuditRecord) (int, error) {
+	const query = `
+		INSERT INTO impersonation_audit (actor_id, user_id, session_id, action, method, path, status, ip, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id
+	`
+	var id int
+	err := r.db.QueryRow(ctx, query, record.ActorID, record.UserID, record.SessionID, record.Action, record.Method,
+		record.Path, record.Status, record.IP, record.Reason, record.CreatedAt).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create impersonation audit record: %w", err)
+	}
+	return id, nil
+}
+
+func (r *ImpersonationAuditRepo) SetStatus(ctx context.Context, id, status int) error {
+	const query = `UPDATE impersonation_audit SET status = $1 WHERE id = $2`
+	_, err := r.db.Exec(ctx, query, status, id)
+	if err != nil {
+		return fmt.Errorf("failed to update impersonation audit record: %w", err)
+	}
+	return nil
+}
+
+// List возвращает записи журнала, отфильтрованные по пользователю и/или администратору (0 — без фильтра).
+// Журнал общий для всех арендаторов, поэтому записи отбираются по пользователю, видимому под RLS.
+func (r *ImpersonationAuditRepo) List(ctx context.Context, userID, actorID, limit int) ([]domain.ImpersonationAuditRecord, error) {
+	const query = `
+		SELECT a.id, COALESCE(a.actor_id, 0), COALESCE(a.user_id, 0), a.session_id, a.action, a.method, a.path, a.status, a.ip, a.reason, a.created_at
+		FROM impersonation_audit a
+		JOIN users u ON u.id = a.user_id
+		WHERE ($1 = 0 OR a.user_id = $1) AND ($2 = 0 OR a.actor_id = $2)
+		ORDER BY a.id DESC
+		LIMIT $3
+	`
+	var records []domain.ImpersonationAuditRecord
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, userID, actorID, limit)
+		if err != nil {
+			return fmt.Errorf("failed to list impersonation audit: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var rec domain.ImpersonationAuditRecord
+			err = rows.Scan(&rec.ID, &rec.ActorID, &rec.UserID, &rec.SessionID, &rec.Action, &rec.Method, &rec.Path, &rec.Status, &rec.IP, &rec.Reason, &rec.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("failed to scan impersonation audit record: %w", err)
+			}
+			records = append(records, rec)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
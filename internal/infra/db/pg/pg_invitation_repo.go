@@ -0,0 +1,191 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+	"time"
+)
+
+type InvitationRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewInvitationRepo(db *pgxpool.Pool) *InvitationRepo {
+	return &InvitationRepo{db: db}
+}
+
+const invitationColumns = `id, email, role, token_hash, COALESCE(invited_by, 0), created_at, expires_at, accepted_at, accepted_user_id, revoked_at`
+
+func scanInvitation(row pgx.Row) (*domain.Invitation, error) {
+	var inv domain.Invitation
+	err := row.Scan(&inv.ID, &inv.Email, &inv.Role, &inv.TokenHash, &inv.InvitedBy, &inv.CreatedAt, &inv.ExpiresAt, &inv.AcceptedAt, &inv.AcceptedUserID, &inv.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (r *InvitationRepo) Create(ctx context.Context, inv *domain.Invitation) (int, error) {
+	const query = `
+		INSERT INTO invitations (email, role, token_hash, invited_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id
+	`
+	var id int
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, inv.Email, inv.Role, inv.TokenHash, inv.InvitedBy, inv.CreatedAt, inv.ExpiresAt).Scan(&id)
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case "23505":
+				return 0, custom.ErrConflict
+			case "23503":
+				return 0, fmt.Errorf("unknown role %q: %w", inv.Role, custom.ErrInvalidInput)
+			}
+		}
+		return 0, fmt.Errorf("failed to create invitation: %w", err)
+	}
+	return id, nil
+}
+
+func (r *InvitationRepo) GetByID(ctx context.Context, id int) (*domain.Invitation, error) {
+	query := `SELECT ` + invitationColumns + ` FROM invitations WHERE id = $1`
+	var inv *domain.Invitation
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) (err error) {
+		inv, err = scanInvitation(tx.QueryRow(ctx, query, id))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+	return inv, nil
+}
+
+// GetOpenByTokenHash ищет неиспользованное и неотозванное приглашение; срок действия проверяет сервис.
+func (r *InvitationRepo) GetOpenByTokenHash(ctx context.Context, tokenHash string) (*domain.Invitation, error) {
+	query := `SELECT ` + invitationColumns + ` FROM invitations WHERE token_hash = $1 AND accepted_at IS NULL AND revoked_at IS NULL`
+	var inv *domain.Invitation
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) (err error) {
+		inv, err = scanInvitation(tx.QueryRow(ctx, query, tokenHash))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+	return inv, nil
+}
+
+// ListOpen возвращает приглашения, которые ещё не приняты и не отозваны, включая просроченные.
+func (r *InvitationRepo) ListOpen(ctx context.Context) ([]domain.Invitation, error) {
+	query := `SELECT ` + invitationColumns + ` FROM invitations WHERE accepted_at IS NULL AND revoked_at IS NULL ORDER BY id DESC`
+	var invitations []domain.Invitation
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query)
+		if err != nil {
+			return fmt.Errorf("failed to list invitations: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			inv, err := scanInvitation(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan invitation: %w", err)
+			}
+			invitations = append(invitations, *inv)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// Reissue заменяет токен открытого приглашения: старая ссылка перестаёт работать.
+func (r *InvitationRepo) Reissue(ctx context.Context, id int, tokenHash string, expiresAt time.Time) error {
+	const query = `
+		UPDATE invitations SET token_hash = $2, expires_at = $3
+		WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL
+	`
+	var tag pgconn.CommandTag
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) (err error) {
+		tag, err = tx.Exec(ctx, query, id, tokenHash, expiresAt)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reissue invitation: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return custom.ErrNotFound
+	}
+	return nil
+}
+
+func (r *InvitationRepo) Revoke(ctx context.Context, id int) error {
+	const query = `UPDATE invitations SET revoked_at = NOW() WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL`
+	var tag pgconn.CommandTag
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) (err error) {
+		tag, err = tx.Exec(ctx, query, id)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return custom.ErrNotFound
+	}
+	return nil
+}
+
+// Accept в одной транзакции создаёт пользователя и закрывает приглашение,
+// так что один токен нельзя использовать дважды.
+func (r *InvitationRepo) Accept(ctx context.Context, id int, user *domain.User) error {
+	return inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return acceptInvitation(ctx, tx, id, user)
+	})
+}
+
+func acceptInvitation(ctx context.Context, tx pgx.Tx, id int, user *domain.User) error {
+	var locked int
+	err := tx.QueryRow(ctx, `
+		SELECT id FROM invitations
+		WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()
+		FOR UPDATE
+	`, id).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return custom.ErrNotFound
+		}
+		return fmt.Errorf("failed to lock invitation: %w", err)
+	}
+
+	err = tx.QueryRow(ctx,
+		`INSERT INTO users (username, email, password_hash, role, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		user.Username, user.Email, user.PasswordHash, user.Role, user.CreatedAt,
+	).Scan(&user.ID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return custom.ErrConflict
+		}
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, `UPDATE invitations SET accepted_at = NOW(), accepted_user_id = $2 WHERE id = $1`, id, user.ID); err != nil {
+		return fmt.Errorf("failed to accept invitation: %w", err)
+	}
+	return nil
+}
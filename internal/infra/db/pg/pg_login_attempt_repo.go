@@ -0,0 +1,91 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"product-catalog/internal/auth"
+	"time"
+)
+
+type LoginAttemptRepo struct {
+	db     *pgxpool.Pool
+	scope  string
+	policy auth.LockoutPolicy
+}
+
+func NewLoginAttemptRepo(db *pgxpool.Pool, scope string, policy auth.LockoutPolicy) *LoginAttemptRepo {
+	return &LoginAttemptRepo{db: db, scope: scope, policy: policy}
+}
+
+func (r *LoginAttemptRepo) RetryAfter(ctx context.Context, key string) (time.Duration, error) {
+	const query = `SELECT locked_until FROM login_attempts WHERE scope = $1 AND key = $2`
+	var lockedUntil *time.Time
+	err := r.db.QueryRow(ctx, query, r.scope, key).Scan(&lockedUntil)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get login attempts: %w", err)
+	}
+	if lockedUntil == nil {
+		return 0, nil
+	}
+	if d := time.Until(*lockedUntil); d > 0 {
+		return d, nil
+	}
+	return 0, nil
+}
+
+func (r *LoginAttemptRepo) RegisterFailure(ctx context.Context, key string) error {
+	const query = `
+		INSERT INTO login_attempts (scope, key, failures, last_failure_at)
+		VALUES ($1, $2, 1, $3)
+		ON CONFLICT (scope, key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < $4 THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure_at = $3
+		RETURNING failures
+	`
+	now := time.Now()
+	var failures int
+	err := r.db.QueryRow(ctx, query, r.scope, key, now, now.Add(-r.policy.Window)).Scan(&failures)
+	if err != nil {
+		return fmt.Errorf("failed to register login failure: %w", err)
+	}
+
+	d := r.policy.LockFor(failures)
+	if d == 0 {
+		return nil
+	}
+	const lockQuery = `UPDATE login_attempts SET locked_until = $1 WHERE scope = $2 AND key = $3`
+	if _, err = r.db.Exec(ctx, lockQuery, now.Add(d), r.scope, key); err != nil {
+		return fmt.Errorf("failed to lock login: %w", err)
+	}
+	return nil
+}
+
+func (r *LoginAttemptRepo) Reset(ctx context.Context, key string) error {
+	const query = `DELETE FROM login_attempts WHERE scope = $1 AND key = $2`
+	_, err := r.db.Exec(ctx, query, r.scope, key)
+	if err != nil {
+		return fmt.Errorf("failed to reset login attempts: %w", err)
+	}
+	return nil
+}
+
+// Sweep удаляет записи своей области, которые уже ни на что не влияют: блокировка
+// истекла, а последняя неудача вышла за окно. Возвращает число удалённых записей.
+func (r *LoginAttemptRepo) Sweep(ctx context.Context) (int, error) {
+	const query = `
+		DELETE FROM login_attempts
+		WHERE scope = $1 AND last_failure_at < $2 AND (locked_until IS NULL OR locked_until <= $3)
+	`
+	now := time.Now()
+	tag, err := r.db.Exec(ctx, query, r.scope, now.Add(-r.policy.Window), now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sweep login attempts: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
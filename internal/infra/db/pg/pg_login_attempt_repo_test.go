@@ -0,0 +1,70 @@
+package pg_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"product-catalog/internal/auth"
+	"product-catalog/internal/infra/db/pg"
+)
+
+func TestLoginAttemptSweep(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	ctx := context.Background()
+
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+	applySchema(t, db)
+
+	scope := fmt.Sprintf("sweep-%d", time.Now().UnixNano())
+	now := time.Now()
+	expired, locked := now.Add(-time.Minute), now.Add(time.Hour)
+	rows := []struct {
+		key         string
+		lastFailure time.Time
+		lockedUntil *time.Time
+		wantKept    bool
+	}{
+		{key: "stale", lastFailure: now.Add(-2 * time.Hour), wantKept: false},
+		{key: "stale-lock-expired", lastFailure: now.Add(-2 * time.Hour), lockedUntil: &expired, wantKept: false},
+		{key: "still-locked", lastFailure: now.Add(-2 * time.Hour), lockedUntil: &locked, wantKept: true},
+		{key: "in-window", lastFailure: now.Add(-time.Minute), wantKept: true},
+	}
+	for _, r := range rows {
+		_, err = db.Exec(ctx, `INSERT INTO login_attempts (scope, key, failures, last_failure_at, locked_until) VALUES ($1, $2, 1, $3, $4)`,
+			scope, r.key, r.lastFailure, r.lockedUntil)
+		if err != nil {
+			t.Fatalf("insert %s: %v", r.key, err)
+		}
+	}
+
+	repo := pg.NewLoginAttemptRepo(db, scope, auth.LockoutPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour})
+	removed, err := repo.Sweep(ctx)
+	if err != nil {
+		t.Fatalf("sweep: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("removed = %d; want 2", removed)
+	}
+	for _, r := range rows {
+		var kept bool
+		err = db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM login_attempts WHERE scope = $1 AND key = $2)`, scope, r.key).Scan(&kept)
+		if err != nil {
+			t.Fatalf("check %s: %v", r.key, err)
+		}
+		if kept != r.wantKept {
+			t.Errorf("%s kept = %v; want %v", r.key, kept, r.wantKept)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"strconv"
+	"strings"
+)
+
+// ProductChangesChannel — канал NOTIFY, в который триггер на products пишет
+// "<tenant_id>:<product_id>" после каждого изменения
+const ProductChangesChannel = "product_changes"
+
+// ProductListener подписывается на изменения товаров, сделанные любой репликой
+type ProductListener struct {
+	db *pgxpool.Pool
+}
+
+func NewProductListener(db *pgxpool.Pool) *ProductListener {
+	return &ProductListener{db: db}
+}
+
+// Listen держит отдельное соединение с LISTEN и вызывает onChange на каждое
+// изменение товара. onSubscribe вызывается сразу после подписки: всё, что
+// изменилось до неё, уведомлений не получит. Возвращает ошибку при потере
+// соединения и nil при отмене ctx.
+func (l *ProductListener) Listen(ctx context.Context, onSubscribe func(), onChange func(tenantID, productID int)) error {
+	pooled, err := l.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	// Соединение с подпиской не возвращается в пул: иначе уведомления копились
+	// бы в нём у случайных запросов
+	conn := pooled.Hijack()
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	if _, err = conn.Exec(ctx, "LISTEN "+ProductChangesChannel); err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	onSubscribe()
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to wait for notification: %w", err)
+		}
+		tenant, product, ok := strings.Cut(n.Payload, ":")
+		tenantID, terr := strconv.Atoi(tenant)
+		productID, perr := strconv.Atoi(product)
+		if !ok || terr != nil || perr != nil {
+			continue
+		}
+		onChange(tenantID, productID)
+	}
+}
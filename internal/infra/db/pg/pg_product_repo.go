@@ -8,8 +8,17 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"product-catalog/internal/domain"
 	custom "product-catalog/internal/errors"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// liveProduct отделяет товары вне корзины для staleOrMissing
+const liveProduct = "deleted_at IS NULL"
+
+// ProductRepo работает с таблицей products под RLS: каждый запрос выполняется
+// через inTenant и видит только товары арендатора из контекста. Удалённые товары
+// лежат в корзине (deleted_at задан) и видны только методам корзины.
 type ProductRepo struct {
 	db *pgxpool.Pool
 }
@@ -19,9 +28,11 @@ func NewProductRepo(db *pgxpool.Pool) *ProductRepo {
 }
 
 func (r *ProductRepo) Create(ctx context.Context, product *domain.Product) (int, error) {
-	const query = `INSERT INTO products (title, price, description, image_url, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	const query = `INSERT INTO products (title, price, description, available, image_url, created_by, status, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8) RETURNING id`
 	var productID int
-	err := r.db.QueryRow(ctx, query, product.Title, product.Price, product.Description, product.ImageURL, product.CreatedAt).Scan(&productID)
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, product.Title, product.Price, product.Description, product.Available, product.ImageURL, product.CreatedBy, product.Status, product.CreatedAt).Scan(&productID)
+	})
 	if err != nil {
 		return 0, fmt.Errorf("failed to create product: %w", err)
 	}
@@ -29,9 +40,11 @@ func (r *ProductRepo) Create(ctx context.Context, product *domain.Product) (int,
 }
 
 func (r *ProductRepo) GetByID(ctx context.Context, id int) (*domain.Product, error) {
-	const query = `SELECT id, title, price, description, available, image_url, created_at FROM products WHERE id = $1`
+	const query = `SELECT id, title, price, description, available, image_url, COALESCE(created_by, 0), status, publish_at, unpublish_at, version, created_at, updated_at FROM products WHERE id = $1 AND deleted_at IS NULL`
 	var productCard domain.Product
-	err := r.db.QueryRow(ctx, query, id).Scan(&productCard.ID, &productCard.Title, &productCard.Price, &productCard.Description, &productCard.Available, &productCard.ImageURL, &productCard.CreatedAt)
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, id).Scan(&productCard.ID, &productCard.Title, &productCard.Price, &productCard.Description, &productCard.Available, &productCard.ImageURL, &productCard.CreatedBy, &productCard.Status, &productCard.PublishAt, &productCard.UnpublishAt, &productCard.Version, &productCard.CreatedAt, &productCard.UpdatedAt)
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, custom.ErrNotFound
@@ -41,39 +54,308 @@ func (r *ProductRepo) GetByID(ctx context.Context, id int) (*domain.Product, err
 	return &productCard, nil
 }
 
-func (r *ProductRepo) GetAll(ctx context.Context) ([]domain.Product, error) {
-	const query = `SELECT id, title, price, available, description, image_url, created_at FROM products ORDER BY id`
-	rows, err := r.db.Query(ctx, query)
+// GetAll возвращает товары вне корзины в одном из статусов statuses; пустой
+// statuses — в любом статусе
+func (r *ProductRepo) GetAll(ctx context.Context, statuses []domain.ProductStatus) ([]domain.Product, error) {
+	const query = `SELECT id, title, price, available, description, image_url, COALESCE(created_by, 0), status, publish_at, unpublish_at, version, created_at, updated_at
+		FROM products WHERE deleted_at IS NULL AND (cardinality($1::text[]) = 0 OR status = ANY($1)) ORDER BY id`
+	names := make([]string, 0, len(statuses))
+	for _, st := range statuses {
+		names = append(names, string(st))
+	}
+	var products []domain.Product
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) (err error) {
+		products, err = queryProducts(ctx, tx, query, names)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get p: %w", err)
 	}
+	return products, nil
+}
+
+func (r *ProductRepo) ListByCreator(ctx context.Context, userID int) ([]domain.Product, error) {
+	const query = `SELECT id, title, price, available, description, image_url, COALESCE(created_by, 0), status, publish_at, unpublish_at, version, created_at, updated_at FROM products WHERE created_by = $1 ORDER BY id`
+	var products []domain.Product
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) (err error) {
+		products, err = queryProducts(ctx, tx, query, userID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list products by creator: %w", err)
+	}
+	return products, nil
+}
+
+func queryProducts(ctx context.Context, tx pgx.Tx, query string, args ...any) ([]domain.Product, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 	var products []domain.Product
 	for rows.Next() {
 		var p domain.Product
-		err = rows.Scan(&p.ID, &p.Title, &p.Price, &p.Available, &p.Description, &p.ImageURL, &p.CreatedAt)
+		err = rows.Scan(&p.ID, &p.Title, &p.Price, &p.Available, &p.Description, &p.ImageURL, &p.CreatedBy, &p.Status, &p.PublishAt, &p.UnpublishAt, &p.Version, &p.CreatedAt, &p.UpdatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan product: %w", err)
 		}
 		products = append(products, p)
 	}
-	return products, nil
+	return products, rows.Err()
 }
 
+// UpdateByID заменяет товар, если его версия всё ещё равна product.Version, и
+// записывает в product новую версию. Устаревшая версия — ErrPreconditionFailed.
 func (r *ProductRepo) UpdateByID(ctx context.Context, id int, product *domain.Product) error {
-	const query = `UPDATE products SET title = $1, price = $2, description = $3, available = $4 WHERE id = $5`
-	_, err := r.db.Exec(ctx, query, product.Title, product.Price, product.Description, product.Available, id)
+	const query = `UPDATE products SET title = $1, price = $2, description = $3, available = $4, image_url = $5, version = version + 1, updated_at = NOW()
+		WHERE id = $6 AND version = $7 AND deleted_at IS NULL RETURNING version, updated_at`
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, query, product.Title, product.Price, product.Description, product.Available, product.ImageURL, id, product.Version).
+			Scan(&product.Version, &product.UpdatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return staleOrMissing(ctx, tx, "products", id, liveProduct)
+		}
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("failed to update product: %w", err)
 	}
 	return nil
 }
 
-func (r *ProductRepo) DeleteByID(ctx context.Context, id int) error {
-	const query = `DELETE FROM products WHERE id = $1`
-	_, err := r.db.Exec(ctx, query, id)
+// Patch обновляет только переданные колонки товара версии version и возвращает
+// товар после изменения
+func (r *ProductRepo) Patch(ctx context.Context, id, version int, patch domain.ProductPatch) (*domain.Product, error) {
+	var (
+		set  []string
+		args []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if patch.Title != nil {
+		set = append(set, "title = "+arg(*patch.Title))
+	}
+	if patch.Price != nil {
+		set = append(set, "price = "+arg(*patch.Price))
+	}
+	if patch.Description != nil {
+		set = append(set, "description = "+arg(*patch.Description))
+	}
+	if patch.Available != nil {
+		set = append(set, "available = "+arg(*patch.Available))
+	}
+	if patch.ImageURL != nil {
+		set = append(set, "image_url = "+arg(*patch.ImageURL))
+	}
+	set = append(set, "version = version + 1", "updated_at = NOW()")
+
+	query := `UPDATE products SET ` + strings.Join(set, ", ") + ` WHERE id = ` + arg(id) + ` AND version = ` + arg(version) + ` AND deleted_at IS NULL` +
+		` RETURNING id, title, price, description, available, image_url, COALESCE(created_by, 0), status, publish_at, unpublish_at, version, created_at, updated_at`
+	var p domain.Product
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, query, args...).Scan(&p.ID, &p.Title, &p.Price, &p.Description, &p.Available, &p.ImageURL, &p.CreatedBy, &p.Status, &p.PublishAt, &p.UnpublishAt, &p.Version, &p.CreatedAt, &p.UpdatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return staleOrMissing(ctx, tx, "products", id, liveProduct)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to patch product: %w", err)
+	}
+	return &p, nil
+}
+
+// UpdateStatus переводит товар версии version в статус status. Публикация и
+// архивирование отменяют запланированную публикацию, архивирование — и снятие с
+// публикации; черновик и товар на проверке сохраняют расписание.
+func (r *ProductRepo) UpdateStatus(ctx context.Context, id, version int, status domain.ProductStatus) (*domain.Product, error) {
+	const query = `UPDATE products SET status = $3,
+		publish_at = CASE WHEN $3 IN ('published', 'archived') THEN NULL ELSE publish_at END,
+		unpublish_at = CASE WHEN $3 = 'archived' THEN NULL ELSE unpublish_at END,
+		version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND version = $2 AND deleted_at IS NULL
+		RETURNING id, title, price, description, available, image_url, COALESCE(created_by, 0), status, publish_at, unpublish_at, version, created_at, updated_at`
+	return r.updateOne(ctx, id, "failed to update product status", query, id, version, status)
+}
+
+// UpdateSchedule заменяет расписание публикации товара версии version
+func (r *ProductRepo) UpdateSchedule(ctx context.Context, id, version int, schedule domain.ProductSchedule) (*domain.Product, error) {
+	const query = `UPDATE products SET publish_at = $3, unpublish_at = $4, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND version = $2 AND deleted_at IS NULL
+		RETURNING id, title, price, description, available, image_url, COALESCE(created_by, 0), status, publish_at, unpublish_at, version, created_at, updated_at`
+	return r.updateOne(ctx, id, "failed to update product schedule", query, id, version, schedule.PublishAt, schedule.UnpublishAt)
+}
+
+// updateOne выполняет UPDATE одного товара с проверкой версии и возвращает товар после изменения
+func (r *ProductRepo) updateOne(ctx context.Context, id int, msg, query string, args ...any) (*domain.Product, error) {
+	var p domain.Product
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, query, args...).Scan(&p.ID, &p.Title, &p.Price, &p.Description, &p.Available, &p.ImageURL, &p.CreatedBy, &p.Status, &p.PublishAt, &p.UnpublishAt, &p.Version, &p.CreatedAt, &p.UpdatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return staleOrMissing(ctx, tx, "products", id, liveProduct)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", msg, err)
+	}
+	return &p, nil
+}
+
+// PublishDue публикует черновики и товары на проверке, чьё время публикации
+// наступило, и возвращает их id. Время сравнивается по часам БД.
+func (r *ProductRepo) PublishDue(ctx context.Context) ([]int, error) {
+	const query = `UPDATE products SET status = 'published', publish_at = NULL, version = version + 1, updated_at = NOW()
+		WHERE deleted_at IS NULL AND status IN ('draft', 'in_review') AND publish_at <= NOW()
+		RETURNING id`
+	ids, err := r.updateDue(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish scheduled products: %w", err)
+	}
+	return ids, nil
+}
+
+// UnpublishDue архивирует опубликованные товары, чьё время снятия с публикации наступило
+func (r *ProductRepo) UnpublishDue(ctx context.Context) ([]int, error) {
+	const query = `UPDATE products SET status = 'archived', unpublish_at = NULL, version = version + 1, updated_at = NOW()
+		WHERE deleted_at IS NULL AND status = 'published' AND unpublish_at <= NOW()
+		RETURNING id`
+	ids, err := r.updateDue(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpublish scheduled products: %w", err)
+	}
+	return ids, nil
+}
+
+func (r *ProductRepo) updateDue(ctx context.Context, query string) ([]int, error) {
+	var ids []int
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query)
+		if err != nil {
+			return err
+		}
+		ids, err = pgx.CollectRows(rows, pgx.RowTo[int])
+		return err
+	})
+	return ids, err
+}
+
+// Revision возвращает ревизию каталога арендатора из контекста
+func (r *ProductRepo) Revision(ctx context.Context) (domain.CatalogRevision, error) {
+	const query = `SELECT revision, updated_at FROM catalog_revisions`
+	var rev domain.CatalogRevision
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, query).Scan(&rev.Revision, &rev.UpdatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return domain.CatalogRevision{}, fmt.Errorf("failed to get catalog revision: %w", err)
+	}
+	return rev, nil
+}
+
+// DeleteByID переносит товар версии version в корзину
+func (r *ProductRepo) DeleteByID(ctx context.Context, id, version int) error {
+	const query = `UPDATE products SET deleted_at = NOW(), version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND version = $2 AND deleted_at IS NULL`
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, query, id, version)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return staleOrMissing(ctx, tx, "products", id, liveProduct)
+		}
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to delete product: %w", err)
 	}
 	return nil
 }
+
+// ListDeleted возвращает товары, пролежавшие в корзине не меньше olderThan, начиная
+// с давних. Время считается по часам БД, которыми проставлен deleted_at.
+func (r *ProductRepo) ListDeleted(ctx context.Context, olderThan time.Duration) ([]domain.Product, error) {
+	const query = `SELECT id, title, price, available, description, image_url, COALESCE(created_by, 0), status, publish_at, unpublish_at, version, created_at, updated_at, deleted_at
+		FROM products WHERE deleted_at <= NOW() - make_interval(secs => $1) ORDER BY deleted_at, id`
+	var products []domain.Product
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, olderThan.Seconds())
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var p domain.Product
+			err = rows.Scan(&p.ID, &p.Title, &p.Price, &p.Available, &p.Description, &p.ImageURL, &p.CreatedBy, &p.Status, &p.PublishAt, &p.UnpublishAt, &p.Version, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt)
+			if err != nil {
+				return fmt.Errorf("failed to scan product: %w", err)
+			}
+			products = append(products, p)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted products: %w", err)
+	}
+	return products, nil
+}
+
+// Restore возвращает товар из корзины; товара нет в корзине — ErrNotFound
+func (r *ProductRepo) Restore(ctx context.Context, id int) (*domain.Product, error) {
+	const query = `UPDATE products SET deleted_at = NULL, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING id, title, price, description, available, image_url, COALESCE(created_by, 0), status, publish_at, unpublish_at, version, created_at, updated_at`
+	var p domain.Product
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, id).Scan(&p.ID, &p.Title, &p.Price, &p.Description, &p.Available, &p.ImageURL, &p.CreatedBy, &p.Status, &p.PublishAt, &p.UnpublishAt, &p.Version, &p.CreatedAt, &p.UpdatedAt)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to restore product: %w", err)
+	}
+	return &p, nil
+}
+
+// Purge окончательно удаляет товар из корзины
+func (r *ProductRepo) Purge(ctx context.Context, id int) error {
+	const query = `DELETE FROM products WHERE id = $1 AND deleted_at IS NOT NULL`
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, query, id)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return custom.ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to purge product: %w", err)
+	}
+	return nil
+}
+
+// ImageInUse сообщает, ссылается ли на объект key другой товар, в том числе из
+// корзины: один загруженный ключ можно передать в image_key нескольких товаров
+func (r *ProductRepo) ImageInUse(ctx context.Context, key string, exceptID int) (bool, error) {
+	const query = `SELECT EXISTS (SELECT 1 FROM products WHERE id <> $2 AND strpos(image_url, $1) > 0)`
+	var inUse bool
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, key, exceptID).Scan(&inUse)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check image usage: %w", err)
+	}
+	return inUse, nil
+}
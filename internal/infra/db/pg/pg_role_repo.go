@@ -0,0 +1,158 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+)
+
+// RoleRepo работает с ролями арендатора из контекста: roles и role_permissions
+// под RLS, поэтому каждый запрос выполняется через inTenant.
+type RoleRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewRoleRepo(db *pgxpool.Pool) *RoleRepo {
+	return &RoleRepo{db: db}
+}
+
+func (r *RoleRepo) GetAll(ctx context.Context) ([]domain.Role, error) {
+	const query = `
+		SELECT r.name, r.description, COALESCE(array_agg(p.permission ORDER BY p.permission) FILTER (WHERE p.permission IS NOT NULL), '{}')
+		FROM roles r LEFT JOIN role_permissions p ON p.tenant_id = r.tenant_id AND p.role = r.name
+		GROUP BY r.name, r.description
+		ORDER BY r.name
+	`
+	var roles []domain.Role
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var role domain.Role
+			var perms []string
+			if err = rows.Scan(&role.Name, &role.Description, &perms); err != nil {
+				return fmt.Errorf("failed to scan role: %w", err)
+			}
+			role.Permissions = toPermissions(perms)
+			roles = append(roles, role)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+	return roles, nil
+}
+
+func (r *RoleRepo) GetPermissions(ctx context.Context, role auth.Role) (auth.Permissions, error) {
+	const query = `SELECT permission FROM role_permissions WHERE role = $1 ORDER BY permission`
+	perms := auth.Permissions{}
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, role)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var p string
+			if err = rows.Scan(&p); err != nil {
+				return fmt.Errorf("failed to scan permission: %w", err)
+			}
+			perms = append(perms, auth.Permission(p))
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role permissions: %w", err)
+	}
+	return perms, nil
+}
+
+// Upsert создаёт или заменяет роль арендатора из контекста; одноимённые роли
+// других арендаторов не затрагиваются
+func (r *RoleRepo) Upsert(ctx context.Context, role *domain.Role) error {
+	return inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		const roleQuery = `
+			INSERT INTO roles (name, description) VALUES ($1, $2)
+			ON CONFLICT (tenant_id, name) DO UPDATE SET description = $2
+		`
+		if _, err := tx.Exec(ctx, roleQuery, role.Name, role.Description); err != nil {
+			return fmt.Errorf("failed to save role: %w", err)
+		}
+
+		const deleteQuery = `DELETE FROM role_permissions WHERE role = $1`
+		if _, err := tx.Exec(ctx, deleteQuery, role.Name); err != nil {
+			return fmt.Errorf("failed to clear role permissions: %w", err)
+		}
+
+		const insertQuery = `INSERT INTO role_permissions (role, permission) VALUES ($1, $2)`
+		for _, p := range role.Permissions {
+			if _, err := tx.Exec(ctx, insertQuery, role.Name, p); err != nil {
+				return fmt.Errorf("failed to save role permission: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *RoleRepo) Delete(ctx context.Context, name auth.Role) error {
+	const query = `DELETE FROM roles WHERE name = $1`
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, query, name)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return custom.ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return custom.ErrConflict
+		}
+		if errors.Is(err, custom.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+	return nil
+}
+
+// GetHolderIDs возвращает пользователей арендатора из контекста с ролью role
+func (r *RoleRepo) GetHolderIDs(ctx context.Context, role auth.Role) ([]int, error) {
+	const query = `SELECT id FROM users WHERE role = $1 ORDER BY id`
+	var ids []int
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, role)
+		if err != nil {
+			return err
+		}
+		ids, err = pgx.CollectRows(rows, pgx.RowTo[int])
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role holders: %w", err)
+	}
+	return ids, nil
+}
+
+func toPermissions(perms []string) auth.Permissions {
+	res := make(auth.Permissions, len(perms))
+	for i, p := range perms {
+		res[i] = auth.Permission(p)
+	}
+	return res
+}
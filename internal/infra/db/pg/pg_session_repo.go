@@ -0,0 +1,131 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+	"time"
+)
+
+type SessionRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewSessionRepo(db *pgxpool.Pool) *SessionRepo {
+	return &SessionRepo{db: db}
+}
+
+func (r *SessionRepo) Create(ctx context.Context, session *domain.Session) error {
+	const query = `
+		INSERT INTO sessions (id, user_id, device, ip, user_agent, created_at, last_seen_at, expires_at, impersonator_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $6, $7, $8)
+	`
+	_, err := r.db.Exec(ctx, query, session.ID, session.UserID, session.Device, session.IP, session.UserAgent, session.CreatedAt, session.ExpiresAt, session.ImpersonatorID)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+func (r *SessionRepo) GetByID(ctx context.Context, id string) (*domain.Session, error) {
+	const query = `
+		SELECT id, user_id, device, ip, user_agent, created_at, last_seen_at, expires_at, revoked_at, impersonator_id
+		FROM sessions WHERE id = $1
+	`
+	var s domain.Session
+	err := r.db.QueryRow(ctx, query, id).Scan(&s.ID, &s.UserID, &s.Device, &s.IP, &s.UserAgent, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt, &s.RevokedAt, &s.ImpersonatorID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return &s, nil
+}
+
+// ListActiveByUserID возвращает неотозванные и неистёкшие сессии, последние активные первыми.
+func (r *SessionRepo) ListActiveByUserID(ctx context.Context, userID int) ([]domain.Session, error) {
+	const query = `
+		SELECT id, user_id, device, ip, user_agent, created_at, last_seen_at, expires_at, revoked_at, impersonator_id
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_seen_at DESC
+	`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []domain.Session
+	for rows.Next() {
+		var s domain.Session
+		err = rows.Scan(&s.ID, &s.UserID, &s.Device, &s.IP, &s.UserAgent, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt, &s.RevokedAt, &s.ImpersonatorID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, nil
+}
+
+// ListByUserID возвращает все сессии пользователя, включая отозванные и истёкшие.
+func (r *SessionRepo) ListByUserID(ctx context.Context, userID int) ([]domain.Session, error) {
+	const query = `
+		SELECT id, user_id, device, ip, user_agent, created_at, last_seen_at, expires_at, revoked_at, impersonator_id
+		FROM sessions
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []domain.Session
+	for rows.Next() {
+		var s domain.Session
+		err = rows.Scan(&s.ID, &s.UserID, &s.Device, &s.IP, &s.UserAgent, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt, &s.RevokedAt, &s.ImpersonatorID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, nil
+}
+
+func (r *SessionRepo) Touch(ctx context.Context, id, ip, userAgent string, seenAt time.Time) error {
+	const query = `UPDATE sessions SET last_seen_at = $2, ip = $3, user_agent = $4 WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, id, seenAt, ip, userAgent)
+	if err != nil {
+		return fmt.Errorf("failed to touch session: %w", err)
+	}
+	return nil
+}
+
+func (r *SessionRepo) Revoke(ctx context.Context, userID int, id string) error {
+	const query = `UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+	tag, err := r.db.Exec(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return custom.ErrNotFound
+	}
+	return nil
+}
+
+// RevokeAllExcept отзывает все сессии пользователя, кроме указанной (exceptID может быть пустым).
+func (r *SessionRepo) RevokeAllExcept(ctx context.Context, userID int, exceptID string) error {
+	const query = `UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL`
+	_, err := r.db.Exec(ctx, query, userID, exceptID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"product-catalog/internal/domain"
+)
+
+type TenantRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewTenantRepo(db *pgxpool.Pool) *TenantRepo {
+	return &TenantRepo{db: db}
+}
+
+func (r *TenantRepo) List(ctx context.Context) ([]domain.Tenant, error) {
+	const query = `SELECT id, slug, name, hosts, created_at FROM tenants ORDER BY id`
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var tenants []domain.Tenant
+	for rows.Next() {
+		var t domain.Tenant
+		if err = rows.Scan(&t.ID, &t.Slug, &t.Name, &t.Hosts, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant: %w", err)
+		}
+		tenants = append(tenants, t)
+	}
+	return tenants, rows.Err()
+}
@@ -0,0 +1,127 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+)
+
+type TwoFactorRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewTwoFactorRepo(db *pgxpool.Pool) *TwoFactorRepo {
+	return &TwoFactorRepo{db: db}
+}
+
+func (r *TwoFactorRepo) GetByUserID(ctx context.Context, userID int) (*domain.TwoFactor, error) {
+	const query = `SELECT user_id, COALESCE(secret, ''), enabled, required, last_used_step, enabled_at FROM user_two_factor WHERE user_id = $1`
+	var tf domain.TwoFactor
+	err := r.db.QueryRow(ctx, query, userID).Scan(&tf.UserID, &tf.Secret, &tf.Enabled, &tf.Required, &tf.LastUsedStep, &tf.EnabledAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get two factor settings: %w", err)
+	}
+	return &tf, nil
+}
+
+func (r *TwoFactorRepo) SaveSecret(ctx context.Context, userID int, secret string) error {
+	const query = `
+		INSERT INTO user_two_factor (user_id, secret, enabled, last_used_step)
+		VALUES ($1, $2, FALSE, 0)
+		ON CONFLICT (user_id) DO UPDATE SET secret = $2, enabled = FALSE, last_used_step = 0, enabled_at = NULL
+	`
+	_, err := r.db.Exec(ctx, query, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to save totp secret: %w", err)
+	}
+	return nil
+}
+
+func (r *TwoFactorRepo) Enable(ctx context.Context, userID int) error {
+	const query = `UPDATE user_two_factor SET enabled = TRUE, enabled_at = NOW() WHERE user_id = $1 AND secret IS NOT NULL`
+	tag, err := r.db.Exec(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to enable two factor: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return custom.ErrNotFound
+	}
+	return nil
+}
+
+func (r *TwoFactorRepo) Disable(ctx context.Context, userID int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	const query = `UPDATE user_two_factor SET secret = NULL, enabled = FALSE, last_used_step = 0, enabled_at = NULL WHERE user_id = $1`
+	if _, err = tx.Exec(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to disable two factor: %w", err)
+	}
+	const codesQuery = `DELETE FROM user_recovery_codes WHERE user_id = $1`
+	if _, err = tx.Exec(ctx, codesQuery, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *TwoFactorRepo) SetRequired(ctx context.Context, userID int, required bool) error {
+	const query = `
+		INSERT INTO user_two_factor (user_id, required) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET required = $2
+	`
+	_, err := r.db.Exec(ctx, query, userID, required)
+	if err != nil {
+		return fmt.Errorf("failed to set two factor requirement: %w", err)
+	}
+	return nil
+}
+
+// MarkStepUsed атомарно фиксирует использованный шаг TOTP и возвращает false,
+// если этот или более поздний шаг уже был использован (повтор кода).
+func (r *TwoFactorRepo) MarkStepUsed(ctx context.Context, userID int, step int64) (bool, error) {
+	const query = `UPDATE user_two_factor SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`
+	tag, err := r.db.Exec(ctx, query, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark totp step used: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *TwoFactorRepo) ReplaceRecoveryCodes(ctx context.Context, userID int, hashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	const deleteQuery = `DELETE FROM user_recovery_codes WHERE user_id = $1`
+	if _, err = tx.Exec(ctx, deleteQuery, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	const insertQuery = `INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`
+	for _, h := range hashes {
+		if _, err = tx.Exec(ctx, insertQuery, userID, h); err != nil {
+			return fmt.Errorf("failed to insert recovery code: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *TwoFactorRepo) UseRecoveryCode(ctx context.Context, userID int, hash string) (bool, error) {
+	const query = `UPDATE user_recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	tag, err := r.db.Exec(ctx, query, userID, hash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
@@ -10,8 +10,13 @@ import (
 	"product-catalog/internal/auth"
 	"product-catalog/internal/domain"
 	custom "product-catalog/internal/errors"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// UserRepo работает с таблицей users под RLS: каждый запрос выполняется через
+// inTenant и видит только пользователей арендатора из контекста.
 type UserRepo struct {
 	db *pgxpool.Pool
 }
@@ -21,8 +26,10 @@ func NewUserRepo(db *pgxpool.Pool) *UserRepo {
 }
 
 func (r *UserRepo) Create(ctx context.Context, user *domain.User) error {
-	const query = `INSERT INTO users (username, email, password_hash, role, created_at) VALUES ($1, $2, $3, $4, $5)`
-	_, err := r.db.Exec(ctx, query, user.Username, user.Email, user.PasswordHash, user.Role, user.CreatedAt)
+	const query = `INSERT INTO users (username, email, password_hash, role, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, user.Username, user.Email, user.PasswordHash, user.Role, user.CreatedAt).Scan(&user.ID)
+	})
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
@@ -34,9 +41,12 @@ func (r *UserRepo) Create(ctx context.Context, user *domain.User) error {
 }
 
 func (r *UserRepo) GetByID(ctx context.Context, id int) (*domain.User, error) {
-	const query = `SELECT id, username, email, role, created_at FROM users WHERE id = $1`
+	const query = `SELECT id, username, email, password_hash, role, status, deleted_at, version, created_at FROM users WHERE id = $1`
 	var userFromDB domain.User
-	err := r.db.QueryRow(ctx, query, id).Scan(&userFromDB.ID, &userFromDB.Username, &userFromDB.Email, &userFromDB.Role, &userFromDB.CreatedAt)
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, id).Scan(&userFromDB.ID, &userFromDB.Username, &userFromDB.Email, &userFromDB.PasswordHash,
+			&userFromDB.Role, &userFromDB.Status, &userFromDB.DeletedAt, &userFromDB.Version, &userFromDB.CreatedAt)
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, custom.ErrNotFound
@@ -46,58 +56,353 @@ func (r *UserRepo) GetByID(ctx context.Context, id int) (*domain.User, error) {
 	return &userFromDB, nil
 }
 
-func (r *UserRepo) GetAll(ctx context.Context) ([]domain.User, error) {
-	const query = `SELECT * FROM users`
-	var users []domain.User
-	row, err := r.db.Query(ctx, query)
+func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	const query = `SELECT id, username, email, role, status, deleted_at, created_at FROM users WHERE lower(email) = lower($1)`
+	var userFromDB domain.User
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, email).Scan(&userFromDB.ID, &userFromDB.Username, &userFromDB.Email, &userFromDB.Role,
+			&userFromDB.Status, &userFromDB.DeletedAt, &userFromDB.CreatedAt)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get users: %w", err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
+	}
+	return &userFromDB, nil
+}
+
+// List возвращает страницу пользователей по фильтру и общее число совпадений.
+func (r *UserRepo) List(ctx context.Context, f domain.UserFilter) ([]domain.User, int, error) {
+	var (
+		where []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if f.Query != "" {
+		p := arg(escapeLike(strings.ToLower(f.Query)) + "%")
+		where = append(where, "(lower(username) LIKE "+p+" OR lower(email) LIKE "+p+")")
+	}
+	if len(f.Roles) > 0 {
+		roles := make([]string, 0, len(f.Roles))
+		for _, role := range f.Roles {
+			roles = append(roles, string(role))
+		}
+		where = append(where, "role = ANY("+arg(roles)+")")
+	}
+	if len(f.Statuses) > 0 {
+		where = append(where, "status = ANY("+arg(statusStrings(f.Statuses))+")")
 	}
-	defer row.Close()
-	for row.Next() {
-		var userFromDB domain.User
-		err = row.Scan(&userFromDB.ID, &userFromDB.Username, &userFromDB.Email, &userFromDB.PasswordHash, &userFromDB.Role, &userFromDB.CreatedAt)
+	if f.CreatedAfter != nil {
+		where = append(where, "created_at >= "+arg(*f.CreatedAfter))
+	}
+	if f.CreatedBefore != nil {
+		where = append(where, "created_at < "+arg(*f.CreatedBefore))
+	}
+
+	query := `SELECT id, username, email, role, status, deleted_at, created_at, COUNT(*) OVER() FROM users`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	// Sort уже проверен сервисом; id добавляется для стабильного порядка страниц
+	order := "ASC"
+	if f.Desc {
+		order = "DESC"
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %s OFFSET %s", f.Sort, order, order, arg(f.Limit), arg(f.Offset))
+
+	var (
+		users []domain.User
+		total int
+	)
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, args...)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get users: %w", err)
+			return fmt.Errorf("failed to list users: %w", err)
 		}
-		users = append(users, userFromDB)
+		defer rows.Close()
+
+		for rows.Next() {
+			var u domain.User
+			err = rows.Scan(&u.ID, &u.Username, &u.Email, &u.Role, &u.Status, &u.DeletedAt, &u.CreatedAt, &total)
+			if err != nil {
+				return fmt.Errorf("failed to scan user: %w", err)
+			}
+			users = append(users, u)
+		}
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("failed to list users: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
 	}
-	return users, nil
+	return users, total, nil
 }
 
-func (r *UserRepo) UpdateByID(ctx context.Context, id int, username, email string, role auth.Role, passwordHash string) error {
-	const query = `UPDATE users SET username = $1, email = $2, role = $3, password_hash=$4 WHERE id = $5`
-	_, err := r.db.Exec(ctx, query, username, email, role, passwordHash, id)
+// UpdateRoleBulk меняет роль у всех не обезличенных пользователей из ids и возвращает изменённые id.
+func (r *UserRepo) UpdateRoleBulk(ctx context.Context, ids []int, role auth.Role) ([]int, error) {
+	const query = `UPDATE users SET role = $2, version = version + 1 WHERE id = ANY($1) AND status <> 'erased' RETURNING id`
+	var updated []int
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, ids, role)
+		if err != nil {
+			return err
+		}
+		updated, err = pgx.CollectRows(rows, pgx.RowTo[int])
+		return err
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return nil, fmt.Errorf("unknown role %q: %w", role, custom.ErrInvalidInput)
+		}
+		return nil, fmt.Errorf("failed to update roles: %w", err)
+	}
+	return updated, nil
+}
+
+// UpdateStatusBulk переводит в статус to пользователей из ids, находящихся в одном из статусов from.
+func (r *UserRepo) UpdateStatusBulk(ctx context.Context, ids []int, from []domain.UserStatus, to domain.UserStatus) ([]int, error) {
+	const query = `
+		UPDATE users
+		SET status = $3, deleted_at = CASE WHEN $3 = 'deleted' THEN NOW() ELSE NULL END, version = version + 1
+		WHERE id = ANY($1) AND status = ANY($2)
+		RETURNING id
+	`
+	var updated []int
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, ids, statusStrings(from), to)
+		if err != nil {
+			return err
+		}
+		updated, err = pgx.CollectRows(rows, pgx.RowTo[int])
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update statuses: %w", err)
+	}
+	return updated, nil
+}
+
+func statusStrings(statuses []domain.UserStatus) []string {
+	res := make([]string, 0, len(statuses))
+	for _, st := range statuses {
+		res = append(res, string(st))
+	}
+	return res
+}
+
+// escapeLike экранирует спецсимволы LIKE, чтобы пользовательский ввод искался буквально.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Patch обновляет только переданные колонки пользователя, если его версия всё ещё
+// равна version; иначе ErrPreconditionFailed.
+func (r *UserRepo) Patch(ctx context.Context, id, version int, patch domain.UserPatch) error {
+	var (
+		set  []string
+		args []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if patch.Username != nil {
+		set = append(set, "username = "+arg(*patch.Username))
+	}
+	if patch.Email != nil {
+		set = append(set, "email = "+arg(*patch.Email))
+	}
+	if patch.Role != nil {
+		set = append(set, "role = "+arg(*patch.Role))
+	}
+	if patch.PasswordHash != nil {
+		set = append(set, "password_hash = "+arg(*patch.PasswordHash))
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	set = append(set, "version = version + 1")
+
+	query := `UPDATE users SET ` + strings.Join(set, ", ") + ` WHERE id = ` + arg(id) + ` AND version = ` + arg(version)
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return staleOrMissing(ctx, tx, "users", id, "")
+		}
+		return nil
+	})
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
 			return custom.ErrConflict
 		}
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return fmt.Errorf("unknown role %q: %w", *patch.Role, custom.ErrInvalidInput)
+		}
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 	return nil
 }
 
-func (r *UserRepo) DeleteByID(ctx context.Context, id int) error {
-	const query = `DELETE FROM users WHERE id = $1`
-	_, err := r.db.Exec(ctx, query, id)
+func (r *UserRepo) UpdatePasswordHash(ctx context.Context, id int, passwordHash string) error {
+	const query = `UPDATE users SET password_hash = $1 WHERE id = $2`
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, query, passwordHash, id)
+		return err
+	})
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to update password hash: %w", err)
+	}
+	return nil
+}
+
+// UpdateStatus переводит пользователя в статус to, если текущий статус входит в from.
+// deleted_at выставляется при мягком удалении и сбрасывается при любом другом переходе.
+func (r *UserRepo) UpdateStatus(ctx context.Context, id int, from []domain.UserStatus, to domain.UserStatus) error {
+	const query = `
+		UPDATE users
+		SET status = $2, deleted_at = CASE WHEN $2 = 'deleted' THEN NOW() ELSE NULL END, version = version + 1
+		WHERE id = $1 AND status = ANY($3)
+	`
+	var tag pgconn.CommandTag
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) (err error) {
+		tag, err = tx.Exec(ctx, query, id, to, statusStrings(from))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update user status: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		if _, err = r.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return fmt.Errorf("user status does not allow this operation: %w", custom.ErrConflict)
+	}
+	return nil
+}
+
+// DeleteByID мягко удаляет активного или деактивированного пользователя версии
+// version. Устаревшая версия — ErrPreconditionFailed, неподходящий статус — ErrConflict.
+func (r *UserRepo) DeleteByID(ctx context.Context, id, version int) error {
+	const query = `
+		UPDATE users
+		SET status = 'deleted', deleted_at = NOW(), version = version + 1
+		WHERE id = $1 AND version = $2 AND status IN ('active', 'deactivated')
+	`
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, query, id, version)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() > 0 {
+			return nil
+		}
+		var current int
+		err = tx.QueryRow(ctx, `SELECT version FROM users WHERE id = $1`, id).Scan(&current)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
 			return custom.ErrNotFound
+		case err != nil:
+			return err
+		case current != version:
+			return custom.ErrPreconditionFailed
 		}
+		return fmt.Errorf("user status does not allow this operation: %w", custom.ErrConflict)
+	})
+	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 	return nil
 }
 
+// Erase обезличивает пользователя: строка остаётся, чтобы не ломать ссылки из
+// товаров и журналов аудита, а все персональные данные и учётные данные удаляются.
+func (r *UserRepo) Erase(ctx context.Context, id int) error {
+	return inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return eraseUser(ctx, tx, id)
+	})
+}
+
+func eraseUser(ctx context.Context, tx pgx.Tx, id int) error {
+	var locked int
+	err := tx.QueryRow(ctx, `SELECT id FROM users WHERE id = $1 AND status <> 'erased' FOR UPDATE`, id).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return custom.ErrNotFound
+		}
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	const anonymize = `
+		UPDATE users
+		SET username = 'deleted_' || id,
+			email = 'deleted_' || id || '@erased.invalid',
+			password_hash = '',
+			status = 'erased',
+			deleted_at = NULL,
+			version = version + 1
+		WHERE id = $1
+	`
+	if _, err = tx.Exec(ctx, anonymize, id); err != nil {
+		return fmt.Errorf("failed to anonymize user: %w", err)
+	}
+
+	cleanup := []string{
+		`DELETE FROM user_two_factor WHERE user_id = $1`,
+		`DELETE FROM user_recovery_codes WHERE user_id = $1`,
+		`DELETE FROM api_keys WHERE user_id = $1`,
+		`DELETE FROM sessions WHERE user_id = $1`,
+		`DELETE FROM user_identities WHERE user_id = $1`,
+		`UPDATE invitations SET email = 'deleted_' || accepted_user_id || '@erased.invalid' WHERE accepted_user_id = $1`,
+	}
+	for _, q := range cleanup {
+		if _, err = tx.Exec(ctx, q, id); err != nil {
+			return fmt.Errorf("failed to erase user data: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListDeletedBefore возвращает ID пользователей, мягко удалённых раньше before.
+func (r *UserRepo) ListDeletedBefore(ctx context.Context, before time.Time) ([]int, error) {
+	const query = `SELECT id FROM users WHERE status = 'deleted' AND deleted_at < $1 ORDER BY id`
+	var ids []int
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, before)
+		if err != nil {
+			return err
+		}
+		ids, err = pgx.CollectRows(rows, pgx.RowTo[int])
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted users: %w", err)
+	}
+	return ids, nil
+}
+
 func (r *UserRepo) ExistsByEmailOrUsername(ctx context.Context, email, username string) (bool, error) {
 	const query = `
 		SELECT 1 FROM users 
-		WHERE email = $1 OR username = $2 
+		WHERE lower(email) = lower($1) OR username = $2 
 		LIMIT 1
 	`
 	var exists int
-	err := r.db.QueryRow(ctx, query, email, username).Scan(&exists)
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, email, username).Scan(&exists)
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return false, nil
@@ -108,12 +413,14 @@ func (r *UserRepo) ExistsByEmailOrUsername(ctx context.Context, email, username
 }
 
 func (r *UserRepo) GetUserCredsAndRoleByEmail(ctx context.Context, email string) (string, int, auth.Role, error) {
-	const query = `SELECT password_hash, id, role FROM users WHERE email = $1`
+	const query = `SELECT password_hash, id, role FROM users WHERE lower(email) = lower($1) AND status = 'active'`
 
 	var pwHash string
 	var id int
 	var role auth.Role
-	err := r.db.QueryRow(ctx, query, email).Scan(&pwHash, &id, &role)
+	err := inTenant(ctx, r.db, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, email).Scan(&pwHash, &id, &role)
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return "", 0, "", custom.ErrNotFound
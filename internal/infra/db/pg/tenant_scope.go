@@ -0,0 +1,61 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"product-catalog/internal/auth"
+	"strconv"
+)
+
+// ErrNoTenant возвращается при обращении к таблицам арендаторов без арендатора в контексте.
+var ErrNoTenant = errors.New("tenant is not set in context")
+
+// txBeginner — пул или транзакция: оба умеют открывать (вложенную) транзакцию.
+type txBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// inTenant выполняет fn в транзакции, где app.tenant_id равен арендатору из ctx.
+// Политики RLS на users, products, roles и invitations пропускают только строки
+// этого арендатора, поэтому все запросы к этим таблицам должны идти через inTenant.
+func inTenant(ctx context.Context, db txBeginner, fn func(tx pgx.Tx) error) error {
+	tenantID, ok := auth.TenantIDFromContext(ctx)
+	if !ok {
+		return ErrNoTenant
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// is_local = true: настройка живёт до конца транзакции и не протекает в пул
+	if _, err = tx.Exec(ctx, `SELECT set_config('app.tenant_id', $1, true)`, strconv.Itoa(tenantID)); err != nil {
+		return fmt.Errorf("failed to set tenant: %w", err)
+	}
+	if err = fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// NewPool открывает пул соединений, каждое из которых работает под ролью appRole.
+// Суперпользователь и владелец без FORCE обходят RLS, поэтому без смены роли
+// изоляция арендаторов не действует.
+func NewPool(ctx context.Context, dsn, appRole string) (*pgxpool.Pool, error) {
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DSN: %w", err)
+	}
+	if appRole != "" {
+		cfg.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+			_, err := conn.Exec(ctx, "SET ROLE "+pgx.Identifier{appRole}.Sanitize())
+			return err
+		}
+	}
+	return pgxpool.NewWithConfig(ctx, cfg)
+}
@@ -0,0 +1,404 @@
+package pg_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/infra/db/pg"
+)
+
+func TestReposRequireTenant(t *testing.T) {
+	// Без арендатора в контексте запрос не должен даже дойти до БД
+	users := pg.NewUserRepo(nil)
+	products := pg.NewProductRepo(nil)
+	roles := pg.NewRoleRepo(nil)
+	identities := pg.NewIdentityRepo(nil)
+	ctx := context.Background()
+
+	calls := map[string]func() error{
+		"users.GetByID":       func() error { _, err := users.GetByID(ctx, 1); return err },
+		"users.List":          func() error { _, _, err := users.List(ctx, domain.UserFilter{Limit: 10}); return err },
+		"users.Create":        func() error { return users.Create(ctx, &domain.User{}) },
+		"products.GetAll":     func() error { _, err := products.GetAll(ctx, nil); return err },
+		"products.DeleteByID": func() error { return products.DeleteByID(ctx, 1, 1) },
+		"products.Revision":   func() error { _, err := products.Revision(ctx); return err },
+		"roles.GetPermissions": func() error {
+			_, err := roles.GetPermissions(ctx, auth.RoleUser)
+			return err
+		},
+		"roles.Upsert": func() error { return roles.Upsert(ctx, &domain.Role{Name: auth.RoleUser}) },
+		"roles.Delete": func() error { return roles.Delete(ctx, auth.RoleUser) },
+		"roles.GetHolderIDs": func() error {
+			_, err := roles.GetHolderIDs(ctx, auth.RoleUser)
+			return err
+		},
+		"identities.Create": func() error {
+			return identities.Create(ctx, &domain.UserIdentity{Provider: "google", Subject: "1"})
+		},
+		"products.Patch": func() error {
+			_, err := products.Patch(ctx, 1, 1, domain.ProductPatch{Available: new(bool)})
+			return err
+		},
+	}
+	for name, call := range calls {
+		if err := call(); !errors.Is(err, pg.ErrNoTenant) {
+			t.Errorf("%s err = %v, want ErrNoTenant", name, err)
+		}
+	}
+}
+
+// TestRowLevelSecurity проверяет изоляцию арендаторов на настоящем Postgres.
+// TEST_DATABASE_URL должен указывать на БД под суперпользователем (как в
+// docker-compose); если схема ещё не применена, тест применит schema.sql.
+func TestRowLevelSecurity(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	ctx := context.Background()
+
+	admin, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer admin.Close()
+	applySchema(t, admin)
+
+	suffix := time.Now().UnixNano()
+	tenantA, tenantB := createTenant(t, admin, fmt.Sprintf("rls-a-%d", suffix)), createTenant(t, admin, fmt.Sprintf("rls-b-%d", suffix))
+	t.Cleanup(func() {
+		for _, q := range []string{
+			`DELETE FROM products WHERE tenant_id = ANY($1)`,
+			`DELETE FROM users WHERE tenant_id = ANY($1)`,
+			`DELETE FROM tenants WHERE id = ANY($1)`,
+		} {
+			_, _ = admin.Exec(ctx, q, []int{tenantA, tenantB})
+		}
+	})
+
+	app, err := pg.NewPool(ctx, dsn, "catalog_app")
+	if err != nil {
+		t.Fatalf("connect as app: %v", err)
+	}
+	defer app.Close()
+	users, products, roles := pg.NewUserRepo(app), pg.NewProductRepo(app), pg.NewRoleRepo(app)
+	identities := pg.NewIdentityRepo(app)
+	ctxA, ctxB := auth.WithTenant(ctx, tenantA), auth.WithTenant(ctx, tenantB)
+
+	// Один email может быть зарегистрирован в разных каталогах
+	userA := &domain.User{Username: "alice", Email: "shared@example.com", PasswordHash: "x", Role: auth.RoleUser, CreatedAt: time.Now()}
+	userB := &domain.User{Username: "alice", Email: "shared@example.com", PasswordHash: "x", Role: auth.RoleUser, CreatedAt: time.Now()}
+	if err = users.Create(ctxA, userA); err != nil {
+		t.Fatalf("create user in A: %v", err)
+	}
+	if err = users.Create(ctxB, userB); err != nil {
+		t.Fatalf("create user with the same email in B: %v", err)
+	}
+	productB := &domain.Product{Title: "B lamp", Price: 100, Description: "b", ImageURL: "", CreatedBy: userB.ID, Status: domain.ProductStatusDraft, CreatedAt: time.Now()}
+	if productB.ID, err = products.Create(ctxB, productB); err != nil {
+		t.Fatalf("create product in B: %v", err)
+	}
+
+	t.Run("read", func(t *testing.T) {
+		if _, err := users.GetByID(ctxA, userB.ID); !errors.Is(err, custom.ErrNotFound) {
+			t.Errorf("A reads B user: err = %v, want ErrNotFound", err)
+		}
+		if _, err := products.GetByID(ctxA, productB.ID); !errors.Is(err, custom.ErrNotFound) {
+			t.Errorf("A reads B product: err = %v, want ErrNotFound", err)
+		}
+		all, err := products.GetAll(ctxA, nil)
+		if err != nil {
+			t.Fatalf("list A products: %v", err)
+		}
+		if slices.ContainsFunc(all, func(p domain.Product) bool { return p.ID == productB.ID }) {
+			t.Error("A product list contains B product")
+		}
+		list, _, err := users.List(ctxA, domain.UserFilter{Sort: domain.UserSortID, Limit: 200})
+		if err != nil {
+			t.Fatalf("list A users: %v", err)
+		}
+		if slices.ContainsFunc(list, func(u domain.User) bool { return u.ID == userB.ID }) {
+			t.Error("A user list contains B user")
+		}
+		// Вход по email не зависит от регистра и находит пользователя своего каталога
+		if _, id, _, err := users.GetUserCredsAndRoleByEmail(ctxA, "Shared@Example.com"); err != nil || id != userA.ID {
+			t.Errorf("A login by mixed-case email = %d, %v; want user %d", id, err, userA.ID)
+		}
+	})
+
+	t.Run("write", func(t *testing.T) {
+		if err := products.UpdateByID(ctxA, productB.ID, &domain.Product{Title: "hijacked", Price: 1, Version: 1}); !errors.Is(err, custom.ErrNotFound) {
+			t.Errorf("A updates B product: err = %v, want ErrNotFound", err)
+		}
+		title := "hijacked"
+		if _, err := products.Patch(ctxA, productB.ID, 1, domain.ProductPatch{Title: &title}); !errors.Is(err, custom.ErrNotFound) {
+			t.Errorf("A patches B product: err = %v, want ErrNotFound", err)
+		}
+		if err := users.Patch(ctxA, userB.ID, 1, domain.UserPatch{Username: &title}); !errors.Is(err, custom.ErrNotFound) {
+			t.Errorf("A patches B user: err = %v, want ErrNotFound", err)
+		}
+		if err := products.DeleteByID(ctxA, productB.ID, 1); !errors.Is(err, custom.ErrNotFound) {
+			t.Errorf("A deletes B product: err = %v, want ErrNotFound", err)
+		}
+		if err := users.UpdateStatus(ctxA, userB.ID, []domain.UserStatus{domain.UserStatusActive}, domain.UserStatusDeactivated); !errors.Is(err, custom.ErrNotFound) {
+			t.Errorf("A deactivates B user: err = %v, want ErrNotFound", err)
+		}
+		if err := users.Erase(ctxA, userB.ID); !errors.Is(err, custom.ErrNotFound) {
+			t.Errorf("A erases B user: err = %v, want ErrNotFound", err)
+		}
+
+		got, err := products.GetByID(ctxB, productB.ID)
+		if err != nil {
+			t.Fatalf("B product after A update and delete: %v", err)
+		}
+		if got.Title != "B lamp" {
+			t.Errorf("B product title = %q, A must not be able to change it", got.Title)
+		}
+		u, err := users.GetByID(ctxB, userB.ID)
+		if err != nil || u.Status != domain.UserStatusActive {
+			t.Errorf("B user after A writes = %+v, %v; want active", u, err)
+		}
+	})
+
+	t.Run("version guard", func(t *testing.T) {
+		current, err := products.GetByID(ctxB, productB.ID)
+		if err != nil {
+			t.Fatalf("get B product: %v", err)
+		}
+		stale := current.Version
+		edit := &domain.Product{Title: "B lamp", Price: 120, Description: "b", Version: stale}
+		if err = products.UpdateByID(ctxB, productB.ID, edit); err != nil || edit.Version != stale+1 {
+			t.Fatalf("update = %v, version %d; want nil, %d", err, edit.Version, stale+1)
+		}
+		price := 90
+		if _, err = products.Patch(ctxB, productB.ID, stale, domain.ProductPatch{Price: &price}); !errors.Is(err, custom.ErrPreconditionFailed) {
+			t.Errorf("patch with stale version: err = %v, want ErrPreconditionFailed", err)
+		}
+		if err = products.DeleteByID(ctxB, productB.ID, stale); !errors.Is(err, custom.ErrPreconditionFailed) {
+			t.Errorf("delete with stale version: err = %v, want ErrPreconditionFailed", err)
+		}
+	})
+
+	t.Run("catalog revision", func(t *testing.T) {
+		before, err := products.Revision(ctxB)
+		if err != nil || before.Revision == 0 {
+			t.Fatalf("B revision = %+v, %v; want non-zero after create", before, err)
+		}
+		if rev, err := products.Revision(ctxA); err != nil || rev.Revision != 0 {
+			t.Errorf("A revision = %+v, %v; A must not see B revision", rev, err)
+		}
+
+		price := 80
+		if _, err = products.Patch(ctxB, productB.ID, 0, domain.ProductPatch{Price: &price}); !errors.Is(err, custom.ErrPreconditionFailed) {
+			t.Fatalf("patch with stale version: err = %v", err)
+		}
+		if rev, _ := products.Revision(ctxB); rev.Revision != before.Revision {
+			t.Errorf("revision after rejected patch = %d, want %d", rev.Revision, before.Revision)
+		}
+		current, err := products.GetByID(ctxB, productB.ID)
+		if err != nil {
+			t.Fatalf("get B product: %v", err)
+		}
+		if _, err = products.Patch(ctxB, productB.ID, current.Version, domain.ProductPatch{Price: &price}); err != nil {
+			t.Fatalf("patch: %v", err)
+		}
+		if rev, _ := products.Revision(ctxB); rev.Revision <= before.Revision {
+			t.Errorf("revision after patch = %d, want > %d", rev.Revision, before.Revision)
+		}
+	})
+
+	t.Run("trash", func(t *testing.T) {
+		current, err := products.GetByID(ctxB, productB.ID)
+		if err != nil {
+			t.Fatalf("get B product: %v", err)
+		}
+		if err = products.DeleteByID(ctxB, productB.ID, current.Version); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, err = products.GetByID(ctxB, productB.ID); !errors.Is(err, custom.ErrNotFound) {
+			t.Errorf("get trashed product: err = %v, want ErrNotFound", err)
+		}
+		if trash, err := products.ListDeleted(ctxA, 0); err != nil || len(trash) != 0 {
+			t.Errorf("A trash = %+v, %v; A must not see B trash", trash, err)
+		}
+		if _, err = products.Restore(ctxA, productB.ID); !errors.Is(err, custom.ErrNotFound) {
+			t.Errorf("A restores B product: err = %v, want ErrNotFound", err)
+		}
+		if err = products.Purge(ctxA, productB.ID); !errors.Is(err, custom.ErrNotFound) {
+			t.Errorf("A purges B product: err = %v, want ErrNotFound", err)
+		}
+		restored, err := products.Restore(ctxB, productB.ID)
+		if err != nil || restored.DeletedAt != nil || restored.Version != current.Version+2 {
+			t.Fatalf("restore = %+v, %v; want live product at version %d", restored, err, current.Version+2)
+		}
+	})
+
+	t.Run("lifecycle", func(t *testing.T) {
+		current, err := products.GetByID(ctxB, productB.ID)
+		if err != nil {
+			t.Fatalf("get B product: %v", err)
+		}
+		published := []domain.ProductStatus{domain.ProductStatusPublished}
+		if list, _ := products.GetAll(ctxB, published); slices.ContainsFunc(list, func(p domain.Product) bool { return p.ID == productB.ID }) {
+			t.Errorf("draft is listed as published")
+		}
+
+		due := time.Now().Add(-time.Minute)
+		scheduled, err := products.UpdateSchedule(ctxB, productB.ID, current.Version, domain.ProductSchedule{PublishAt: &due})
+		if err != nil || scheduled.PublishAt == nil {
+			t.Fatalf("schedule = %+v, %v", scheduled, err)
+		}
+		// Расписание чужого арендатора не применяется
+		if ids, err := products.PublishDue(ctxA); err != nil || slices.Contains(ids, productB.ID) {
+			t.Errorf("A publish due = %v, %v; must not touch B products", ids, err)
+		}
+		if ids, err := products.PublishDue(ctxB); err != nil || !slices.Contains(ids, productB.ID) {
+			t.Fatalf("B publish due = %v, %v; want B product published", ids, err)
+		}
+		list, err := products.GetAll(ctxB, published)
+		if err != nil || !slices.ContainsFunc(list, func(p domain.Product) bool { return p.ID == productB.ID && p.PublishAt == nil }) {
+			t.Fatalf("published list = %+v, %v; want B product without pending publish_at", list, err)
+		}
+
+		if _, err = products.UpdateStatus(ctxB, productB.ID, current.Version, domain.ProductStatusArchived); !errors.Is(err, custom.ErrPreconditionFailed) {
+			t.Errorf("status change with stale version: err = %v, want ErrPreconditionFailed", err)
+		}
+		archived, err := products.UpdateStatus(ctxB, productB.ID, scheduled.Version+1, domain.ProductStatusArchived)
+		if err != nil || archived.Status != domain.ProductStatusArchived {
+			t.Errorf("archive = %+v, %v", archived, err)
+		}
+	})
+
+	t.Run("roles", func(t *testing.T) {
+		// Администратор A с role:manage переписывает и удаляет только роли своего каталога
+		seller := auth.Role("seller")
+		if err := roles.Upsert(ctxA, &domain.Role{Name: seller, Description: "A sellers", Permissions: auth.Permissions{auth.PermUserManage}}); err != nil {
+			t.Fatalf("A saves seller: %v", err)
+		}
+		if perms, err := roles.GetPermissions(ctxB, seller); err != nil || !slices.Equal(perms, auth.Permissions{auth.PermProductWrite}) {
+			t.Errorf("B seller permissions = %v, %v; want [product:write]", perms, err)
+		}
+		if perms, err := roles.GetPermissions(ctxA, seller); err != nil || !slices.Equal(perms, auth.Permissions{auth.PermUserManage}) {
+			t.Errorf("A seller permissions = %v, %v; want [user:manage]", perms, err)
+		}
+
+		if err := roles.Upsert(ctxB, &domain.Role{Name: "b-editor", Permissions: auth.Permissions{auth.PermCategoryWrite}}); err != nil {
+			t.Fatalf("B saves custom role: %v", err)
+		}
+		all, err := roles.GetAll(ctxA)
+		if err != nil {
+			t.Fatalf("list A roles: %v", err)
+		}
+		if slices.ContainsFunc(all, func(r domain.Role) bool { return r.Name == "b-editor" }) {
+			t.Error("A role list contains B role")
+		}
+		if holders, err := roles.GetHolderIDs(ctxA, auth.RoleUser); err != nil || !slices.Contains(holders, userA.ID) || slices.Contains(holders, userB.ID) {
+			t.Errorf("A user role holders = %v, %v; want A users only", holders, err)
+		}
+		if err = roles.Delete(ctxA, "b-editor"); !errors.Is(err, custom.ErrNotFound) {
+			t.Errorf("A deletes B role: err = %v, want ErrNotFound", err)
+		}
+		if err = roles.Delete(ctxA, seller); err != nil {
+			t.Fatalf("A deletes own seller: %v", err)
+		}
+		if perms, err := roles.GetPermissions(ctxB, seller); err != nil || len(perms) != 1 {
+			t.Errorf("B seller after A delete = %v, %v; want kept", perms, err)
+		}
+		if perms, err := roles.GetPermissions(ctxB, "b-editor"); err != nil || len(perms) != 1 {
+			t.Errorf("B custom role after A delete = %v, %v; want kept", perms, err)
+		}
+	})
+
+	t.Run("identities", func(t *testing.T) {
+		// Один и тот же аккаунт IdP привязывается в A и создаёт пользователя в B
+		subject := fmt.Sprintf("sub-%d", suffix)
+		linked := &domain.UserIdentity{UserID: userA.ID, Provider: "google", Subject: subject, Email: "shared@example.com", CreatedAt: time.Now()}
+		if err := identities.Create(ctxA, linked); err != nil {
+			t.Fatalf("link identity in A: %v", err)
+		}
+		provisioned := &domain.User{Username: "sso-bob", Email: "bob@example.com", PasswordHash: "x", Role: auth.RoleUser, CreatedAt: time.Now()}
+		second := &domain.UserIdentity{Provider: "google", Subject: subject, Email: "bob@example.com", CreatedAt: time.Now()}
+		if err := identities.CreateUserWithIdentity(ctxB, provisioned, second); err != nil {
+			t.Fatalf("provision the same subject in B: %v", err)
+		}
+		if err := identities.Create(ctxA, &domain.UserIdentity{UserID: userA.ID, Provider: "google", Subject: subject, Email: "x", CreatedAt: time.Now()}); !errors.Is(err, custom.ErrConflict) {
+			t.Errorf("link the subject twice in A: err = %v, want ErrConflict", err)
+		}
+
+		for name, tc := range map[string]struct {
+			ctx  context.Context
+			want int
+		}{"A": {ctxA, userA.ID}, "B": {ctxB, provisioned.ID}} {
+			got, err := identities.GetByProviderSubject(tc.ctx, "google", subject)
+			if err != nil || got.UserID != tc.want {
+				t.Errorf("%s identity = %+v, %v; want user %d", name, got, err, tc.want)
+			}
+		}
+		if list, err := identities.ListByUserID(ctxA, provisioned.ID); err != nil || len(list) != 0 {
+			t.Errorf("A lists B identities = %+v, %v; want none", list, err)
+		}
+	})
+
+	t.Run("raw sql", func(t *testing.T) {
+		// Даже запрос в обход репозиториев не может записать строку чужому арендатору
+		tx, err := app.Begin(ctx)
+		if err != nil {
+			t.Fatalf("begin: %v", err)
+		}
+		defer tx.Rollback(ctx)
+		if _, err = tx.Exec(ctx, `SELECT set_config('app.tenant_id', $1, true)`, fmt.Sprint(tenantA)); err != nil {
+			t.Fatalf("set tenant: %v", err)
+		}
+		_, err = tx.Exec(ctx, `INSERT INTO products (tenant_id, title, price) VALUES ($1, 'smuggled', 1)`, tenantB)
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || pgErr.Code != "42501" {
+			t.Fatalf("insert into B from A: err = %v, want RLS violation", err)
+		}
+
+		// Без app.tenant_id не видно ни одной строки
+		var n int
+		if err = app.QueryRow(ctx, `SELECT count(*) FROM users`).Scan(&n); err != nil || n != 0 {
+			t.Fatalf("users visible without tenant = %d, %v; want 0", n, err)
+		}
+	})
+}
+
+func applySchema(t *testing.T, db *pgxpool.Pool) {
+	t.Helper()
+	ctx := context.Background()
+	var exists bool
+	if err := db.QueryRow(ctx, `SELECT to_regclass('public.tenants') IS NOT NULL`).Scan(&exists); err != nil {
+		t.Fatalf("check schema: %v", err)
+	}
+	if exists {
+		return
+	}
+	schema, err := os.ReadFile("../../../../schema.sql")
+	if err != nil {
+		t.Fatalf("read schema: %v", err)
+	}
+	if _, err = db.Exec(ctx, string(schema)); err != nil {
+		t.Fatalf("apply schema: %v", err)
+	}
+}
+
+func createTenant(t *testing.T, db *pgxpool.Pool, slug string) int {
+	t.Helper()
+	var id int
+	err := db.QueryRow(context.Background(), `INSERT INTO tenants (slug, name) VALUES ($1, $1) RETURNING id`, slug).Scan(&id)
+	if err != nil {
+		t.Fatalf("create tenant: %v", err)
+	}
+	return id
+}
@@ -0,0 +1,28 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	custom "product-catalog/internal/errors"
+)
+
+// staleOrMissing объясняет, почему запрос с условием version = $n не затронул строку:
+// строки нет — ErrNotFound, строка есть, но версия другая — ErrPreconditionFailed.
+// Вызывается в той же транзакции, что и сам запрос. alive — условие, при котором
+// строка считается существующей (например, товар не в корзине); пустое — любая строка.
+func staleOrMissing(ctx context.Context, tx pgx.Tx, table string, id int, alive string) error {
+	query := `SELECT EXISTS (SELECT 1 FROM ` + pgx.Identifier{table}.Sanitize() + ` WHERE id = $1`
+	if alive != "" {
+		query += ` AND ` + alive
+	}
+	query += `)`
+	var exists bool
+	if err := tx.QueryRow(ctx, query, id).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check %s version: %w", table, err)
+	}
+	if exists {
+		return custom.ErrPreconditionFailed
+	}
+	return custom.ErrNotFound
+}
@@ -0,0 +1,52 @@
+// Package jobs запускает периодические фоновые задачи внутри процесса API.
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Start запускает каждую задачу в отдельной горутине: первый прогон сразу,
+// затем раз в Interval. Задачи останавливаются при отмене ctx.
+func Start(ctx context.Context, logger *zap.Logger, jobs ...Job) {
+	for _, job := range jobs {
+		go run(ctx, logger.With(zap.String("job", job.Name)), job)
+	}
+}
+
+func run(ctx context.Context, logger *zap.Logger, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		runOnce(ctx, logger, job)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func runOnce(ctx context.Context, logger *zap.Logger, job Job) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			logger.Error("job panicked", zap.Any("panic", rec))
+		}
+	}()
+
+	started := time.Now()
+	if err := job.Run(ctx); err != nil {
+		logger.Error("job failed", zap.Error(err), zap.Duration("duration", time.Since(started)))
+		return
+	}
+	logger.Debug("job finished", zap.Duration("duration", time.Since(started)))
+}
@@ -0,0 +1,175 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	"product-catalog/internal/dto"
+	custom "product-catalog/internal/errors"
+	"strings"
+	"time"
+)
+
+const (
+	keyPrefix    = "pck_"
+	prefixLength = 8
+	secretLength = 32
+)
+
+type Repository interface {
+	Create(ctx context.Context, key *domain.APIKey) (int, error)
+	ListByUserID(ctx context.Context, userID int) ([]domain.APIKey, error)
+	Revoke(ctx context.Context, userID, id int) error
+	GetByHash(ctx context.Context, hash string) (*domain.APIKey, auth.Role, error)
+	TouchLastUsed(ctx context.Context, id int) error
+}
+
+type RoleRepository interface {
+	GetPermissions(ctx context.Context, role auth.Role) (auth.Permissions, error)
+}
+
+type Service struct {
+	repo     Repository
+	roleRepo RoleRepository
+}
+
+func NewAPIKeyService(repo Repository, roleRepo RoleRepository) *Service {
+	return &Service{repo: repo, roleRepo: roleRepo}
+}
+
+// CreateKey выпускает ключ; scopes — это разрешения, и выдать ключу можно
+// только те, что есть у самого пользователя.
+func (s *Service) CreateKey(ctx context.Context, userID int, perms auth.Permissions, input *dto.CreateAPIKeyInput) (*dto.CreatedAPIKeyOutput, error) {
+	if strings.TrimSpace(input.Name) == "" || len(input.Scopes) == 0 {
+		return nil, custom.ErrInvalidInput
+	}
+	for _, scope := range input.Scopes {
+		if !auth.IsKnownPermission(auth.Permission(scope)) {
+			return nil, fmt.Errorf("unknown scope %q: %w", scope, custom.ErrInvalidInput)
+		}
+		if !perms.Has(auth.Permission(scope)) {
+			return nil, fmt.Errorf("scope %q is not granted to user: %w", scope, custom.ErrForbidden)
+		}
+	}
+
+	raw, prefix, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &domain.APIKey{
+		UserID:    userID,
+		Name:      input.Name,
+		Prefix:    prefix,
+		KeyHash:   hashKey(raw),
+		Scopes:    input.Scopes,
+		CreatedAt: time.Now(),
+	}
+	if input.ExpiresInDays != nil {
+		if *input.ExpiresInDays <= 0 {
+			return nil, custom.ErrInvalidInput
+		}
+		expiresAt := key.CreatedAt.AddDate(0, 0, *input.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+
+	key.ID, err = s.repo.Create(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return &dto.CreatedAPIKeyOutput{APIKeyOutput: toOutput(key), Key: raw}, nil
+}
+
+func (s *Service) ListKeys(ctx context.Context, userID int) ([]dto.APIKeyOutput, error) {
+	keys, err := s.repo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+
+	out := make([]dto.APIKeyOutput, 0, len(keys))
+	for i := range keys {
+		out = append(out, toOutput(&keys[i]))
+	}
+	return out, nil
+}
+
+func (s *Service) RevokeKey(ctx context.Context, userID, keyID int) error {
+	if err := s.repo.Revoke(ctx, userID, keyID); err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) AuthenticateAPIKey(ctx context.Context, raw string) (*auth.APIKeyIdentity, error) {
+	if !strings.HasPrefix(raw, keyPrefix) {
+		return nil, custom.ErrUnauthorized
+	}
+
+	key, role, err := s.repo.GetByHash(ctx, hashKey(raw))
+	if err != nil {
+		if errors.Is(err, custom.ErrNotFound) {
+			return nil, custom.ErrUnauthorized
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt)) {
+		return nil, custom.ErrUnauthorized
+	}
+
+	rolePerms, err := s.roleRepo.GetPermissions(ctx, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role permissions: %w", err)
+	}
+
+	if err = s.repo.TouchLastUsed(ctx, key.ID); err != nil {
+		return nil, fmt.Errorf("failed to update api key last used: %w", err)
+	}
+
+	scopes := make(auth.Permissions, len(key.Scopes))
+	for i, sc := range key.Scopes {
+		scopes[i] = auth.Permission(sc)
+	}
+
+	return &auth.APIKeyIdentity{
+		KeyID:       key.ID,
+		UserID:      key.UserID,
+		Role:        role,
+		Permissions: rolePerms.Intersect(scopes),
+	}, nil
+}
+
+// generateKey возвращает ключ вида pck_<prefix>_<secret>; prefix хранится
+// открыто и нужен только чтобы пользователь мог узнать ключ в списке.
+func generateKey() (string, string, error) {
+	buf := make([]byte, prefixLength/2+secretLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	prefix := hex.EncodeToString(buf[:prefixLength/2])
+	secret := hex.EncodeToString(buf[prefixLength/2:])
+	return keyPrefix + prefix + "_" + secret, keyPrefix + prefix, nil
+}
+
+func hashKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func toOutput(k *domain.APIKey) dto.APIKeyOutput {
+	return dto.APIKeyOutput{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
@@ -0,0 +1,174 @@
+package apikey_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	"product-catalog/internal/dto"
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/service/apikey"
+)
+
+type fakeRepo struct {
+	keys    []*domain.APIKey
+	role    auth.Role
+	touched []int
+}
+
+func (r *fakeRepo) Create(_ context.Context, key *domain.APIKey) (int, error) {
+	cp := *key
+	cp.ID = len(r.keys) + 1
+	r.keys = append(r.keys, &cp)
+	return cp.ID, nil
+}
+
+func (r *fakeRepo) ListByUserID(_ context.Context, userID int) ([]domain.APIKey, error) {
+	var res []domain.APIKey
+	for _, k := range r.keys {
+		if k.UserID == userID {
+			res = append(res, *k)
+		}
+	}
+	return res, nil
+}
+
+func (r *fakeRepo) Revoke(_ context.Context, userID, id int) error {
+	for _, k := range r.keys {
+		if k.ID == id && k.UserID == userID {
+			now := time.Now()
+			k.RevokedAt = &now
+			return nil
+		}
+	}
+	return custom.ErrNotFound
+}
+
+func (r *fakeRepo) GetByHash(_ context.Context, hash string) (*domain.APIKey, auth.Role, error) {
+	for _, k := range r.keys {
+		if k.KeyHash == hash {
+			cp := *k
+			return &cp, r.role, nil
+		}
+	}
+	return nil, "", custom.ErrNotFound
+}
+
+func (r *fakeRepo) TouchLastUsed(_ context.Context, id int) error {
+	r.touched = append(r.touched, id)
+	return nil
+}
+
+type fakeRoles map[auth.Role]auth.Permissions
+
+func (r fakeRoles) GetPermissions(_ context.Context, role auth.Role) (auth.Permissions, error) {
+	return r[role], nil
+}
+
+// issueKey выпускает ключ продавцу со scopes product:write и category:write
+func issueKey(t *testing.T, repo *fakeRepo, roles fakeRoles) (*apikey.Service, string) {
+	t.Helper()
+	svc := apikey.NewAPIKeyService(repo, roles)
+	perms := auth.Permissions{auth.PermCategoryWrite, auth.PermProductWrite}
+	out, err := svc.CreateKey(context.Background(), 7, perms, &dto.CreateAPIKeyInput{
+		Name:   "ci",
+		Scopes: []string{string(auth.PermCategoryWrite), string(auth.PermProductWrite)},
+	})
+	if err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+	return svc, out.Key
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name      string
+		rolePerms auth.Permissions
+		mutate    func(k *domain.APIKey)
+		raw       func(raw string) string
+		wantErr   error
+		wantPerms auth.Permissions
+	}{
+		{
+			name:      "valid key",
+			rolePerms: auth.Permissions{auth.PermProductWrite, auth.PermCategoryWrite, auth.PermUserRead},
+			wantPerms: auth.Permissions{auth.PermProductWrite, auth.PermCategoryWrite},
+		},
+		{
+			name:      "scope the role lost is dropped",
+			rolePerms: auth.Permissions{auth.PermProductWrite},
+			wantPerms: auth.Permissions{auth.PermProductWrite},
+		},
+		{
+			name:      "revoked key",
+			rolePerms: auth.Permissions{auth.PermCategoryWrite, auth.PermProductWrite},
+			mutate:    func(k *domain.APIKey) { k.RevokedAt = &past },
+			wantErr:   custom.ErrUnauthorized,
+		},
+		{
+			name:      "expired key",
+			rolePerms: auth.Permissions{auth.PermCategoryWrite, auth.PermProductWrite},
+			mutate:    func(k *domain.APIKey) { k.ExpiresAt = &past },
+			wantErr:   custom.ErrUnauthorized,
+		},
+		{
+			name:      "wrong prefix",
+			rolePerms: auth.Permissions{auth.PermCategoryWrite, auth.PermProductWrite},
+			raw:       func(raw string) string { return "xyz_" + strings.TrimPrefix(raw, "pck_") },
+			wantErr:   custom.ErrUnauthorized,
+		},
+		{
+			name:      "unknown key",
+			rolePerms: auth.Permissions{auth.PermCategoryWrite, auth.PermProductWrite},
+			raw:       func(raw string) string { return raw + "0" },
+			wantErr:   custom.ErrUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepo{role: auth.RoleUser}
+			svc, raw := issueKey(t, repo, fakeRoles{auth.RoleUser: tt.rolePerms})
+			if tt.mutate != nil {
+				tt.mutate(repo.keys[0])
+			}
+			if tt.raw != nil {
+				raw = tt.raw(raw)
+			}
+
+			id, err := svc.AuthenticateAPIKey(context.Background(), raw)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v; want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(repo.touched) != 0 {
+					t.Errorf("rejected key marked as used: %v", repo.touched)
+				}
+				return
+			}
+			if id.UserID != 7 || id.KeyID != repo.keys[0].ID || id.Role != auth.RoleUser {
+				t.Errorf("identity = %+v; want user 7, key %d", id, repo.keys[0].ID)
+			}
+			if !slices.Equal(id.Permissions, tt.wantPerms) {
+				t.Errorf("permissions = %v; want %v", id.Permissions, tt.wantPerms)
+			}
+		})
+	}
+}
+
+func TestCreateKeyRejectsUngrantedScope(t *testing.T) {
+	svc := apikey.NewAPIKeyService(&fakeRepo{}, fakeRoles{})
+	_, err := svc.CreateKey(context.Background(), 7, auth.Permissions{auth.PermProductWrite}, &dto.CreateAPIKeyInput{
+		Name:   "ci",
+		Scopes: []string{string(auth.PermCategoryWrite)},
+	})
+	if !errors.Is(err, custom.ErrForbidden) {
+		t.Fatalf("err = %v; want ErrForbidden", err)
+	}
+}
@@ -0,0 +1,360 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	"product-catalog/internal/dto"
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/service/file"
+	"time"
+)
+
+const (
+	// Сколько выгрузка может собираться, прежде чем считается зависшей
+	buildTimeout = 15 * time.Minute
+	// Верхняя граница записей журнала имперсонации в одном архиве
+	auditLimit = 10000
+)
+
+type Repository interface {
+	Create(ctx context.Context, export *domain.DataExport) (int, error)
+	GetLatestByUserID(ctx context.Context, userID int) (*domain.DataExport, error)
+	MarkReady(ctx context.Context, id int, objectKey string, expiresAt time.Time) error
+	MarkFailed(ctx context.Context, id int, reason string) error
+	ListExpired(ctx context.Context, staleBefore time.Time) ([]domain.DataExport, error)
+	Delete(ctx context.Context, id int) error
+}
+
+type UserRepository interface {
+	GetByID(ctx context.Context, id int) (*domain.User, error)
+}
+
+type SessionRepository interface {
+	ListByUserID(ctx context.Context, userID int) ([]domain.Session, error)
+}
+
+type IdentityRepository interface {
+	ListByUserID(ctx context.Context, userID int) ([]domain.UserIdentity, error)
+}
+
+type APIKeyLister interface {
+	ListKeys(ctx context.Context, userID int) ([]dto.APIKeyOutput, error)
+}
+
+type ProductRepository interface {
+	ListByCreator(ctx context.Context, userID int) ([]domain.Product, error)
+}
+
+type AuditRepository interface {
+	List(ctx context.Context, userID, actorID, limit int) ([]domain.ImpersonationAuditRecord, error)
+}
+
+type Service struct {
+	repo       Repository
+	users      UserRepository
+	sessions   SessionRepository
+	identities IdentityRepository
+	apiKeys    APIKeyLister
+	products   ProductRepository
+	audit      AuditRepository
+	sto        file.Storage
+	linkTTL    time.Duration
+}
+
+func NewExportService(repo Repository, users UserRepository, sessions SessionRepository, identities IdentityRepository, apiKeys APIKeyLister, products ProductRepository, audit AuditRepository, sto file.Storage, linkTTL time.Duration) *Service {
+	return &Service{
+		repo:       repo,
+		users:      users,
+		sessions:   sessions,
+		identities: identities,
+		apiKeys:    apiKeys,
+		products:   products,
+		audit:      audit,
+		sto:        sto,
+		linkTTL:    linkTTL,
+	}
+}
+
+// RequestExport возвращает актуальную выгрузку пользователя. Если готового
+// архива нет, ставит сборку в фон и возвращает выгрузку в статусе pending.
+func (s *Service) RequestExport(ctx context.Context, userID int) (*dto.DataExportOutput, error) {
+	if _, impersonating := auth.ActorIDFromContext(ctx); impersonating {
+		return nil, fmt.Errorf("data export is not allowed while impersonating: %w", custom.ErrForbidden)
+	}
+
+	latest, err := s.repo.GetLatestByUserID(ctx, userID)
+	if err != nil && !errors.Is(err, custom.ErrNotFound) {
+		return nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+	if latest != nil {
+		switch {
+		case latest.Status == domain.DataExportReady && latest.ExpiresAt != nil && time.Until(*latest.ExpiresAt) > time.Minute:
+			return s.toOutput(ctx, latest)
+		case latest.Status == domain.DataExportPending && time.Since(latest.CreatedAt) < buildTimeout:
+			return s.toOutput(ctx, latest)
+		case latest.Status == domain.DataExportPending:
+			// Процесс, собиравший архив, скорее всего перезапустился
+			if err = s.repo.MarkFailed(ctx, latest.ID, "build timed out"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	export := &domain.DataExport{UserID: userID, Status: domain.DataExportPending, CreatedAt: time.Now()}
+	export.ID, err = s.repo.Create(ctx, export)
+	if errors.Is(err, custom.ErrConflict) {
+		// Параллельный запрос уже поставил сборку
+		return s.latestOutput(ctx, userID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create data export: %w", err)
+	}
+
+	go s.build(context.WithoutCancel(ctx), export.ID, userID)
+
+	return s.toOutput(ctx, export)
+}
+
+// PurgeExpired удаляет архивы с истёкшими ссылками и выгрузки обезличенных пользователей.
+func (s *Service) PurgeExpired(ctx context.Context) (int, error) {
+	exports, err := s.repo.ListExpired(ctx, time.Now().Add(-buildTimeout))
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	purged := 0
+	for _, e := range exports {
+		if e.ObjectKey != "" {
+			if err = s.sto.Delete(ctx, e.ObjectKey); err != nil {
+				errs = append(errs, fmt.Errorf("export %d: %w", e.ID, err))
+				continue
+			}
+		}
+		if err = s.repo.Delete(ctx, e.ID); err != nil {
+			errs = append(errs, fmt.Errorf("export %d: %w", e.ID, err))
+			continue
+		}
+		purged++
+	}
+	return purged, errors.Join(errs...)
+}
+
+// build собирает архив после завершения запроса: клиент не ждёт сборки.
+// Из контекста запроса сохраняется только арендатор и другие значения, не отмена.
+func (s *Service) build(ctx context.Context, id, userID int) {
+	ctx, cancel := context.WithTimeout(ctx, buildTimeout)
+	defer cancel()
+
+	archive, err := s.buildArchive(ctx, userID)
+	if err == nil {
+		var key string
+		key, err = objectKey(ctx, userID)
+		if err == nil {
+			err = s.sto.Upload(ctx, key, bytes.NewReader(archive), int64(len(archive)), "application/zip")
+		}
+		if err == nil {
+			err = s.repo.MarkReady(ctx, id, key, time.Now().Add(s.linkTTL))
+			if err != nil {
+				_ = s.sto.Delete(ctx, key)
+			}
+		}
+	}
+	if err != nil {
+		_ = s.repo.MarkFailed(ctx, id, err.Error())
+	}
+}
+
+// buildArchive собирает zip: export.json и загруженные пользователем файлы в files/.
+func (s *Service) buildArchive(ctx context.Context, userID int) ([]byte, error) {
+	data, err := s.collect(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := s.products.ListByCreator(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list products: %w", err)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	data.Products = make([]dto.ExportedProduct, 0, len(products))
+	for _, p := range products {
+		out := dto.ExportedProduct{ID: p.ID, Title: p.Title, Price: p.Price, Description: p.Description, Available: p.Available, CreatedAt: p.CreatedAt}
+		if key, ok := file.KeyFromURL(p.ImageURL); ok {
+			name := "files/" + path.Base(key)
+			// Отсутствующий в хранилище файл не должен ломать всю выгрузку
+			if err = s.addFile(ctx, zw, name, key); err == nil {
+				out.ImageFile = name
+			}
+		}
+		data.Products = append(data.Products, out)
+	}
+
+	w, err := zw.Create("export.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to add export.json: %w", err)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(data); err != nil {
+		return nil, fmt.Errorf("failed to encode export.json: %w", err)
+	}
+
+	if err = zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *Service) collect(ctx context.Context, userID int) (*dto.UserDataExport, error) {
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	data := &dto.UserDataExport{
+		GeneratedAt: time.Now().UTC(),
+		Profile: dto.UserOutput{
+			ID: u.ID, Username: u.Username, Email: u.Email, Role: u.Role,
+			Status: string(u.Status), DeletedAt: u.DeletedAt, CreatedAt: u.CreatedAt,
+		},
+	}
+
+	identities, err := s.identities.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	data.Identities = make([]dto.IdentityOutput, 0, len(identities))
+	for _, identity := range identities {
+		data.Identities = append(data.Identities, dto.IdentityOutput{
+			Provider:    identity.Provider,
+			Email:       identity.Email,
+			CreatedAt:   identity.CreatedAt,
+			LastLoginAt: identity.LastLoginAt,
+		})
+	}
+
+	sessions, err := s.sessions.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	data.Sessions = make([]dto.SessionOutput, 0, len(sessions))
+	for _, sess := range sessions {
+		data.Sessions = append(data.Sessions, dto.SessionOutput{
+			ID:             sess.ID,
+			Device:         sess.Device,
+			IP:             sess.IP,
+			UserAgent:      sess.UserAgent,
+			CreatedAt:      sess.CreatedAt,
+			LastSeenAt:     sess.LastSeenAt,
+			ImpersonatedBy: sess.ImpersonatorID,
+		})
+	}
+
+	data.APIKeys, err = s.apiKeys.ListKeys(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	if data.APIKeys == nil {
+		data.APIKeys = []dto.APIKeyOutput{}
+	}
+
+	// Пользователь может фигурировать в журнале и как цель, и как администратор
+	asTarget, err := s.audit.List(ctx, userID, 0, auditLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list impersonation audit: %w", err)
+	}
+	asActor, err := s.audit.List(ctx, 0, userID, auditLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list impersonation audit: %w", err)
+	}
+	seen := make(map[int]bool)
+	data.ImpersonationAudit = make([]dto.ImpersonationAuditOutput, 0, len(asTarget)+len(asActor))
+	for _, rec := range append(asTarget, asActor...) {
+		if seen[rec.ID] {
+			continue
+		}
+		seen[rec.ID] = true
+		data.ImpersonationAudit = append(data.ImpersonationAudit, dto.ImpersonationAuditOutput{
+			ID:        rec.ID,
+			ActorID:   rec.ActorID,
+			UserID:    rec.UserID,
+			SessionID: rec.SessionID,
+			Action:    rec.Action,
+			Method:    rec.Method,
+			Path:      rec.Path,
+			Status:    rec.Status,
+			IP:        rec.IP,
+			Reason:    rec.Reason,
+			CreatedAt: rec.CreatedAt,
+		})
+	}
+
+	return data, nil
+}
+
+func (s *Service) addFile(ctx context.Context, zw *zip.Writer, name, key string) error {
+	rc, err := s.sto.Download(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, rc)
+	return err
+}
+
+func (s *Service) latestOutput(ctx context.Context, userID int) (*dto.DataExportOutput, error) {
+	latest, err := s.repo.GetLatestByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+	return s.toOutput(ctx, latest)
+}
+
+func (s *Service) toOutput(ctx context.Context, e *domain.DataExport) (*dto.DataExportOutput, error) {
+	out := &dto.DataExportOutput{
+		ID:          e.ID,
+		Status:      string(e.Status),
+		CreatedAt:   e.CreatedAt,
+		CompletedAt: e.CompletedAt,
+		ExpiresAt:   e.ExpiresAt,
+	}
+	if e.Status == domain.DataExportReady && e.ExpiresAt != nil {
+		url, err := s.sto.GetPresignedURL(ctx, e.ObjectKey, time.Until(*e.ExpiresAt))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get download url: %w", err)
+		}
+		out.DownloadURL = url
+	}
+	return out, nil
+}
+
+func objectKey(ctx context.Context, userID int) (string, error) {
+	tenantID, ok := auth.TenantIDFromContext(ctx)
+	if !ok {
+		return "", errors.New("tenant is not set in context")
+	}
+	buf := make([]byte, file.KeyLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	key := fmt.Sprintf("exports/%d/%d_%s.zip", userID, time.Now().UnixNano(), hex.EncodeToString(buf))
+	return file.TenantKey(tenantID, key), nil
+}
@@ -0,0 +1,250 @@
+package export_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	"product-catalog/internal/dto"
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/service/export"
+)
+
+type fakeExportRepo struct {
+	mu      sync.Mutex
+	exports map[int]*domain.DataExport
+}
+
+func (r *fakeExportRepo) Create(_ context.Context, e *domain.DataExport) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.exports {
+		if existing.UserID == e.UserID && existing.Status == domain.DataExportPending {
+			return 0, custom.ErrConflict
+		}
+	}
+	cp := *e
+	cp.ID = len(r.exports) + 1
+	r.exports[cp.ID] = &cp
+	return cp.ID, nil
+}
+
+func (r *fakeExportRepo) GetLatestByUserID(_ context.Context, userID int) (*domain.DataExport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var latest *domain.DataExport
+	for _, e := range r.exports {
+		if e.UserID == userID && (latest == nil || e.ID > latest.ID) {
+			latest = e
+		}
+	}
+	if latest == nil {
+		return nil, custom.ErrNotFound
+	}
+	cp := *latest
+	return &cp, nil
+}
+
+func (r *fakeExportRepo) MarkReady(_ context.Context, id int, objectKey string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := r.exports[id]
+	e.Status, e.ObjectKey, e.ExpiresAt = domain.DataExportReady, objectKey, &expiresAt
+	return nil
+}
+
+func (r *fakeExportRepo) MarkFailed(_ context.Context, id int, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exports[id].Status, r.exports[id].Error = domain.DataExportFailed, reason
+	return nil
+}
+
+func (r *fakeExportRepo) ListExpired(context.Context, time.Time) ([]domain.DataExport, error) {
+	return nil, nil
+}
+
+func (r *fakeExportRepo) Delete(context.Context, int) error { return nil }
+
+type fakeStorage struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *fakeStorage) Upload(_ context.Context, key string, body io.Reader, _ int64, _ string) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = data
+	return nil
+}
+
+func (s *fakeStorage) GetPresignedURL(_ context.Context, key string, _ time.Duration) (string, error) {
+	return "http://storage.local/uploads/" + key, nil
+}
+
+func (s *fakeStorage) Download(_ context.Context, key string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, errors.New("no such key")
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *fakeStorage) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *fakeStorage) Exists(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.objects[key]
+	return ok, nil
+}
+
+type fakeUsers struct{}
+
+func (fakeUsers) GetByID(_ context.Context, id int) (*domain.User, error) {
+	return &domain.User{ID: id, Username: "alice", Email: "alice@example.com", PasswordHash: "secret-hash", Role: auth.RoleUser, Status: domain.UserStatusActive}, nil
+}
+
+type fakeSessions struct{}
+
+func (fakeSessions) ListByUserID(_ context.Context, userID int) ([]domain.Session, error) {
+	return []domain.Session{{ID: "sid-1", UserID: userID, Device: "Firefox on Linux", IP: "10.0.0.1"}}, nil
+}
+
+type fakeIdentities struct{}
+
+func (fakeIdentities) ListByUserID(context.Context, int) ([]domain.UserIdentity, error) {
+	return nil, nil
+}
+
+type fakeAPIKeys struct{}
+
+func (fakeAPIKeys) ListKeys(context.Context, int) ([]dto.APIKeyOutput, error) {
+	return []dto.APIKeyOutput{{ID: 1, Name: "ci", Prefix: "pc_abc"}}, nil
+}
+
+type fakeProducts struct{}
+
+func (fakeProducts) ListByCreator(_ context.Context, userID int) ([]domain.Product, error) {
+	return []domain.Product{
+		{ID: 1, Title: "Lamp", Price: 100, ImageURL: "http://storage.local/uploads/tenants/3/lamp.png?X-Amz-Signature=abc", CreatedBy: userID},
+		{ID: 2, Title: "Chair", Price: 200, ImageURL: "http://storage.local/uploads/missing.png", CreatedBy: userID},
+	}, nil
+}
+
+type fakeAudit struct{}
+
+func (fakeAudit) List(_ context.Context, userID, actorID, _ int) ([]domain.ImpersonationAuditRecord, error) {
+	// Одна и та же запись попадает в обе выборки
+	return []domain.ImpersonationAuditRecord{{ID: 7, ActorID: 1, UserID: 1, Action: domain.ImpersonationActionStart}}, nil
+}
+
+func TestRequestExportBuildsArchive(t *testing.T) {
+	repo := &fakeExportRepo{exports: map[int]*domain.DataExport{}}
+	sto := &fakeStorage{objects: map[string][]byte{"tenants/3/lamp.png": []byte("png-bytes")}}
+	svc := export.NewExportService(repo, fakeUsers{}, fakeSessions{}, fakeIdentities{}, fakeAPIKeys{}, fakeProducts{}, fakeAudit{}, sto, time.Hour)
+
+	ctx := auth.WithTenant(context.Background(), 3)
+	first, err := svc.RequestExport(ctx, 1)
+	if err != nil {
+		t.Fatalf("request export: %v", err)
+	}
+
+	var out *dto.DataExportOutput
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		out, err = svc.RequestExport(ctx, 1)
+		if err != nil {
+			t.Fatalf("poll export: %v", err)
+		}
+		if out.Status != string(domain.DataExportPending) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if out.ID != first.ID {
+		t.Fatalf("export id = %d, want %d: polling must not start a new export", out.ID, first.ID)
+	}
+	if out.Status != string(domain.DataExportReady) || out.DownloadURL == "" {
+		t.Fatalf("export = %+v, want ready with download url", out)
+	}
+
+	key := strings.TrimPrefix(out.DownloadURL, "http://storage.local/uploads/")
+	if !strings.HasPrefix(key, "tenants/3/") {
+		t.Errorf("archive key %q is not under the tenant prefix", key)
+	}
+	archive := sto.objects[key]
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("open archive: %v", err)
+	}
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	if files["files/lamp.png"] != "png-bytes" {
+		t.Errorf("archive is missing uploaded image, files: %v", keys(files))
+	}
+	if strings.Contains(files["export.json"], "secret-hash") {
+		t.Error("export.json leaks password hash")
+	}
+
+	var data dto.UserDataExport
+	if err = json.Unmarshal([]byte(files["export.json"]), &data); err != nil {
+		t.Fatalf("decode export.json: %v", err)
+	}
+	if data.Profile.Email != "alice@example.com" || len(data.Sessions) != 1 || len(data.APIKeys) != 1 {
+		t.Errorf("unexpected export contents: %+v", data)
+	}
+	if len(data.Products) != 2 || data.Products[0].ImageFile != "files/lamp.png" || data.Products[1].ImageFile != "" {
+		t.Errorf("products = %+v", data.Products)
+	}
+	if len(data.ImpersonationAudit) != 1 {
+		t.Errorf("audit records = %d, want 1 after deduplication", len(data.ImpersonationAudit))
+	}
+}
+
+func TestRequestExportDeniedWhileImpersonating(t *testing.T) {
+	repo := &fakeExportRepo{exports: map[int]*domain.DataExport{}}
+	svc := export.NewExportService(repo, fakeUsers{}, fakeSessions{}, fakeIdentities{}, fakeAPIKeys{}, fakeProducts{}, fakeAudit{}, &fakeStorage{objects: map[string][]byte{}}, time.Hour)
+
+	ctx := auth.WithActor(context.Background(), 99)
+	if _, err := svc.RequestExport(ctx, 1); !errors.Is(err, custom.ErrForbidden) {
+		t.Fatalf("err = %v, want ErrForbidden", err)
+	}
+	if len(repo.exports) != 0 {
+		t.Fatal("export was created while impersonating")
+	}
+}
+
+func keys(m map[string]string) []string {
+	var res []string
+	for k := range m {
+		res = append(res, k)
+	}
+	return res
+}
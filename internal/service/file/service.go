@@ -9,7 +9,11 @@ import (
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/url"
+	"path"
 	"path/filepath"
+	"product-catalog/internal/auth"
+	custom "product-catalog/internal/errors"
 	"strings"
 	"time"
 )
@@ -18,11 +22,16 @@ const (
 	MaxFileSize  = 10 << 20
 	AllowedTypes = "image/jpeg,image/png,application/pdf"
 	KeyLength    = 16
+	// TenantsDir — корень, под которым лежат файлы каждого арендатора
+	TenantsDir = "tenants"
 )
 
 type Storage interface {
 	Upload(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
 	GetPresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+	Download(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	Exists(ctx context.Context, key string) (bool, error)
 }
 
 type FileService struct {
@@ -33,9 +42,20 @@ func NewFileService(sto Storage) *FileService {
 	return &FileService{sto: sto}
 }
 
+// Upload сохраняет файл и возвращает presigned URL на него
 func (s *FileService) Upload(ctx context.Context, fh *multipart.FileHeader) (string, error) {
+	key, err := s.Store(ctx, fh)
+	if err != nil {
+		return "", err
+	}
+	return s.presign(ctx, key)
+}
+
+// Store проверяет и сохраняет файл под префиксом арендатора и возвращает его ключ.
+// Ключ затем можно передать в JSON-запросе вместо самого файла.
+func (s *FileService) Store(ctx context.Context, fh *multipart.FileHeader) (string, error) {
 	if fh.Size > MaxFileSize {
-		return "", errors.New("file size exceeds maximum allowed")
+		return "", fmt.Errorf("%w: file size exceeds maximum allowed", custom.ErrInvalidInput)
 	}
 
 	file, err := fh.Open()
@@ -50,30 +70,90 @@ func (s *FileService) Upload(ctx context.Context, fh *multipart.FileHeader) (str
 	}
 
 	if !isAllowedType(realType) {
-		return "", fmt.Errorf("file type not allowed: %s", realType)
+		return "", fmt.Errorf("%w: file type not allowed: %s", custom.ErrInvalidInput, realType)
 	}
 
 	if _, err = file.Seek(0, io.SeekStart); err != nil {
 		return "", fmt.Errorf("reset file pointer: %w", err)
 	}
 
+	tenantID, ok := auth.TenantIDFromContext(ctx)
+	if !ok {
+		return "", errors.New("tenant is not set in context")
+	}
+
 	key, err := generateSafeKey(filepath.Ext(fh.Filename))
 	if err != nil {
 		return "", fmt.Errorf("generate key: %w", err)
 	}
+	key = TenantKey(tenantID, key)
 
 	if err := s.sto.Upload(ctx, key, file, fh.Size, fh.Header.Get("Content-Type")); err != nil {
 		return "", fmt.Errorf("upload file: %w", err)
 	}
+	return key, nil
+}
+
+// URLForKey возвращает presigned URL для ранее загруженного файла. Ключ должен
+// принадлежать арендатору из контекста и существовать в хранилище.
+func (s *FileService) URLForKey(ctx context.Context, key string) (string, error) {
+	tenantID, ok := auth.TenantIDFromContext(ctx)
+	if !ok {
+		return "", errors.New("tenant is not set in context")
+	}
+	if path.Clean(key) != key || !strings.HasPrefix(key, TenantKey(tenantID, "")) {
+		return "", fmt.Errorf("%w: unknown image key", custom.ErrInvalidInput)
+	}
 
+	exists, err := s.sto.Exists(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("check file: %w", err)
+	}
+	if !exists {
+		return "", fmt.Errorf("%w: unknown image key", custom.ErrInvalidInput)
+	}
+	return s.presign(ctx, key)
+}
+
+// Delete удаляет ранее сохранённый файл, например загруженный для отклонённого запроса
+func (s *FileService) Delete(ctx context.Context, key string) error {
+	if err := s.sto.Delete(ctx, key); err != nil {
+		return fmt.Errorf("delete file: %w", err)
+	}
+	return nil
+}
+
+func (s *FileService) presign(ctx context.Context, key string) (string, error) {
 	url, err := s.sto.GetPresignedURL(ctx, key, 24*time.Hour)
 	if err != nil {
 		return "", fmt.Errorf("get presigned URL: %w", err)
 	}
-
 	return url, nil
 }
 
+// TenantKey добавляет к ключу префикс арендатора, чтобы файлы разных каталогов
+// не пересекались в общем бакете.
+func TenantKey(tenantID int, key string) string {
+	return fmt.Sprintf("%s/%d/%s", TenantsDir, tenantID, key)
+}
+
+// KeyFromURL извлекает ключ объекта из presigned URL, выданного Upload.
+// Файлы, загруженные до разделения на арендаторов, лежат в корне бакета.
+func KeyFromURL(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Path == "" {
+		return "", false
+	}
+	if i := strings.Index(u.Path, "/"+TenantsDir+"/"); i >= 0 {
+		return u.Path[i+1:], true
+	}
+	key := path.Base(u.Path)
+	if key == "/" || key == "." {
+		return "", false
+	}
+	return key, true
+}
+
 func generateSafeKey(ext string) (string, error) {
 	buf := make([]byte, KeyLength)
 	if _, err := rand.Read(buf); err != nil {
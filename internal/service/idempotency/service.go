@@ -0,0 +1,78 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+	"time"
+)
+
+type Repository interface {
+	Reserve(ctx context.Context, rec *domain.IdempotencyRecord) (bool, error)
+	Get(ctx context.Context, userID int, key string) (*domain.IdempotencyRecord, error)
+	Complete(ctx context.Context, rec *domain.IdempotencyRecord) error
+	Release(ctx context.Context, userID int, key string) error
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+// Service хранит ответы на запросы с Idempotency-Key в течение ttl. Ключи
+// действуют в пределах пользователя: чужой ключ не даст прочитать чужой ответ.
+type Service struct {
+	repo Repository
+	ttl  time.Duration
+}
+
+func NewIdempotencyService(repo Repository, ttl time.Duration) *Service {
+	return &Service{repo: repo, ttl: ttl}
+}
+
+// Begin занимает ключ за запросом. Если запрос с этим ключом уже выполнен,
+// возвращается сохранённый ответ; nil означает, что запрос нужно выполнить и
+// затем вызвать Complete или Release. Тот же ключ с другим запросом —
+// ErrIdempotencyKeyReused, ещё не завершённый запрос — ErrConflict.
+func (s *Service) Begin(ctx context.Context, userID int, key, fingerprint string) (*domain.IdempotencyRecord, error) {
+	now := time.Now()
+	rec := &domain.IdempotencyRecord{UserID: userID, Key: key, Fingerprint: fingerprint, CreatedAt: now, ExpiresAt: now.Add(s.ttl)}
+	reserved, err := s.repo.Reserve(ctx, rec)
+	if err != nil {
+		return nil, err
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	existing, err := s.repo.Get(ctx, userID, key)
+	if err != nil {
+		if errors.Is(err, custom.ErrNotFound) {
+			// Первый запрос освободил ключ между Reserve и Get
+			return nil, fmt.Errorf("%w: request with this Idempotency-Key was just released, retry", custom.ErrConflict)
+		}
+		return nil, err
+	}
+	if existing.Fingerprint != fingerprint {
+		return nil, fmt.Errorf("%w: key was used with a different request", custom.ErrIdempotencyKeyReused)
+	}
+	if existing.StatusCode == 0 {
+		return nil, fmt.Errorf("%w: request with this Idempotency-Key is still in progress", custom.ErrConflict)
+	}
+	return existing, nil
+}
+
+// Complete сохраняет ответ на запрос, занявший ключ в Begin
+func (s *Service) Complete(ctx context.Context, rec *domain.IdempotencyRecord) error {
+	if err := s.repo.Complete(ctx, rec); err != nil {
+		return fmt.Errorf("failed to store response: %w", err)
+	}
+	return nil
+}
+
+// Release освобождает ключ, не сохраняя ответ: повтор выполнится заново
+func (s *Service) Release(ctx context.Context, userID int, key string) error {
+	return s.repo.Release(ctx, userID, key)
+}
+
+func (s *Service) PurgeExpired(ctx context.Context) (int, error) {
+	return s.repo.DeleteExpired(ctx, time.Now())
+}
@@ -0,0 +1,134 @@
+package idempotency_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/service/idempotency"
+)
+
+type recordKey struct {
+	userID int
+	key    string
+}
+
+type fakeRepo struct {
+	records map[recordKey]*domain.IdempotencyRecord
+}
+
+func (r *fakeRepo) Reserve(_ context.Context, rec *domain.IdempotencyRecord) (bool, error) {
+	k := recordKey{rec.UserID, rec.Key}
+	if existing, ok := r.records[k]; ok && existing.ExpiresAt.After(rec.CreatedAt) {
+		return false, nil
+	}
+	cp := *rec
+	r.records[k] = &cp
+	return true, nil
+}
+
+func (r *fakeRepo) Get(_ context.Context, userID int, key string) (*domain.IdempotencyRecord, error) {
+	rec, ok := r.records[recordKey{userID, key}]
+	if !ok {
+		return nil, custom.ErrNotFound
+	}
+	cp := *rec
+	return &cp, nil
+}
+
+func (r *fakeRepo) Complete(_ context.Context, rec *domain.IdempotencyRecord) error {
+	existing, ok := r.records[recordKey{rec.UserID, rec.Key}]
+	if !ok || existing.StatusCode != 0 {
+		return custom.ErrNotFound
+	}
+	existing.StatusCode, existing.Header, existing.Body = rec.StatusCode, rec.Header, rec.Body
+	return nil
+}
+
+func (r *fakeRepo) Release(_ context.Context, userID int, key string) error {
+	if rec, ok := r.records[recordKey{userID, key}]; ok && rec.StatusCode == 0 {
+		delete(r.records, recordKey{userID, key})
+	}
+	return nil
+}
+
+func (r *fakeRepo) DeleteExpired(_ context.Context, now time.Time) (int, error) {
+	n := 0
+	for k, rec := range r.records {
+		if !rec.ExpiresAt.After(now) {
+			delete(r.records, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestBeginReplay(t *testing.T) {
+	repo := &fakeRepo{records: map[recordKey]*domain.IdempotencyRecord{}}
+	svc := idempotency.NewIdempotencyService(repo, time.Hour)
+	ctx := context.Background()
+
+	stored, err := svc.Begin(ctx, 1, "k1", "fp")
+	if err != nil || stored != nil {
+		t.Fatalf("first begin = %v, %v; want the request to run", stored, err)
+	}
+
+	// Повтор, пока первый запрос выполняется
+	if _, err = svc.Begin(ctx, 1, "k1", "fp"); !errors.Is(err, custom.ErrConflict) {
+		t.Fatalf("begin in progress err = %v, want ErrConflict", err)
+	}
+
+	err = svc.Complete(ctx, &domain.IdempotencyRecord{UserID: 1, Key: "k1", Fingerprint: "fp", StatusCode: 201, Body: []byte(`{"id":7}`)})
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	stored, err = svc.Begin(ctx, 1, "k1", "fp")
+	if err != nil || stored == nil || stored.StatusCode != 201 || string(stored.Body) != `{"id":7}` {
+		t.Fatalf("retry = %+v, %v; want stored 201", stored, err)
+	}
+
+	// Тот же ключ с другим телом
+	if _, err = svc.Begin(ctx, 1, "k1", "other"); !errors.Is(err, custom.ErrIdempotencyKeyReused) {
+		t.Errorf("reused key err = %v, want ErrIdempotencyKeyReused", err)
+	}
+	// Ключи разных пользователей независимы
+	if stored, err = svc.Begin(ctx, 2, "k1", "other"); err != nil || stored != nil {
+		t.Errorf("other user begin = %v, %v; want the request to run", stored, err)
+	}
+}
+
+func TestReleaseAllowsRetry(t *testing.T) {
+	repo := &fakeRepo{records: map[recordKey]*domain.IdempotencyRecord{}}
+	svc := idempotency.NewIdempotencyService(repo, time.Hour)
+	ctx := context.Background()
+
+	if _, err := svc.Begin(ctx, 1, "k1", "fp"); err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	if err := svc.Release(ctx, 1, "k1"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if stored, err := svc.Begin(ctx, 1, "k1", "fp"); err != nil || stored != nil {
+		t.Fatalf("begin after release = %v, %v; want the request to run again", stored, err)
+	}
+}
+
+func TestExpiredKey(t *testing.T) {
+	repo := &fakeRepo{records: map[recordKey]*domain.IdempotencyRecord{}}
+	svc := idempotency.NewIdempotencyService(repo, -time.Second)
+	ctx := context.Background()
+
+	if _, err := svc.Begin(ctx, 1, "k1", "fp"); err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	// Истёкший ключ можно использовать с другим запросом
+	if stored, err := svc.Begin(ctx, 1, "k1", "other"); err != nil || stored != nil {
+		t.Fatalf("begin with expired key = %v, %v; want the request to run", stored, err)
+	}
+	if n, err := svc.PurgeExpired(ctx); err != nil || n != 1 {
+		t.Errorf("purged = %d, %v; want 1", n, err)
+	}
+}
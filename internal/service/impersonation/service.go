@@ -0,0 +1,149 @@
+package impersonation
+
+import (
+	"context"
+	"fmt"
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	"product-catalog/internal/dto"
+	custom "product-catalog/internal/errors"
+	"strings"
+	"time"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+type AuditRepository interface {
+	Create(ctx context.Context, record *domain.ImpersonationAuditRecord) (int, error)
+	SetStatus(ctx context.Context, id, status int) error
+	List(ctx context.Context, userID, actorID, limit int) ([]domain.ImpersonationAuditRecord, error)
+}
+
+type UserRepository interface {
+	GetByID(ctx context.Context, id int) (*domain.User, error)
+}
+
+type RoleRepository interface {
+	GetPermissions(ctx context.Context, role auth.Role) (auth.Permissions, error)
+}
+
+type SessionStarter interface {
+	StartImpersonationSession(ctx context.Context, userID, actorID int, client dto.ClientInfo, ttl time.Duration) (string, error)
+}
+
+type TokenIssuer interface {
+	GenerateImpersonationToken(userID, tenantID int, role auth.Role, perms auth.Permissions, sessionID string, actorID int, ttl time.Duration) (string, error)
+}
+
+type Service struct {
+	audit    AuditRepository
+	users    UserRepository
+	roleRepo RoleRepository
+	sessions SessionStarter
+	tokens   TokenIssuer
+	ttl      time.Duration
+}
+
+func NewImpersonationService(audit AuditRepository, users UserRepository, roleRepo RoleRepository, sessions SessionStarter, tokens TokenIssuer, ttl time.Duration) *Service {
+	return &Service{audit: audit, users: users, roleRepo: roleRepo, sessions: sessions, tokens: tokens, ttl: ttl}
+}
+
+// Impersonate выдаёт администратору actorID короткоживущий токен пользователя targetID.
+// Нельзя войти от имени пользователя с правами, которых нет у самого администратора.
+func (s *Service) Impersonate(ctx context.Context, actorID int, perms auth.Permissions, input *dto.ImpersonateInput, client dto.ClientInfo) (*dto.ImpersonationOutput, error) {
+	if !perms.Has(auth.PermUserImpersonate) {
+		return nil, custom.ErrForbidden
+	}
+	if _, nested := auth.ActorIDFromContext(ctx); nested {
+		return nil, fmt.Errorf("nested impersonation is not allowed: %w", custom.ErrForbidden)
+	}
+	reason := strings.TrimSpace(input.Reason)
+	if reason == "" {
+		return nil, fmt.Errorf("reason is required: %w", custom.ErrInvalidInput)
+	}
+	if input.UserID == actorID {
+		return nil, fmt.Errorf("cannot impersonate yourself: %w", custom.ErrInvalidInput)
+	}
+
+	tenantID, ok := auth.TenantIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("tenant is not set in context")
+	}
+
+	// Пользователь ищется в каталоге администратора, поэтому войти от имени
+	// пользователя другого арендатора нельзя
+	target, err := s.users.GetByID(ctx, input.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if target.Status != domain.UserStatusActive {
+		return nil, fmt.Errorf("cannot impersonate an inactive user: %w", custom.ErrConflict)
+	}
+	targetPerms, err := s.roleRepo.GetPermissions(ctx, target.Role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role permissions: %w", err)
+	}
+	for _, p := range targetPerms {
+		if !perms.Has(p) {
+			return nil, fmt.Errorf("target has permission %q not held by actor: %w", p, custom.ErrForbidden)
+		}
+	}
+
+	sessionID, err := s.sessions.StartImpersonationSession(ctx, target.ID, actorID, client, s.ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.audit.Create(ctx, &domain.ImpersonationAuditRecord{
+		ActorID:   actorID,
+		UserID:    target.ID,
+		SessionID: sessionID,
+		Action:    domain.ImpersonationActionStart,
+		IP:        client.IP,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.tokens.GenerateImpersonationToken(target.ID, tenantID, target.Role, targetPerms, sessionID, actorID, s.ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate impersonation token: %w", err)
+	}
+	return &dto.ImpersonationOutput{Token: token, ExpiresAt: time.Now().Add(s.ttl)}, nil
+}
+
+func (s *Service) RecordImpersonatedRequest(ctx context.Context, event auth.ImpersonationEvent) (int, error) {
+	return s.audit.Create(ctx, &domain.ImpersonationAuditRecord{
+		ActorID:   event.ActorID,
+		UserID:    event.UserID,
+		SessionID: event.SessionID,
+		Action:    domain.ImpersonationActionRequest,
+		Method:    event.Method,
+		Path:      event.Path,
+		IP:        event.IP,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (s *Service) CompleteImpersonatedRequest(ctx context.Context, recordID, status int) error {
+	return s.audit.SetStatus(ctx, recordID, status)
+}
+
+func (s *Service) ListAudit(ctx context.Context, userID, actorID, limit int) ([]domain.ImpersonationAuditRecord, error) {
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+	records, err := s.audit.List(ctx, userID, actorID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list impersonation audit: %w", err)
+	}
+	return records, nil
+}
@@ -0,0 +1,245 @@
+package invitation
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	"product-catalog/internal/dto"
+	custom "product-catalog/internal/errors"
+	"strings"
+	"time"
+)
+
+const (
+	tokenPrefix = "inv_"
+	tokenLength = 32
+
+	statusPending = "pending"
+	statusExpired = "expired"
+)
+
+type Repository interface {
+	Create(ctx context.Context, inv *domain.Invitation) (int, error)
+	GetByID(ctx context.Context, id int) (*domain.Invitation, error)
+	GetOpenByTokenHash(ctx context.Context, tokenHash string) (*domain.Invitation, error)
+	ListOpen(ctx context.Context) ([]domain.Invitation, error)
+	Reissue(ctx context.Context, id int, tokenHash string, expiresAt time.Time) error
+	Revoke(ctx context.Context, id int) error
+	Accept(ctx context.Context, id int, user *domain.User) error
+}
+
+type UserRepository interface {
+	GetByEmail(ctx context.Context, email string) (*domain.User, error)
+}
+
+type RoleRepository interface {
+	GetPermissions(ctx context.Context, role auth.Role) (auth.Permissions, error)
+}
+
+type Hasher interface {
+	Hash(password string) (string, error)
+}
+
+type PasswordPolicy interface {
+	Validate(password string, related ...string) error
+}
+
+type Service struct {
+	repo           Repository
+	users          UserRepository
+	roleRepo       RoleRepository
+	hasher         Hasher
+	passwordPolicy PasswordPolicy
+	ttl            time.Duration
+}
+
+func NewInvitationService(repo Repository, users UserRepository, roleRepo RoleRepository, hasher Hasher, passwordPolicy PasswordPolicy, ttl time.Duration) *Service {
+	return &Service{repo: repo, users: users, roleRepo: roleRepo, hasher: hasher, passwordPolicy: passwordPolicy, ttl: ttl}
+}
+
+// CreateInvitation выпускает приглашение. Назначить можно только роль,
+// все разрешения которой есть у самого администратора.
+func (s *Service) CreateInvitation(ctx context.Context, actorID int, perms auth.Permissions, input *dto.CreateInvitationInput) (*dto.CreatedInvitationOutput, error) {
+	if !perms.Has(auth.PermUserManage) {
+		return nil, custom.ErrForbidden
+	}
+	email := strings.ToLower(strings.TrimSpace(input.Email))
+	if !strings.Contains(email, "@") || input.Role == "" {
+		return nil, fmt.Errorf("email and role are required: %w", custom.ErrInvalidInput)
+	}
+	if err := s.checkRole(ctx, perms, input.Role); err != nil {
+		return nil, err
+	}
+
+	_, err := s.users.GetByEmail(ctx, email)
+	if err == nil {
+		return nil, fmt.Errorf("user with this email already exists: %w", custom.ErrConflict)
+	}
+	if !errors.Is(err, custom.ErrNotFound) {
+		return nil, fmt.Errorf("failed to check user exists: %w", err)
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	inv := &domain.Invitation{
+		Email:     email,
+		Role:      input.Role,
+		TokenHash: hashToken(token),
+		InvitedBy: actorID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.ttl),
+	}
+	inv.ID, err = s.repo.Create(ctx, inv)
+	if err != nil {
+		if errors.Is(err, custom.ErrConflict) {
+			return nil, fmt.Errorf("an open invitation for this email exists, resend or revoke it: %w", custom.ErrConflict)
+		}
+		return nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	return &dto.CreatedInvitationOutput{InvitationOutput: toOutput(inv), Token: token}, nil
+}
+
+func (s *Service) ListInvitations(ctx context.Context, perms auth.Permissions) ([]dto.InvitationOutput, error) {
+	if !perms.Has(auth.PermUserManage) {
+		return nil, custom.ErrForbidden
+	}
+	invitations, err := s.repo.ListOpen(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+
+	out := make([]dto.InvitationOutput, 0, len(invitations))
+	for i := range invitations {
+		out = append(out, toOutput(&invitations[i]))
+	}
+	return out, nil
+}
+
+// ResendInvitation выпускает новый токен и продлевает срок действия; прежний токен аннулируется.
+func (s *Service) ResendInvitation(ctx context.Context, perms auth.Permissions, id int) (*dto.CreatedInvitationOutput, error) {
+	if !perms.Has(auth.PermUserManage) {
+		return nil, custom.ErrForbidden
+	}
+	inv, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+	if err = s.checkRole(ctx, perms, inv.Role); err != nil {
+		return nil, err
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	inv.TokenHash = hashToken(token)
+	inv.ExpiresAt = time.Now().Add(s.ttl)
+	if err = s.repo.Reissue(ctx, id, inv.TokenHash, inv.ExpiresAt); err != nil {
+		return nil, fmt.Errorf("failed to reissue invitation: %w", err)
+	}
+
+	return &dto.CreatedInvitationOutput{InvitationOutput: toOutput(inv), Token: token}, nil
+}
+
+func (s *Service) RevokeInvitation(ctx context.Context, perms auth.Permissions, id int) error {
+	if !perms.Has(auth.PermUserManage) {
+		return custom.ErrForbidden
+	}
+	if err := s.repo.Revoke(ctx, id); err != nil {
+		return fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+	return nil
+}
+
+// AcceptInvitation создаёт аккаунт с email и ролью из приглашения.
+// Неизвестный, использованный, отозванный и просроченный токены неотличимы: ErrNotFound.
+func (s *Service) AcceptInvitation(ctx context.Context, input *dto.AcceptInvitationInput) (*domain.User, error) {
+	if !strings.HasPrefix(input.Token, tokenPrefix) {
+		return nil, custom.ErrNotFound
+	}
+	inv, err := s.repo.GetOpenByTokenHash(ctx, hashToken(input.Token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+	if time.Now().After(inv.ExpiresAt) {
+		return nil, custom.ErrNotFound
+	}
+
+	username := strings.TrimSpace(input.Username)
+	if username == "" {
+		return nil, fmt.Errorf("username is required: %w", custom.ErrInvalidInput)
+	}
+	if err = s.passwordPolicy.Validate(input.Password, username, inv.Email); err != nil {
+		return nil, fmt.Errorf("%w: %v", custom.ErrInvalidInput, err)
+	}
+
+	hash, err := s.hasher.Hash(input.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	newUser := &domain.User{
+		Username:     username,
+		Email:        inv.Email,
+		PasswordHash: hash,
+		Role:         inv.Role,
+		Status:       domain.UserStatusActive,
+		CreatedAt:    time.Now(),
+	}
+	if err = s.repo.Accept(ctx, inv.ID, newUser); err != nil {
+		return nil, fmt.Errorf("failed to accept invitation: %w", err)
+	}
+	return newUser, nil
+}
+
+func (s *Service) checkRole(ctx context.Context, perms auth.Permissions, role auth.Role) error {
+	rolePerms, err := s.roleRepo.GetPermissions(ctx, role)
+	if err != nil {
+		return fmt.Errorf("failed to get role permissions: %w", err)
+	}
+	for _, p := range rolePerms {
+		if !perms.Has(p) {
+			return fmt.Errorf("role %q has permission %q not held by inviter: %w", role, p, custom.ErrForbidden)
+		}
+	}
+	return nil
+}
+
+func generateToken() (string, error) {
+	buf := make([]byte, tokenLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+	return tokenPrefix + hex.EncodeToString(buf), nil
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func toOutput(inv *domain.Invitation) dto.InvitationOutput {
+	status := statusPending
+	if time.Now().After(inv.ExpiresAt) {
+		status = statusExpired
+	}
+	return dto.InvitationOutput{
+		ID:        inv.ID,
+		Email:     inv.Email,
+		Role:      inv.Role,
+		Status:    status,
+		InvitedBy: inv.InvitedBy,
+		CreatedAt: inv.CreatedAt,
+		ExpiresAt: inv.ExpiresAt,
+	}
+}
@@ -0,0 +1,218 @@
+package invitation_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	"product-catalog/internal/dto"
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/service/invitation"
+)
+
+type fakeRepo struct {
+	invitations map[int]*domain.Invitation
+	users       []domain.User
+}
+
+func (r *fakeRepo) Create(_ context.Context, inv *domain.Invitation) (int, error) {
+	for _, existing := range r.invitations {
+		if existing.Email == inv.Email && existing.AcceptedAt == nil && existing.RevokedAt == nil {
+			return 0, custom.ErrConflict
+		}
+	}
+	cp := *inv
+	cp.ID = len(r.invitations) + 1
+	r.invitations[cp.ID] = &cp
+	return cp.ID, nil
+}
+
+func (r *fakeRepo) GetByID(_ context.Context, id int) (*domain.Invitation, error) {
+	inv, ok := r.invitations[id]
+	if !ok {
+		return nil, custom.ErrNotFound
+	}
+	cp := *inv
+	return &cp, nil
+}
+
+func (r *fakeRepo) GetOpenByTokenHash(_ context.Context, tokenHash string) (*domain.Invitation, error) {
+	for _, inv := range r.invitations {
+		if inv.TokenHash == tokenHash && inv.AcceptedAt == nil && inv.RevokedAt == nil {
+			cp := *inv
+			return &cp, nil
+		}
+	}
+	return nil, custom.ErrNotFound
+}
+
+func (r *fakeRepo) ListOpen(context.Context) ([]domain.Invitation, error) {
+	var res []domain.Invitation
+	for _, inv := range r.invitations {
+		if inv.AcceptedAt == nil && inv.RevokedAt == nil {
+			res = append(res, *inv)
+		}
+	}
+	return res, nil
+}
+
+func (r *fakeRepo) Reissue(_ context.Context, id int, tokenHash string, expiresAt time.Time) error {
+	inv := r.invitations[id]
+	if inv.AcceptedAt != nil || inv.RevokedAt != nil {
+		return custom.ErrNotFound
+	}
+	inv.TokenHash, inv.ExpiresAt = tokenHash, expiresAt
+	return nil
+}
+
+func (r *fakeRepo) Revoke(_ context.Context, id int) error {
+	now := time.Now()
+	r.invitations[id].RevokedAt = &now
+	return nil
+}
+
+func (r *fakeRepo) Accept(_ context.Context, id int, u *domain.User) error {
+	now := time.Now()
+	u.ID = len(r.users) + 1
+	r.users = append(r.users, *u)
+	r.invitations[id].AcceptedAt = &now
+	return nil
+}
+
+type fakeUsers struct {
+	emails map[string]bool
+}
+
+func (u fakeUsers) GetByEmail(_ context.Context, email string) (*domain.User, error) {
+	if u.emails[email] {
+		return &domain.User{Email: email}, nil
+	}
+	return nil, custom.ErrNotFound
+}
+
+type fakeRoles map[auth.Role]auth.Permissions
+
+func (r fakeRoles) GetPermissions(_ context.Context, role auth.Role) (auth.Permissions, error) {
+	return r[role], nil
+}
+
+type fakeHasher struct{}
+
+func (fakeHasher) Hash(password string) (string, error) { return "hash:" + password, nil }
+
+var (
+	managerPerms = auth.Permissions{auth.PermUserManage, auth.PermUserRead}
+	roles        = fakeRoles{
+		auth.RoleUser:  {auth.PermUserRead},
+		auth.RoleAdmin: {auth.PermUserRead, auth.PermUserManage, auth.PermUserImpersonate},
+	}
+)
+
+func newService(t *testing.T, repo *fakeRepo, ttl time.Duration) *invitation.Service {
+	t.Helper()
+	policy, err := auth.NewPasswordPolicy(8, 64, "")
+	if err != nil {
+		t.Fatalf("password policy: %v", err)
+	}
+	users := fakeUsers{emails: map[string]bool{"taken@example.com": true}}
+	return invitation.NewInvitationService(repo, users, roles, fakeHasher{}, policy, ttl)
+}
+
+func TestCreateInvitation(t *testing.T) {
+	tests := []struct {
+		name    string
+		perms   auth.Permissions
+		input   dto.CreateInvitationInput
+		wantErr error
+	}{
+		{name: "manager invites user", perms: managerPerms, input: dto.CreateInvitationInput{Email: " New@Example.com ", Role: auth.RoleUser}},
+		{name: "without permission", perms: auth.Permissions{auth.PermUserRead}, input: dto.CreateInvitationInput{Email: "new@example.com", Role: auth.RoleUser}, wantErr: custom.ErrForbidden},
+		{name: "role with more permissions than inviter", perms: managerPerms, input: dto.CreateInvitationInput{Email: "new@example.com", Role: auth.RoleAdmin}, wantErr: custom.ErrForbidden},
+		{name: "existing user", perms: managerPerms, input: dto.CreateInvitationInput{Email: "taken@example.com", Role: auth.RoleUser}, wantErr: custom.ErrConflict},
+		{name: "missing email", perms: managerPerms, input: dto.CreateInvitationInput{Role: auth.RoleUser}, wantErr: custom.ErrInvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepo{invitations: map[int]*domain.Invitation{}}
+			svc := newService(t, repo, time.Hour)
+
+			out, err := svc.CreateInvitation(context.Background(), 1, tt.perms, &tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if out.Email != "new@example.com" || out.Status != "pending" || !strings.HasPrefix(out.Token, "inv_") {
+				t.Fatalf("unexpected invitation: %+v", out)
+			}
+			if repo.invitations[out.ID].TokenHash == out.Token {
+				t.Fatal("token stored in plaintext")
+			}
+
+			if _, err = svc.CreateInvitation(context.Background(), 1, tt.perms, &tt.input); !errors.Is(err, custom.ErrConflict) {
+				t.Fatalf("second invitation err = %v, want ErrConflict", err)
+			}
+		})
+	}
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	repo := &fakeRepo{invitations: map[int]*domain.Invitation{}}
+	svc := newService(t, repo, time.Hour)
+
+	created, err := svc.CreateInvitation(context.Background(), 1, managerPerms, &dto.CreateInvitationInput{Email: "new@example.com", Role: auth.RoleUser})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	_, err = svc.AcceptInvitation(context.Background(), &dto.AcceptInvitationInput{Token: created.Token, Username: "newbie", Password: "short"})
+	if !errors.Is(err, custom.ErrInvalidInput) {
+		t.Fatalf("weak password err = %v, want ErrInvalidInput", err)
+	}
+
+	u, err := svc.AcceptInvitation(context.Background(), &dto.AcceptInvitationInput{Token: created.Token, Username: "newbie", Password: "long enough pass"})
+	if err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	if u.Email != "new@example.com" || u.Role != auth.RoleUser || u.PasswordHash != "hash:long enough pass" {
+		t.Fatalf("unexpected user: %+v", u)
+	}
+
+	_, err = svc.AcceptInvitation(context.Background(), &dto.AcceptInvitationInput{Token: created.Token, Username: "again", Password: "long enough pass"})
+	if !errors.Is(err, custom.ErrNotFound) {
+		t.Fatalf("reused token err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestResendInvalidatesOldToken(t *testing.T) {
+	repo := &fakeRepo{invitations: map[int]*domain.Invitation{}}
+	svc := newService(t, repo, -time.Minute)
+
+	created, err := svc.CreateInvitation(context.Background(), 1, managerPerms, &dto.CreateInvitationInput{Email: "new@example.com", Role: auth.RoleUser})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	input := &dto.AcceptInvitationInput{Token: created.Token, Username: "newbie", Password: "long enough pass"}
+	if _, err = svc.AcceptInvitation(context.Background(), input); !errors.Is(err, custom.ErrNotFound) {
+		t.Fatalf("expired token err = %v, want ErrNotFound", err)
+	}
+
+	svc = newService(t, repo, time.Hour)
+	resent, err := svc.ResendInvitation(context.Background(), managerPerms, created.ID)
+	if err != nil {
+		t.Fatalf("resend: %v", err)
+	}
+	if _, err = svc.AcceptInvitation(context.Background(), input); !errors.Is(err, custom.ErrNotFound) {
+		t.Fatalf("old token err = %v, want ErrNotFound", err)
+	}
+	input.Token = resent.Token
+	if _, err = svc.AcceptInvitation(context.Background(), input); err != nil {
+		t.Fatalf("accept with new token: %v", err)
+	}
+}
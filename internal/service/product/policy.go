@@ -0,0 +1,54 @@
+package product
+
+import (
+	"fmt"
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+)
+
+// Политика записи: product:manage даёт доступ к любым товарам (админы),
+// product:write — только к своим (продавцы), без них товары доступны только на чтение.
+
+func canCreate(perms auth.Permissions) error {
+	if perms.Has(auth.PermProductWrite) || perms.Has(auth.PermProductManage) {
+		return nil
+	}
+	return custom.ErrForbidden
+}
+
+func canModify(requesterID int, perms auth.Permissions, product *domain.Product) error {
+	if perms.Has(auth.PermProductManage) {
+		return nil
+	}
+	if perms.Has(auth.PermProductWrite) && product.CreatedBy == requesterID {
+		return nil
+	}
+	return custom.ErrForbidden
+}
+
+// canEdit — содержимое опубликованного товара прошло проверку, поэтому менять его
+// может только product:manage; владелец сначала снимает товар с публикации
+func canEdit(requesterID int, perms auth.Permissions, product *domain.Product) error {
+	if err := canModify(requesterID, perms, product); err != nil {
+		return err
+	}
+	if product.Status == domain.ProductStatusPublished && !perms.Has(auth.PermProductManage) {
+		return fmt.Errorf("%w: published product can be edited only with %s", custom.ErrForbidden, auth.PermProductManage)
+	}
+	return nil
+}
+
+// canManage — действия над чужими товарами в целом (корзина) доступны только с product:manage
+func canManage(perms auth.Permissions) error {
+	if perms.Has(auth.PermProductManage) {
+		return nil
+	}
+	return custom.ErrForbidden
+}
+
+// canViewUnpublished — черновики, товары на проверке и архив видят редакторы каталога:
+// все, кто может создавать или изменять товары
+func canViewUnpublished(perms auth.Permissions) bool {
+	return canCreate(perms) == nil
+}
@@ -2,25 +2,60 @@ package product
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"product-catalog/internal/auth"
 	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/service/file"
+	"slices"
+	"time"
 )
 
 type Repository interface {
 	Create(ctx context.Context, product *domain.Product) (int, error)
 	GetByID(ctx context.Context, id int) (*domain.Product, error)
-	GetAll(ctx context.Context) ([]domain.Product, error)
+	// GetAll возвращает товары в одном из статусов statuses, пустой — в любом
+	GetAll(ctx context.Context, statuses []domain.ProductStatus) ([]domain.Product, error)
+	Revision(ctx context.Context) (domain.CatalogRevision, error)
+	// UpdateByID, Patch и DeleteByID срабатывают, только если версия товара в БД
+	// совпадает с ожидаемой, иначе возвращают ErrPreconditionFailed
 	UpdateByID(ctx context.Context, id int, product *domain.Product) error
-	DeleteByID(ctx context.Context, id int) error
+	Patch(ctx context.Context, id, version int, patch domain.ProductPatch) (*domain.Product, error)
+	// DeleteByID переносит товар в корзину; GetByID, GetAll и изменения его больше не видят
+	DeleteByID(ctx context.Context, id, version int) error
+	ListDeleted(ctx context.Context, olderThan time.Duration) ([]domain.Product, error)
+	Restore(ctx context.Context, id int) (*domain.Product, error)
+	Purge(ctx context.Context, id int) error
+	ImageInUse(ctx context.Context, key string, exceptID int) (bool, error)
+	UpdateStatus(ctx context.Context, id, version int, status domain.ProductStatus) (*domain.Product, error)
+	UpdateSchedule(ctx context.Context, id, version int, schedule domain.ProductSchedule) (*domain.Product, error)
+	PublishDue(ctx context.Context) ([]int, error)
+	UnpublishDue(ctx context.Context) ([]int, error)
+}
+
+type ImageStorage interface {
+	Delete(ctx context.Context, key string) error
 }
 
 type Service struct {
-	repo Repository
+	repo           Repository
+	images         ImageStorage
+	trashRetention time.Duration
+}
+
+func NewProductService(repo Repository, images ImageStorage, trashRetention time.Duration) *Service {
+	return &Service{repo: repo, images: images, trashRetention: trashRetention}
 }
 
-func NewProductService(repo Repository) *Service { return &Service{repo: repo} }
+func (s *Service) CreateProduct(ctx context.Context, requesterID int, product *domain.Product, perms auth.Permissions) (int, error) {
+	if err := canCreate(perms); err != nil {
+		return 0, err
+	}
 
-func (s *Service) CreateProduct(ctx context.Context, product *domain.Product) (int, error) {
+	// Новый товар попадает в каталог только после публикации
+	product.CreatedBy = requesterID
+	product.Status = domain.ProductStatusDraft
 	id, err := s.repo.Create(ctx, product)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create product: %w", err)
@@ -28,30 +63,257 @@ func (s *Service) CreateProduct(ctx context.Context, product *domain.Product) (i
 	return id, nil
 }
 
-func (s *Service) GetProductByID(ctx context.Context, id int) (*domain.Product, error) {
+// GetProductByID отдаёт неопубликованный товар только редакторам каталога;
+// остальным он не виден, как и отсутствующий
+func (s *Service) GetProductByID(ctx context.Context, id int, perms auth.Permissions) (*domain.Product, error) {
 	product, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get product by id: %w", err)
 	}
+	if product.Status != domain.ProductStatusPublished && !canViewUnpublished(perms) {
+		return nil, fmt.Errorf("failed to get product by id: %w", custom.ErrNotFound)
+	}
 	return product, nil
 }
 
-func (s *Service) GetAllProducts(ctx context.Context) ([]domain.Product, error) {
-	product, err := s.repo.GetAll(ctx)
+// GetAllProducts отдаёт редакторам товары во всех статусах, остальным — только опубликованные
+func (s *Service) GetAllProducts(ctx context.Context, perms auth.Permissions) ([]domain.Product, error) {
+	var statuses []domain.ProductStatus
+	if !canViewUnpublished(perms) {
+		statuses = []domain.ProductStatus{domain.ProductStatusPublished}
+	}
+	product, err := s.repo.GetAll(ctx, statuses)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all products: %w", err)
 	}
 	return product, nil
 }
 
-func (s *Service) UpdateProductByID(ctx context.Context, id int, product *domain.Product) error {
-	err := s.repo.UpdateByID(ctx, id, product)
+// GetCatalogRevision возвращает ревизию каталога: по ней список товаров
+// проверяется на изменения без чтения самих товаров
+func (s *Service) GetCatalogRevision(ctx context.Context) (domain.CatalogRevision, error) {
+	rev, err := s.repo.Revision(ctx)
+	if err != nil {
+		return domain.CatalogRevision{}, fmt.Errorf("failed to get catalog revision: %w", err)
+	}
+	return rev, nil
+}
+
+// UpdateProductByID заменяет товар версии product.Version; после успеха
+// product.Version содержит новую версию
+func (s *Service) UpdateProductByID(ctx context.Context, requesterID, id int, product *domain.Product, perms auth.Permissions) error {
+	existing, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get product: %w", err)
+	}
+	if err = canEdit(requesterID, perms, existing); err != nil {
+		return err
+	}
+	if existing.Version != product.Version {
+		return custom.ErrPreconditionFailed
+	}
+	// Без новой картинки PUT сохраняет текущую
+	if product.ImageURL == "" {
+		product.ImageURL = existing.ImageURL
+	}
+
+	err = s.repo.UpdateByID(ctx, id, product)
 	if err != nil {
 		return fmt.Errorf("failed to update product: %w", err)
 	}
 	return nil
 }
 
-func (s *Service) DeleteProductByID(ctx context.Context, id int) error {
-	return s.repo.DeleteByID(ctx, id)
+// PatchProductByID частично изменяет товар. apply получает текущий товар уже после
+// проверки прав и возвращает изменения; в БД пишутся только они.
+func (s *Service) PatchProductByID(ctx context.Context, requesterID, id, version int, perms auth.Permissions,
+	apply func(current *domain.Product) (domain.ProductPatch, error)) (*domain.Product, error) {
+	existing, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	if err = canEdit(requesterID, perms, existing); err != nil {
+		return nil, err
+	}
+	if existing.Version != version {
+		return nil, custom.ErrPreconditionFailed
+	}
+
+	patch, err := apply(existing)
+	if err != nil {
+		return nil, err
+	}
+	if patch.IsEmpty() {
+		return existing, nil
+	}
+	updated, err := s.repo.Patch(ctx, id, version, patch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to patch product: %w", err)
+	}
+	return updated, nil
+}
+
+func (s *Service) DeleteProductByID(ctx context.Context, requesterID, id, version int, perms auth.Permissions) error {
+	existing, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get product: %w", err)
+	}
+	if err = canModify(requesterID, perms, existing); err != nil {
+		return err
+	}
+	if existing.Version != version {
+		return custom.ErrPreconditionFailed
+	}
+
+	return s.repo.DeleteByID(ctx, id, version)
+}
+
+// transitions — допустимые ручные переходы между статусами товара
+var transitions = map[domain.ProductStatus][]domain.ProductStatus{
+	domain.ProductStatusDraft:     {domain.ProductStatusInReview, domain.ProductStatusPublished, domain.ProductStatusArchived},
+	domain.ProductStatusInReview:  {domain.ProductStatusDraft, domain.ProductStatusPublished, domain.ProductStatusArchived},
+	domain.ProductStatusPublished: {domain.ProductStatusArchived},
+	domain.ProductStatusArchived:  {domain.ProductStatusDraft},
+}
+
+// ChangeStatus переводит товар версии version в статус status. Отправить товар
+// на проверку, вернуть в черновики или снять с публикации может владелец,
+// опубликовать — только редактор с product:manage.
+func (s *Service) ChangeStatus(ctx context.Context, requesterID, id, version int, status domain.ProductStatus, perms auth.Permissions) (*domain.Product, error) {
+	if _, known := transitions[status]; !known {
+		return nil, fmt.Errorf("%w: unknown product status %q", custom.ErrInvalidInput, status)
+	}
+	existing, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	if err = canModify(requesterID, perms, existing); err != nil {
+		return nil, err
+	}
+	if status == domain.ProductStatusPublished {
+		if err = canManage(perms); err != nil {
+			return nil, err
+		}
+	}
+	if existing.Version != version {
+		return nil, custom.ErrPreconditionFailed
+	}
+	if !slices.Contains(transitions[existing.Status], status) {
+		return nil, fmt.Errorf("%w: product cannot move from %s to %s", custom.ErrConflict, existing.Status, status)
+	}
+
+	product, err := s.repo.UpdateStatus(ctx, id, version, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to change product status: %w", err)
+	}
+	return product, nil
+}
+
+// ScheduleProduct задаёт время автоматической публикации и снятия с публикации.
+// Запланированная публикация равносильна одобрению, поэтому доступна только с
+// product:manage; опубликовать по расписанию можно только черновик или товар на проверке.
+func (s *Service) ScheduleProduct(ctx context.Context, id, version int, schedule domain.ProductSchedule, perms auth.Permissions) (*domain.Product, error) {
+	if err := canManage(perms); err != nil {
+		return nil, err
+	}
+	if schedule.PublishAt != nil && schedule.UnpublishAt != nil && !schedule.UnpublishAt.After(*schedule.PublishAt) {
+		return nil, fmt.Errorf("%w: unpublish_at must be after publish_at", custom.ErrInvalidInput)
+	}
+	existing, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	if existing.Version != version {
+		return nil, custom.ErrPreconditionFailed
+	}
+	switch {
+	case schedule.PublishAt != nil && existing.Status != domain.ProductStatusDraft && existing.Status != domain.ProductStatusInReview:
+		return nil, fmt.Errorf("%w: only draft or in_review products can be scheduled for publishing", custom.ErrConflict)
+	case schedule.UnpublishAt != nil && existing.Status == domain.ProductStatusArchived:
+		return nil, fmt.Errorf("%w: archived product cannot be scheduled for unpublishing", custom.ErrConflict)
+	}
+
+	product, err := s.repo.UpdateSchedule(ctx, id, version, schedule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule product: %w", err)
+	}
+	return product, nil
+}
+
+// ApplySchedule публикует и снимает с публикации товары, чьё время наступило.
+// Публикация идёт первой: товар, у которого прошли оба срока, окажется в архиве.
+func (s *Service) ApplySchedule(ctx context.Context) (published, unpublished int, err error) {
+	ids, err := s.repo.PublishDue(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	published = len(ids)
+	if ids, err = s.repo.UnpublishDue(ctx); err != nil {
+		return published, 0, err
+	}
+	return published, len(ids), nil
+}
+
+// ListTrash возвращает удалённые товары, которые ещё можно восстановить
+func (s *Service) ListTrash(ctx context.Context, perms auth.Permissions) ([]domain.Product, error) {
+	if err := canManage(perms); err != nil {
+		return nil, err
+	}
+	products, err := s.repo.ListDeleted(ctx, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", err)
+	}
+	return products, nil
+}
+
+func (s *Service) RestoreProduct(ctx context.Context, id int, perms auth.Permissions) (*domain.Product, error) {
+	if err := canManage(perms); err != nil {
+		return nil, err
+	}
+	product, err := s.repo.Restore(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore product: %w", err)
+	}
+	return product, nil
+}
+
+// PurgeTrash окончательно удаляет товары, пролежавшие в корзине дольше срока
+// хранения, вместе с их картинками. Товар с неудалённой картинкой остаётся в
+// корзине до следующего прогона.
+func (s *Service) PurgeTrash(ctx context.Context) (int, error) {
+	products, err := s.repo.ListDeleted(ctx, s.trashRetention)
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	purged := 0
+	for _, p := range products {
+		if err = s.deleteImage(ctx, p); err != nil {
+			errs = append(errs, fmt.Errorf("product %d: %w", p.ID, err))
+			continue
+		}
+		if err = s.repo.Purge(ctx, p.ID); err != nil {
+			errs = append(errs, fmt.Errorf("product %d: %w", p.ID, err))
+			continue
+		}
+		purged++
+	}
+	return purged, errors.Join(errs...)
+}
+
+// deleteImage удаляет картинку товара, если на неё не ссылаются другие товары
+func (s *Service) deleteImage(ctx context.Context, p domain.Product) error {
+	key, ok := file.KeyFromURL(p.ImageURL)
+	if !ok {
+		return nil
+	}
+	inUse, err := s.repo.ImageInUse(ctx, key, p.ID)
+	if err != nil || inUse {
+		return err
+	}
+	if err = s.images.Delete(ctx, key); err != nil {
+		return fmt.Errorf("failed to delete image: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,589 @@
+package product_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/service/product"
+)
+
+type fakeRepo struct {
+	products map[int]*domain.Product
+	nextID   int
+	patches  int
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{products: map[int]*domain.Product{}, nextID: 1}
+}
+
+func (r *fakeRepo) Create(_ context.Context, p *domain.Product) (int, error) {
+	id := r.nextID
+	r.nextID++
+	cp := *p
+	cp.ID = id
+	cp.Version = 1
+	r.products[id] = &cp
+	return id, nil
+}
+
+func (r *fakeRepo) GetByID(_ context.Context, id int) (*domain.Product, error) {
+	p, ok := r.products[id]
+	if !ok || p.DeletedAt != nil {
+		return nil, custom.ErrNotFound
+	}
+	cp := *p
+	return &cp, nil
+}
+
+func (r *fakeRepo) GetAll(_ context.Context, statuses []domain.ProductStatus) ([]domain.Product, error) {
+	var res []domain.Product
+	for _, p := range r.products {
+		if p.DeletedAt == nil && (len(statuses) == 0 || slices.Contains(statuses, p.Status)) {
+			res = append(res, *p)
+		}
+	}
+	return res, nil
+}
+
+func (r *fakeRepo) Revision(context.Context) (domain.CatalogRevision, error) {
+	return domain.CatalogRevision{}, nil
+}
+
+func (r *fakeRepo) UpdateByID(_ context.Context, id int, p *domain.Product) error {
+	cur := r.products[id]
+	if cur.Version != p.Version {
+		return custom.ErrPreconditionFailed
+	}
+	cur.Title, cur.Price, cur.Description, cur.Available, cur.ImageURL = p.Title, p.Price, p.Description, p.Available, p.ImageURL
+	cur.Version++
+	p.Version = cur.Version
+	return nil
+}
+
+func (r *fakeRepo) Patch(_ context.Context, id, version int, patch domain.ProductPatch) (*domain.Product, error) {
+	r.patches++
+	p, ok := r.products[id]
+	if !ok {
+		return nil, custom.ErrNotFound
+	}
+	if p.Version != version {
+		return nil, custom.ErrPreconditionFailed
+	}
+	p.Version++
+	if patch.Title != nil {
+		p.Title = *patch.Title
+	}
+	if patch.Price != nil {
+		p.Price = *patch.Price
+	}
+	if patch.Description != nil {
+		p.Description = *patch.Description
+	}
+	if patch.Available != nil {
+		p.Available = *patch.Available
+	}
+	if patch.ImageURL != nil {
+		p.ImageURL = *patch.ImageURL
+	}
+	cp := *p
+	return &cp, nil
+}
+
+func (r *fakeRepo) DeleteByID(_ context.Context, id, version int) error {
+	p, ok := r.products[id]
+	if !ok || p.DeletedAt != nil {
+		return custom.ErrNotFound
+	}
+	if p.Version != version {
+		return custom.ErrPreconditionFailed
+	}
+	now := time.Now()
+	p.DeletedAt = &now
+	p.Version++
+	return nil
+}
+
+func (r *fakeRepo) ListDeleted(_ context.Context, olderThan time.Duration) ([]domain.Product, error) {
+	var res []domain.Product
+	for _, p := range r.products {
+		if p.DeletedAt != nil && time.Since(*p.DeletedAt) >= olderThan {
+			res = append(res, *p)
+		}
+	}
+	return res, nil
+}
+
+func (r *fakeRepo) Restore(_ context.Context, id int) (*domain.Product, error) {
+	p, ok := r.products[id]
+	if !ok || p.DeletedAt == nil {
+		return nil, custom.ErrNotFound
+	}
+	p.DeletedAt = nil
+	p.Version++
+	cp := *p
+	return &cp, nil
+}
+
+func (r *fakeRepo) Purge(_ context.Context, id int) error {
+	if p, ok := r.products[id]; !ok || p.DeletedAt == nil {
+		return custom.ErrNotFound
+	}
+	delete(r.products, id)
+	return nil
+}
+
+func (r *fakeRepo) ImageInUse(_ context.Context, key string, exceptID int) (bool, error) {
+	for id, p := range r.products {
+		if id != exceptID && strings.Contains(p.ImageURL, key) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRepo) UpdateStatus(_ context.Context, id, version int, status domain.ProductStatus) (*domain.Product, error) {
+	p, ok := r.products[id]
+	if !ok || p.DeletedAt != nil {
+		return nil, custom.ErrNotFound
+	}
+	if p.Version != version {
+		return nil, custom.ErrPreconditionFailed
+	}
+	p.Status = status
+	if status == domain.ProductStatusPublished || status == domain.ProductStatusArchived {
+		p.PublishAt = nil
+	}
+	if status == domain.ProductStatusArchived {
+		p.UnpublishAt = nil
+	}
+	p.Version++
+	cp := *p
+	return &cp, nil
+}
+
+func (r *fakeRepo) UpdateSchedule(_ context.Context, id, version int, schedule domain.ProductSchedule) (*domain.Product, error) {
+	p, ok := r.products[id]
+	if !ok || p.DeletedAt != nil {
+		return nil, custom.ErrNotFound
+	}
+	if p.Version != version {
+		return nil, custom.ErrPreconditionFailed
+	}
+	p.PublishAt, p.UnpublishAt = schedule.PublishAt, schedule.UnpublishAt
+	p.Version++
+	cp := *p
+	return &cp, nil
+}
+
+func (r *fakeRepo) PublishDue(context.Context) ([]int, error) {
+	var ids []int
+	for id, p := range r.products {
+		if (p.Status == domain.ProductStatusDraft || p.Status == domain.ProductStatusInReview) && p.PublishAt != nil && !p.PublishAt.After(time.Now()) {
+			p.Status, p.PublishAt = domain.ProductStatusPublished, nil
+			p.Version++
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (r *fakeRepo) UnpublishDue(context.Context) ([]int, error) {
+	var ids []int
+	for id, p := range r.products {
+		if p.Status == domain.ProductStatusPublished && p.UnpublishAt != nil && !p.UnpublishAt.After(time.Now()) {
+			p.Status, p.UnpublishAt = domain.ProductStatusArchived, nil
+			p.Version++
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+type fakeImages struct {
+	deleted []string
+}
+
+func (s *fakeImages) Delete(_ context.Context, key string) error {
+	s.deleted = append(s.deleted, key)
+	return nil
+}
+
+func TestProductWritePolicy(t *testing.T) {
+	ctx := context.Background()
+	admin := auth.Permissions{auth.PermProductWrite, auth.PermProductManage}
+	seller := auth.Permissions{auth.PermProductWrite}
+	regular := auth.Permissions{}
+
+	const sellerID, otherSellerID, adminID, userID = 1, 2, 3, 4
+
+	svc := product.NewProductService(newFakeRepo(), &fakeImages{}, time.Hour)
+
+	if _, err := svc.CreateProduct(ctx, userID, &domain.Product{Title: "nope"}, regular); !errors.Is(err, custom.ErrForbidden) {
+		t.Fatalf("regular user create: got %v, want ErrForbidden", err)
+	}
+
+	id, err := svc.CreateProduct(ctx, sellerID, &domain.Product{Title: "mine"}, seller)
+	if err != nil {
+		t.Fatalf("seller create: %v", err)
+	}
+	created, _ := svc.GetProductByID(ctx, id, seller)
+	if created.CreatedBy != sellerID {
+		t.Fatalf("created_by = %d, want %d", created.CreatedBy, sellerID)
+	}
+
+	tests := []struct {
+		name        string
+		requesterID int
+		perms       auth.Permissions
+		wantErr     error
+	}{
+		{"regular user is read-only", userID, regular, custom.ErrForbidden},
+		{"other seller cannot edit", otherSellerID, seller, custom.ErrForbidden},
+		{"owner can edit", sellerID, seller, nil},
+		{"admin can edit anything", adminID, admin, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cur, _ := svc.GetProductByID(ctx, id, seller)
+			err := svc.UpdateProductByID(ctx, tt.requesterID, id, &domain.Product{Title: "changed", Version: cur.Version}, tt.perms)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("update: got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	cur, _ := svc.GetProductByID(ctx, id, seller)
+	if err = svc.DeleteProductByID(ctx, otherSellerID, id, cur.Version, seller); !errors.Is(err, custom.ErrForbidden) {
+		t.Errorf("other seller delete: got %v, want ErrForbidden", err)
+	}
+	if err = svc.DeleteProductByID(ctx, adminID, 999, 1, admin); !errors.Is(err, custom.ErrNotFound) {
+		t.Errorf("delete missing: got %v, want ErrNotFound", err)
+	}
+	if err = svc.DeleteProductByID(ctx, sellerID, id, cur.Version, seller); err != nil {
+		t.Errorf("owner delete: %v", err)
+	}
+}
+
+func TestUpdateKeepsImage(t *testing.T) {
+	ctx := context.Background()
+	seller := auth.Permissions{auth.PermProductWrite}
+	svc := product.NewProductService(newFakeRepo(), &fakeImages{}, time.Hour)
+
+	id, _ := svc.CreateProduct(ctx, 1, &domain.Product{Title: "lamp", ImageURL: "http://img/old.png"}, seller)
+
+	if err := svc.UpdateProductByID(ctx, 1, id, &domain.Product{Title: "lamp", Available: true, Version: 1}, seller); err != nil {
+		t.Fatalf("update without image: %v", err)
+	}
+	got, _ := svc.GetProductByID(ctx, id, seller)
+	if got.ImageURL != "http://img/old.png" || !got.Available {
+		t.Fatalf("after update without image: %+v", got)
+	}
+
+	if err := svc.UpdateProductByID(ctx, 1, id, &domain.Product{Title: "lamp", ImageURL: "http://img/new.png", Version: 2}, seller); err != nil {
+		t.Fatalf("update with image: %v", err)
+	}
+	if got, _ = svc.GetProductByID(ctx, id, seller); got.ImageURL != "http://img/new.png" {
+		t.Fatalf("image_url = %q, want the new image", got.ImageURL)
+	}
+}
+
+func TestPatchProduct(t *testing.T) {
+	ctx := context.Background()
+	seller := auth.Permissions{auth.PermProductWrite}
+	repo := newFakeRepo()
+	svc := product.NewProductService(repo, &fakeImages{}, time.Hour)
+
+	id, _ := svc.CreateProduct(ctx, 1, &domain.Product{Title: "lamp", Price: 10, Available: true}, seller)
+
+	called := false
+	_, err := svc.PatchProductByID(ctx, 2, id, 1, seller, func(*domain.Product) (domain.ProductPatch, error) {
+		called = true
+		return domain.ProductPatch{}, nil
+	})
+	if !errors.Is(err, custom.ErrForbidden) || called {
+		t.Fatalf("other seller: err = %v, apply called = %v; want ErrForbidden before apply", err, called)
+	}
+
+	_, err = svc.PatchProductByID(ctx, 1, id, 1, seller, func(*domain.Product) (domain.ProductPatch, error) {
+		return domain.ProductPatch{}, custom.ErrConflict
+	})
+	if !errors.Is(err, custom.ErrConflict) || repo.patches != 0 {
+		t.Fatalf("failed apply: err = %v, patches = %d", err, repo.patches)
+	}
+
+	if _, err = svc.PatchProductByID(ctx, 1, id, 1, seller, func(*domain.Product) (domain.ProductPatch, error) {
+		return domain.ProductPatch{}, nil
+	}); err != nil || repo.patches != 0 {
+		t.Fatalf("empty patch: err = %v, patches = %d; want no write", err, repo.patches)
+	}
+
+	price := 12
+	got, err := svc.PatchProductByID(ctx, 1, id, 1, seller, func(cur *domain.Product) (domain.ProductPatch, error) {
+		if cur.Price != 10 {
+			t.Errorf("apply got price %d, want current 10", cur.Price)
+		}
+		return domain.ProductPatch{Price: &price}, nil
+	})
+	if err != nil {
+		t.Fatalf("patch price: %v", err)
+	}
+	if got.Price != 12 || got.Title != "lamp" || !got.Available {
+		t.Fatalf("after patch: %+v; only price must change", got)
+	}
+}
+
+func TestProductVersionGuard(t *testing.T) {
+	ctx := context.Background()
+	seller := auth.Permissions{auth.PermProductWrite}
+	svc := product.NewProductService(newFakeRepo(), &fakeImages{}, time.Hour)
+	id, _ := svc.CreateProduct(ctx, 1, &domain.Product{Title: "lamp", Price: 10}, seller)
+
+	first := &domain.Product{Title: "first", Price: 10, Version: 1}
+	if err := svc.UpdateProductByID(ctx, 1, id, first, seller); err != nil {
+		t.Fatalf("first update: %v", err)
+	}
+	if first.Version != 2 {
+		t.Fatalf("version after update = %d, want 2", first.Version)
+	}
+
+	// Второй редактор читал товар до первого изменения
+	if err := svc.UpdateProductByID(ctx, 1, id, &domain.Product{Title: "second", Price: 10, Version: 1}, seller); !errors.Is(err, custom.ErrPreconditionFailed) {
+		t.Fatalf("stale update: err = %v, want ErrPreconditionFailed", err)
+	}
+	noop := func(*domain.Product) (domain.ProductPatch, error) { return domain.ProductPatch{}, nil }
+	if _, err := svc.PatchProductByID(ctx, 1, id, 1, seller, noop); !errors.Is(err, custom.ErrPreconditionFailed) {
+		t.Fatalf("stale patch: err = %v, want ErrPreconditionFailed", err)
+	}
+	if err := svc.DeleteProductByID(ctx, 1, id, 1, seller); !errors.Is(err, custom.ErrPreconditionFailed) {
+		t.Fatalf("stale delete: err = %v, want ErrPreconditionFailed", err)
+	}
+	if got, _ := svc.GetProductByID(ctx, id, seller); got.Title != "first" {
+		t.Fatalf("title = %q, stale writes must not apply", got.Title)
+	}
+}
+
+func TestTrash(t *testing.T) {
+	ctx := context.Background()
+	admin := auth.Permissions{auth.PermProductWrite, auth.PermProductManage}
+	seller := auth.Permissions{auth.PermProductWrite}
+	repo := newFakeRepo()
+	images := &fakeImages{}
+	// Нулевой срок хранения: всё из корзины удаляется при первом прогоне
+	svc := product.NewProductService(repo, images, 0)
+
+	const imageURL = "http://minio:9000/uploads/tenants/1/own.png?X-Amz-Signature=a"
+	const sharedURL = "http://minio:9000/uploads/tenants/1/shared.png?X-Amz-Signature=b"
+	id, _ := svc.CreateProduct(ctx, 1, &domain.Product{Title: "lamp", ImageURL: imageURL}, seller)
+	sharedID, _ := svc.CreateProduct(ctx, 1, &domain.Product{Title: "desk", ImageURL: sharedURL}, seller)
+	// Тот же ключ картинки передан в image_key ещё одного товара
+	_, _ = svc.CreateProduct(ctx, 1, &domain.Product{Title: "chair", ImageURL: sharedURL + "&other"}, seller)
+
+	if err := svc.DeleteProductByID(ctx, 1, id, 1, seller); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := svc.GetProductByID(ctx, id, seller); !errors.Is(err, custom.ErrNotFound) {
+		t.Fatalf("get deleted product err = %v, want ErrNotFound", err)
+	}
+	if err := svc.DeleteProductByID(ctx, 1, id, 2, seller); !errors.Is(err, custom.ErrNotFound) {
+		t.Fatalf("delete twice err = %v, want ErrNotFound", err)
+	}
+
+	if _, err := svc.ListTrash(ctx, seller); !errors.Is(err, custom.ErrForbidden) {
+		t.Errorf("seller lists trash: err = %v, want ErrForbidden", err)
+	}
+	if _, err := svc.RestoreProduct(ctx, id, seller); !errors.Is(err, custom.ErrForbidden) {
+		t.Errorf("seller restores: err = %v, want ErrForbidden", err)
+	}
+	trash, err := svc.ListTrash(ctx, admin)
+	if err != nil || len(trash) != 1 || trash[0].ID != id {
+		t.Fatalf("trash = %+v, %v; want the deleted product", trash, err)
+	}
+
+	restored, err := svc.RestoreProduct(ctx, id, admin)
+	if err != nil || restored.DeletedAt != nil {
+		t.Fatalf("restore = %+v, %v", restored, err)
+	}
+	if _, err = svc.GetProductByID(ctx, id, seller); err != nil {
+		t.Fatalf("get restored product: %v", err)
+	}
+	if _, err = svc.RestoreProduct(ctx, id, admin); !errors.Is(err, custom.ErrNotFound) {
+		t.Errorf("restore product not in trash: err = %v, want ErrNotFound", err)
+	}
+
+	_ = svc.DeleteProductByID(ctx, 1, id, restored.Version, seller)
+	_ = svc.DeleteProductByID(ctx, 1, sharedID, 1, seller)
+	n, err := svc.PurgeTrash(ctx)
+	if err != nil || n != 2 {
+		t.Fatalf("purged = %d, %v; want 2", n, err)
+	}
+	if !slices.Equal(images.deleted, []string{"tenants/1/own.png"}) {
+		t.Errorf("deleted images = %v, want only the image no other product uses", images.deleted)
+	}
+	if trash, _ = svc.ListTrash(ctx, admin); len(trash) != 0 {
+		t.Errorf("trash after purge = %+v, want empty", trash)
+	}
+}
+
+func TestProductLifecycle(t *testing.T) {
+	ctx := context.Background()
+	admin := auth.Permissions{auth.PermProductWrite, auth.PermProductManage}
+	seller := auth.Permissions{auth.PermProductWrite}
+	var anonymous auth.Permissions
+	const sellerID, otherSellerID, adminID = 1, 2, 3
+
+	svc := product.NewProductService(newFakeRepo(), &fakeImages{}, time.Hour)
+	id, _ := svc.CreateProduct(ctx, sellerID, &domain.Product{Title: "lamp"}, seller)
+
+	// Новый товар — черновик, в публичном каталоге его нет
+	if _, err := svc.GetProductByID(ctx, id, anonymous); !errors.Is(err, custom.ErrNotFound) {
+		t.Fatalf("anonymous get draft: err = %v, want ErrNotFound", err)
+	}
+	if list, _ := svc.GetAllProducts(ctx, anonymous); len(list) != 0 {
+		t.Fatalf("public list = %+v, want no drafts", list)
+	}
+	if p, err := svc.GetProductByID(ctx, id, seller); err != nil || p.Status != domain.ProductStatusDraft {
+		t.Fatalf("editor get = %+v, %v; want draft", p, err)
+	}
+
+	steps := []struct {
+		name        string
+		requesterID int
+		perms       auth.Permissions
+		status      domain.ProductStatus
+		wantErr     error
+	}{
+		{"unknown status", sellerID, seller, "deleted", custom.ErrInvalidInput},
+		{"other seller cannot submit", otherSellerID, seller, domain.ProductStatusInReview, custom.ErrForbidden},
+		{"owner submits for review", sellerID, seller, domain.ProductStatusInReview, nil},
+		{"owner cannot publish", sellerID, seller, domain.ProductStatusPublished, custom.ErrForbidden},
+		{"admin publishes", adminID, admin, domain.ProductStatusPublished, nil},
+		{"published cannot go back to review", adminID, admin, domain.ProductStatusInReview, custom.ErrConflict},
+		{"owner archives", sellerID, seller, domain.ProductStatusArchived, nil},
+		{"archived cannot be published directly", adminID, admin, domain.ProductStatusPublished, custom.ErrConflict},
+		{"owner returns to drafts", sellerID, seller, domain.ProductStatusDraft, nil},
+	}
+	for _, st := range steps {
+		cur, _ := svc.GetProductByID(ctx, id, admin)
+		p, err := svc.ChangeStatus(ctx, st.requesterID, id, cur.Version, st.status, st.perms)
+		if !errors.Is(err, st.wantErr) {
+			t.Fatalf("%s: err = %v, want %v", st.name, err, st.wantErr)
+		}
+		if err == nil && p.Status != st.status {
+			t.Fatalf("%s: status = %q, want %q", st.name, p.Status, st.status)
+		}
+		if st.status == domain.ProductStatusPublished && err == nil {
+			if _, err = svc.GetProductByID(ctx, id, anonymous); err != nil {
+				t.Fatalf("anonymous get published: %v", err)
+			}
+		}
+	}
+	if _, err := svc.ChangeStatus(ctx, sellerID, id, 1, domain.ProductStatusInReview, seller); !errors.Is(err, custom.ErrPreconditionFailed) {
+		t.Errorf("stale status change: err = %v, want ErrPreconditionFailed", err)
+	}
+}
+
+func TestProductSchedule(t *testing.T) {
+	ctx := context.Background()
+	admin := auth.Permissions{auth.PermProductWrite, auth.PermProductManage}
+	seller := auth.Permissions{auth.PermProductWrite}
+	var anonymous auth.Permissions
+
+	svc := product.NewProductService(newFakeRepo(), &fakeImages{}, time.Hour)
+	id, _ := svc.CreateProduct(ctx, 1, &domain.Product{Title: "lamp"}, seller)
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+
+	if _, err := svc.ScheduleProduct(ctx, id, 1, domain.ProductSchedule{PublishAt: &past}, seller); !errors.Is(err, custom.ErrForbidden) {
+		t.Errorf("seller schedules: err = %v, want ErrForbidden", err)
+	}
+	if _, err := svc.ScheduleProduct(ctx, id, 1, domain.ProductSchedule{PublishAt: &future, UnpublishAt: &past}, admin); !errors.Is(err, custom.ErrInvalidInput) {
+		t.Errorf("unpublish before publish: err = %v, want ErrInvalidInput", err)
+	}
+	if _, err := svc.ScheduleProduct(ctx, id, 1, domain.ProductSchedule{PublishAt: &past, UnpublishAt: &future}, admin); err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+
+	published, unpublished, err := svc.ApplySchedule(ctx)
+	if err != nil || published != 1 || unpublished != 0 {
+		t.Fatalf("apply = %d, %d, %v; want 1 published", published, unpublished, err)
+	}
+	p, err := svc.GetProductByID(ctx, id, anonymous)
+	if err != nil || p.PublishAt != nil || p.UnpublishAt == nil {
+		t.Fatalf("after publish = %+v, %v; want published with pending unpublish", p, err)
+	}
+	if _, err = svc.ScheduleProduct(ctx, id, p.Version, domain.ProductSchedule{PublishAt: &future}, admin); !errors.Is(err, custom.ErrConflict) {
+		t.Errorf("schedule publish of published product: err = %v, want ErrConflict", err)
+	}
+
+	if _, err = svc.ScheduleProduct(ctx, id, p.Version, domain.ProductSchedule{UnpublishAt: &past}, admin); err != nil {
+		t.Fatalf("schedule unpublish: %v", err)
+	}
+	if published, unpublished, err = svc.ApplySchedule(ctx); err != nil || published != 0 || unpublished != 1 {
+		t.Fatalf("apply = %d, %d, %v; want 1 unpublished", published, unpublished, err)
+	}
+	if _, err = svc.GetProductByID(ctx, id, anonymous); !errors.Is(err, custom.ErrNotFound) {
+		t.Errorf("anonymous get archived: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestPublishedProductEdits(t *testing.T) {
+	ctx := context.Background()
+	admin := auth.Permissions{auth.PermProductWrite, auth.PermProductManage}
+	seller := auth.Permissions{auth.PermProductWrite}
+	const sellerID, adminID = 1, 2
+
+	svc := product.NewProductService(newFakeRepo(), &fakeImages{}, time.Hour)
+	id, _ := svc.CreateProduct(ctx, sellerID, &domain.Product{Title: "lamp", Price: 10}, seller)
+	published, err := svc.ChangeStatus(ctx, adminID, id, 1, domain.ProductStatusPublished, admin)
+	if err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	// Одобренное содержимое владелец без проверки не меняет
+	edit := &domain.Product{Title: "unreviewed", Price: 10, Version: published.Version}
+	if err = svc.UpdateProductByID(ctx, sellerID, id, edit, seller); !errors.Is(err, custom.ErrForbidden) {
+		t.Errorf("owner updates published product: err = %v, want ErrForbidden", err)
+	}
+	title := "unreviewed"
+	apply := func(*domain.Product) (domain.ProductPatch, error) { return domain.ProductPatch{Title: &title}, nil }
+	if _, err = svc.PatchProductByID(ctx, sellerID, id, published.Version, seller, apply); !errors.Is(err, custom.ErrForbidden) {
+		t.Errorf("owner patches published product: err = %v, want ErrForbidden", err)
+	}
+	if got, _ := svc.GetProductByID(ctx, id, seller); got.Title != "lamp" {
+		t.Fatalf("title = %q, published content must not change", got.Title)
+	}
+
+	if _, err = svc.PatchProductByID(ctx, adminID, id, published.Version, admin, apply); err != nil {
+		t.Errorf("admin patches published product: %v", err)
+	}
+}
+
+func TestStatusChangeKeepsSchedule(t *testing.T) {
+	ctx := context.Background()
+	admin := auth.Permissions{auth.PermProductWrite, auth.PermProductManage}
+	seller := auth.Permissions{auth.PermProductWrite}
+
+	svc := product.NewProductService(newFakeRepo(), &fakeImages{}, time.Hour)
+	id, _ := svc.CreateProduct(ctx, 1, &domain.Product{Title: "lamp"}, seller)
+	future := time.Now().Add(time.Hour)
+	p, err := svc.ScheduleProduct(ctx, id, 1, domain.ProductSchedule{PublishAt: &future}, admin)
+	if err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+
+	// Отправка на проверку не отменяет время публикации
+	if p, err = svc.ChangeStatus(ctx, 1, id, p.Version, domain.ProductStatusInReview, seller); err != nil || p.PublishAt == nil {
+		t.Fatalf("submit = %+v, %v; want publish_at kept", p, err)
+	}
+	// Ручная публикация делает расписание ненужным
+	if p, err = svc.ChangeStatus(ctx, 2, id, p.Version, domain.ProductStatusPublished, admin); err != nil || p.PublishAt != nil {
+		t.Fatalf("publish = %+v, %v; want publish_at cleared", p, err)
+	}
+}
@@ -0,0 +1,97 @@
+package role
+
+import (
+	"context"
+	"fmt"
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+	"slices"
+	"strings"
+)
+
+type Repository interface {
+	GetAll(ctx context.Context) ([]domain.Role, error)
+	GetPermissions(ctx context.Context, role auth.Role) (auth.Permissions, error)
+	Upsert(ctx context.Context, role *domain.Role) error
+	Delete(ctx context.Context, name auth.Role) error
+	GetHolderIDs(ctx context.Context, role auth.Role) ([]int, error)
+}
+
+type SessionRevoker interface {
+	RevokeAllSessions(ctx context.Context, userID int) error
+}
+
+type Service struct {
+	repo     Repository
+	sessions SessionRevoker
+}
+
+func NewRoleService(repo Repository, sessions SessionRevoker) *Service {
+	return &Service{repo: repo, sessions: sessions}
+}
+
+func (s *Service) GetAllRoles(ctx context.Context) ([]domain.Role, error) {
+	roles, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+	return roles, nil
+}
+
+func (s *Service) SaveRole(ctx context.Context, role *domain.Role) error {
+	role.Name = auth.Role(strings.TrimSpace(string(role.Name)))
+	if role.Name == "" {
+		return fmt.Errorf("role name is required: %w", custom.ErrInvalidInput)
+	}
+	// Администратор всегда сохраняет полный набор прав, иначе можно потерять доступ к управлению ролями.
+	if role.Name == auth.RoleAdmin {
+		return fmt.Errorf("built-in role %q cannot be changed: %w", role.Name, custom.ErrConflict)
+	}
+	for _, p := range role.Permissions {
+		if !auth.IsKnownPermission(p) {
+			return fmt.Errorf("unknown permission %q: %w", p, custom.ErrInvalidInput)
+		}
+	}
+
+	current, err := s.repo.GetPermissions(ctx, role.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get role permissions: %w", err)
+	}
+	if err = s.repo.Upsert(ctx, role); err != nil {
+		return fmt.Errorf("failed to save role: %w", err)
+	}
+	if samePermissions(current, role.Permissions) {
+		return nil
+	}
+
+	// Права роли зашиты в выданные токены, поэтому держатели роли входят заново
+	// и получают новый набор прав.
+	holders, err := s.repo.GetHolderIDs(ctx, role.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get role holders: %w", err)
+	}
+	for _, id := range holders {
+		if err = s.sessions.RevokeAllSessions(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func samePermissions(a, b auth.Permissions) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(slices.Compact(a), slices.Compact(b))
+}
+
+func (s *Service) DeleteRole(ctx context.Context, name auth.Role) error {
+	if name == auth.RoleAdmin || name == auth.RoleUser {
+		return fmt.Errorf("built-in role %q cannot be deleted: %w", name, custom.ErrConflict)
+	}
+	if err := s.repo.Delete(ctx, name); err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package role_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	"product-catalog/internal/service/role"
+)
+
+// fakeRepo хранит права ролей и их держателей
+type fakeRepo struct {
+	perms   map[auth.Role]auth.Permissions
+	holders map[auth.Role][]int
+}
+
+func (r *fakeRepo) GetAll(context.Context) ([]domain.Role, error) { return nil, nil }
+
+func (r *fakeRepo) GetPermissions(_ context.Context, name auth.Role) (auth.Permissions, error) {
+	return r.perms[name], nil
+}
+
+func (r *fakeRepo) Upsert(_ context.Context, rl *domain.Role) error {
+	r.perms[rl.Name] = rl.Permissions
+	return nil
+}
+
+func (r *fakeRepo) Delete(context.Context, auth.Role) error { return nil }
+
+func (r *fakeRepo) GetHolderIDs(_ context.Context, name auth.Role) ([]int, error) {
+	return r.holders[name], nil
+}
+
+type fakeSessions struct {
+	revoked []int
+}
+
+func (s *fakeSessions) RevokeAllSessions(_ context.Context, userID int) error {
+	s.revoked = append(s.revoked, userID)
+	return nil
+}
+
+func TestSaveRoleRevokesHolderSessions(t *testing.T) {
+	seller := auth.Role("seller")
+
+	tests := []struct {
+		name        string
+		role        domain.Role
+		wantRevoked []int
+	}{
+		{
+			name:        "permissions changed",
+			role:        domain.Role{Name: seller, Permissions: auth.Permissions{auth.PermProductWrite, auth.PermCategoryWrite}},
+			wantRevoked: []int{3, 5},
+		},
+		{
+			name:        "permissions removed",
+			role:        domain.Role{Name: seller},
+			wantRevoked: []int{3, 5},
+		},
+		{
+			name: "only description changed",
+			role: domain.Role{Name: seller, Description: "Sellers", Permissions: auth.Permissions{auth.PermProductWrite}},
+		},
+		{
+			name: "new role",
+			role: domain.Role{Name: "editor", Permissions: auth.Permissions{auth.PermCategoryWrite}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepo{
+				perms:   map[auth.Role]auth.Permissions{seller: {auth.PermProductWrite}},
+				holders: map[auth.Role][]int{seller: {3, 5}},
+			}
+			sessions := &fakeSessions{}
+			svc := role.NewRoleService(repo, sessions)
+
+			if err := svc.SaveRole(context.Background(), &tt.role); err != nil {
+				t.Fatalf("SaveRole: %v", err)
+			}
+			if !slices.Equal(sessions.revoked, tt.wantRevoked) {
+				t.Errorf("revoked = %v; want %v", sessions.revoked, tt.wantRevoked)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"product-catalog/internal/domain"
+	"product-catalog/internal/dto"
+	custom "product-catalog/internal/errors"
+	"strings"
+	"time"
+)
+
+const (
+	idLength = 32
+	// Время активности обновляем не чаще раза в минуту, чтобы не писать в БД на каждый запрос.
+	touchInterval      = time.Minute
+	maxUserAgentLength = 512
+)
+
+type Repository interface {
+	Create(ctx context.Context, session *domain.Session) error
+	GetByID(ctx context.Context, id string) (*domain.Session, error)
+	ListActiveByUserID(ctx context.Context, userID int) ([]domain.Session, error)
+	Touch(ctx context.Context, id, ip, userAgent string, seenAt time.Time) error
+	Revoke(ctx context.Context, userID int, id string) error
+	RevokeAllExcept(ctx context.Context, userID int, exceptID string) error
+}
+
+type Service struct {
+	repo Repository
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+func NewSessionService(repo Repository, ttl time.Duration) *Service {
+	return &Service{repo: repo, ttl: ttl, now: time.Now}
+}
+
+// StartSession создаёт сессию для нового access-токена и возвращает её ID (claim sid).
+func (s *Service) StartSession(ctx context.Context, userID int, client dto.ClientInfo) (string, error) {
+	return s.start(ctx, userID, client, s.ttl, nil)
+}
+
+// StartImpersonationSession открывает сессию пользователя userID от имени администратора actorID.
+// Такая сессия видна пользователю в списке и может быть им завершена.
+func (s *Service) StartImpersonationSession(ctx context.Context, userID, actorID int, client dto.ClientInfo, ttl time.Duration) (string, error) {
+	return s.start(ctx, userID, client, ttl, &actorID)
+}
+
+func (s *Service) start(ctx context.Context, userID int, client dto.ClientInfo, ttl time.Duration, impersonatorID *int) (string, error) {
+	id, err := generateID()
+	if err != nil {
+		return "", err
+	}
+
+	userAgent := truncate(client.UserAgent, maxUserAgentLength)
+	now := s.now()
+	err = s.repo.Create(ctx, &domain.Session{
+		ID:             id,
+		UserID:         userID,
+		Device:         DescribeDevice(userAgent),
+		IP:             client.IP,
+		UserAgent:      userAgent,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(ttl),
+		ImpersonatorID: impersonatorID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to start session: %w", err)
+	}
+	return id, nil
+}
+
+func (s *Service) ValidateSession(ctx context.Context, sessionID string, userID int, ip, userAgent string) error {
+	sess, err := s.repo.GetByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, custom.ErrNotFound) {
+			return custom.ErrUnauthorized
+		}
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+
+	now := s.now()
+	if sess.UserID != userID || sess.RevokedAt != nil || !now.Before(sess.ExpiresAt) {
+		return custom.ErrUnauthorized
+	}
+
+	userAgent = truncate(userAgent, maxUserAgentLength)
+	if now.Sub(sess.LastSeenAt) >= touchInterval || sess.IP != ip || sess.UserAgent != userAgent {
+		if err = s.repo.Touch(ctx, sessionID, ip, userAgent, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) ListSessions(ctx context.Context, userID int) ([]domain.Session, error) {
+	sessions, err := s.repo.ListActiveByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+func (s *Service) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	if err := s.repo.Revoke(ctx, userID, sessionID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// RevokeOtherSessions завершает все сессии пользователя, кроме текущей.
+func (s *Service) RevokeOtherSessions(ctx context.Context, userID int, currentID string) error {
+	if err := s.repo.RevokeAllExcept(ctx, userID, currentID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// RevokeAllSessions завершает все сессии пользователя, например при блокировке аккаунта.
+func (s *Service) RevokeAllSessions(ctx context.Context, userID int) error {
+	return s.RevokeOtherSessions(ctx, userID, "")
+}
+
+// DescribeDevice строит короткое описание устройства по User-Agent, например "Chrome on Windows".
+func DescribeDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return "Unknown device"
+	}
+
+	var os string
+	switch {
+	case strings.Contains(ua, "iphone"):
+		os = "iPhone"
+	case strings.Contains(ua, "ipad"):
+		os = "iPad"
+	case strings.Contains(ua, "android"):
+		os = "Android"
+	case strings.Contains(ua, "windows"):
+		os = "Windows"
+	case strings.Contains(ua, "mac os") || strings.Contains(ua, "macintosh"):
+		os = "macOS"
+	case strings.Contains(ua, "linux"):
+		os = "Linux"
+	}
+
+	var client string
+	switch {
+	case strings.Contains(ua, "edg/"):
+		client = "Edge"
+	case strings.Contains(ua, "opr/") || strings.Contains(ua, "opera"):
+		client = "Opera"
+	case strings.Contains(ua, "firefox/"):
+		client = "Firefox"
+	case strings.Contains(ua, "chrome/") || strings.Contains(ua, "crios/"):
+		client = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		client = "Safari"
+	case strings.Contains(ua, "curl/"):
+		client = "curl"
+	case strings.Contains(ua, "postman"):
+		client = "Postman"
+	}
+
+	switch {
+	case client != "" && os != "":
+		return client + " on " + os
+	case client != "":
+		return client
+	case os != "":
+		return os
+	}
+	return "Unknown device"
+}
+
+func generateID() (string, error) {
+	buf := make([]byte, idLength/2)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate session id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,134 @@
+package session_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"product-catalog/internal/domain"
+	"product-catalog/internal/dto"
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/service/session"
+)
+
+type fakeRepo struct {
+	sessions map[string]*domain.Session
+	touches  int
+}
+
+func (r *fakeRepo) Create(_ context.Context, s *domain.Session) error {
+	cp := *s
+	cp.LastSeenAt = s.CreatedAt
+	r.sessions[s.ID] = &cp
+	return nil
+}
+
+func (r *fakeRepo) GetByID(_ context.Context, id string) (*domain.Session, error) {
+	s, ok := r.sessions[id]
+	if !ok {
+		return nil, custom.ErrNotFound
+	}
+	cp := *s
+	return &cp, nil
+}
+
+func (r *fakeRepo) ListActiveByUserID(_ context.Context, userID int) ([]domain.Session, error) {
+	var res []domain.Session
+	for _, s := range r.sessions {
+		if s.UserID == userID && s.RevokedAt == nil {
+			res = append(res, *s)
+		}
+	}
+	return res, nil
+}
+
+func (r *fakeRepo) Touch(_ context.Context, id, ip, userAgent string, seenAt time.Time) error {
+	r.touches++
+	s := r.sessions[id]
+	s.IP, s.UserAgent, s.LastSeenAt = ip, userAgent, seenAt
+	return nil
+}
+
+func (r *fakeRepo) Revoke(_ context.Context, userID int, id string) error {
+	s, ok := r.sessions[id]
+	if !ok || s.UserID != userID || s.RevokedAt != nil {
+		return custom.ErrNotFound
+	}
+	now := time.Now()
+	s.RevokedAt = &now
+	return nil
+}
+
+func (r *fakeRepo) RevokeAllExcept(_ context.Context, userID int, exceptID string) error {
+	now := time.Now()
+	for id, s := range r.sessions {
+		if s.UserID == userID && id != exceptID {
+			s.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func TestValidateSession(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeRepo{sessions: map[string]*domain.Session{}}
+	svc := session.NewSessionService(repo, time.Hour)
+	client := dto.ClientInfo{IP: "10.0.0.1", UserAgent: "curl/8.0"}
+
+	first, err := svc.StartSession(ctx, 1, client)
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	second, _ := svc.StartSession(ctx, 1, client)
+
+	if err = svc.ValidateSession(ctx, first, 1, client.IP, client.UserAgent); err != nil {
+		t.Fatalf("ValidateSession: %v", err)
+	}
+	if repo.touches != 0 {
+		t.Fatalf("touches = %d, want 0 for a fresh session", repo.touches)
+	}
+	if err = svc.ValidateSession(ctx, first, 1, "10.0.0.2", client.UserAgent); err != nil || repo.touches != 1 {
+		t.Fatalf("err = %v, touches = %d; want ip change recorded", err, repo.touches)
+	}
+	if err = svc.ValidateSession(ctx, first, 2, client.IP, client.UserAgent); !errors.Is(err, custom.ErrUnauthorized) {
+		t.Fatalf("foreign user: err = %v, want ErrUnauthorized", err)
+	}
+
+	if err = svc.RevokeOtherSessions(ctx, 1, second); err != nil {
+		t.Fatalf("RevokeOtherSessions: %v", err)
+	}
+	if err = svc.ValidateSession(ctx, first, 1, client.IP, client.UserAgent); !errors.Is(err, custom.ErrUnauthorized) {
+		t.Fatalf("revoked: err = %v, want ErrUnauthorized", err)
+	}
+	if err = svc.ValidateSession(ctx, second, 1, client.IP, client.UserAgent); err != nil {
+		t.Fatalf("current session: %v", err)
+	}
+
+	repo.sessions[second].ExpiresAt = time.Now().Add(-time.Second)
+	if err = svc.ValidateSession(ctx, second, 1, client.IP, client.UserAgent); !errors.Is(err, custom.ErrUnauthorized) {
+		t.Fatalf("expired: err = %v, want ErrUnauthorized", err)
+	}
+	if err = svc.ValidateSession(ctx, "missing", 1, client.IP, client.UserAgent); !errors.Is(err, custom.ErrUnauthorized) {
+		t.Fatalf("missing: err = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestDescribeDevice(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want string
+	}{
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0 Safari/537.36", "Chrome on Windows"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1", "Safari on iPhone"},
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:127.0) Gecko/20100101 Firefox/127.0", "Firefox on Linux"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0 Safari/537.36 Edg/126.0", "Edge on macOS"},
+		{"curl/8.4.0", "curl"},
+		{"", "Unknown device"},
+	}
+	for _, tt := range tests {
+		if got := session.DescribeDevice(tt.ua); got != tt.want {
+			t.Errorf("DescribeDevice(%q) = %q, want %q", tt.ua, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"product-catalog/internal/adapters/oidc"
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	"product-catalog/internal/dto"
+	custom "product-catalog/internal/errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	flowPurpose       = "oidc_flow"
+	maxUsernameLength = 12
+	minUsernameLength = 3
+)
+
+type Provider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier string) (string, error)
+	VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*domain.ExternalProfile, error)
+}
+
+type IdentityRepository interface {
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*domain.UserIdentity, error)
+	ListByUserID(ctx context.Context, userID int) ([]domain.UserIdentity, error)
+	Create(ctx context.Context, identity *domain.UserIdentity) error
+	CreateUserWithIdentity(ctx context.Context, user *domain.User, identity *domain.UserIdentity) error
+	TouchLastLogin(ctx context.Context, id int) error
+}
+
+type UserRepository interface {
+	GetByEmail(ctx context.Context, email string) (*domain.User, error)
+	ExistsByEmailOrUsername(ctx context.Context, email, username string) (bool, error)
+}
+
+type Hasher interface {
+	Hash(password string) (string, error)
+}
+
+type LoginCompleter interface {
+	CompleteExternalLogin(ctx context.Context, userID int, client dto.ClientInfo) (*dto.LoginOutput, error)
+}
+
+type Service struct {
+	providers  map[string]Provider
+	identities IdentityRepository
+	users      UserRepository
+	hasher     Hasher
+	logins     LoginCompleter
+	secret     []byte
+	flowTTL    time.Duration
+}
+
+func NewSSOService(providers map[string]Provider, identities IdentityRepository, users UserRepository, hasher Hasher, logins LoginCompleter, secret string, flowTTL time.Duration) *Service {
+	return &Service{
+		providers:  providers,
+		identities: identities,
+		users:      users,
+		hasher:     hasher,
+		logins:     logins,
+		secret:     []byte(secret),
+		flowTTL:    flowTTL,
+	}
+}
+
+// flowClaims хранит state, nonce и code_verifier между редиректом к провайдеру
+// и callback. Передаются в подписанной HttpOnly-cookie, а не в URL.
+type flowClaims struct {
+	Provider string `json:"prv"`
+	State    string `json:"st"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"cv"`
+	Purpose  string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+func (s *Service) BeginLogin(ctx context.Context, providerName string) (string, string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", "", custom.ErrNotFound
+	}
+
+	state, err := oidc.RandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.RandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oidc.RandomToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, oidc.CodeChallenge(verifier))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to build authorization url: %w", err)
+	}
+
+	claims := &flowClaims{
+		Provider: providerName,
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+		Purpose:  flowPurpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.flowTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	flow, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to sign oidc flow: %w", err)
+	}
+	return authURL, flow, nil
+}
+
+func (s *Service) CompleteLogin(ctx context.Context, providerName, code, state, flow string, client dto.ClientInfo) (*dto.LoginOutput, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, custom.ErrNotFound
+	}
+
+	claims, err := s.parseFlow(flow)
+	if err != nil || claims.Provider != providerName || claims.State == "" || claims.State != state {
+		return nil, fmt.Errorf("invalid oidc state: %w", custom.ErrUnauthorized)
+	}
+
+	rawIDToken, err := provider.Exchange(ctx, code, claims.Verifier)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, custom.ErrUnauthorized)
+	}
+	profile, err := provider.VerifyIDToken(ctx, rawIDToken, claims.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, custom.ErrUnauthorized)
+	}
+
+	userID, err := s.resolveUser(ctx, providerName, profile)
+	if err != nil {
+		return nil, err
+	}
+	return s.logins.CompleteExternalLogin(ctx, userID, client)
+}
+
+func (s *Service) ListIdentities(ctx context.Context, userID int) ([]domain.UserIdentity, error) {
+	identities, err := s.identities.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	return identities, nil
+}
+
+func (s *Service) parseFlow(flow string) (*flowClaims, error) {
+	claims := &flowClaims{}
+	_, err := jwt.ParseWithClaims(flow, claims, func(t *jwt.Token) (interface{}, error) {
+		return s.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != flowPurpose {
+		return nil, errors.New("invalid flow purpose")
+	}
+	return claims, nil
+}
+
+// resolveUser находит пользователя по привязанной identity. При первом входе
+// identity привязывается к существующему аккаунту с тем же подтверждённым email
+// либо создаётся новый пользователь с ролью по умолчанию.
+func (s *Service) resolveUser(ctx context.Context, providerName string, profile *domain.ExternalProfile) (int, error) {
+	identity, err := s.identities.GetByProviderSubject(ctx, providerName, profile.Subject)
+	if err == nil {
+		if err = s.identities.TouchLastLogin(ctx, identity.ID); err != nil {
+			return 0, err
+		}
+		return identity.UserID, nil
+	}
+	if !errors.Is(err, custom.ErrNotFound) {
+		return 0, fmt.Errorf("failed to get identity: %w", err)
+	}
+
+	if profile.Email == "" {
+		return 0, fmt.Errorf("identity provider returned no email: %w", custom.ErrUnauthorized)
+	}
+
+	identity = &domain.UserIdentity{
+		Provider:  providerName,
+		Subject:   profile.Subject,
+		Email:     profile.Email,
+		CreatedAt: time.Now(),
+	}
+
+	existing, err := s.users.GetByEmail(ctx, profile.Email)
+	switch {
+	case err == nil:
+		if !profile.EmailVerified {
+			return 0, fmt.Errorf("cannot link unverified email to existing account: %w", custom.ErrConflict)
+		}
+		identity.UserID = existing.ID
+		if err = s.identities.Create(ctx, identity); err != nil {
+			return 0, fmt.Errorf("failed to link identity: %w", err)
+		}
+		return existing.ID, nil
+	case !errors.Is(err, custom.ErrNotFound):
+		return 0, fmt.Errorf("failed to get user by email: %w", err)
+	}
+
+	return s.provision(ctx, profile, identity)
+}
+
+func (s *Service) provision(ctx context.Context, profile *domain.ExternalProfile, identity *domain.UserIdentity) (int, error) {
+	username, err := s.uniqueUsername(ctx, profile)
+	if err != nil {
+		return 0, err
+	}
+
+	// Пароль случайный и нигде не сохраняется: такой пользователь входит только через SSO.
+	randomPassword, err := oidc.RandomToken()
+	if err != nil {
+		return 0, err
+	}
+	hash, err := s.hasher.Hash(randomPassword)
+	if err != nil {
+		return 0, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	newUser := &domain.User{
+		Username:     username,
+		Email:        profile.Email,
+		PasswordHash: hash,
+		Role:         auth.RoleUser,
+		CreatedAt:    time.Now(),
+	}
+	if err = s.identities.CreateUserWithIdentity(ctx, newUser, identity); err != nil {
+		return 0, fmt.Errorf("failed to provision user: %w", err)
+	}
+	return newUser.ID, nil
+}
+
+func (s *Service) uniqueUsername(ctx context.Context, profile *domain.ExternalProfile) (string, error) {
+	base := sanitizeUsername(profile.Username)
+	if len(base) < minUsernameLength {
+		base = sanitizeUsername(strings.Split(profile.Email, "@")[0])
+	}
+	if len(base) < minUsernameLength {
+		base = "user"
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		exists, err := s.users.ExistsByEmailOrUsername(ctx, "", candidate)
+		if err != nil {
+			return "", fmt.Errorf("failed to check username: %w", err)
+		}
+		if !exists {
+			return candidate, nil
+		}
+		suffix, err := randomDigits(4)
+		if err != nil {
+			return "", err
+		}
+		candidate = truncate(base, maxUsernameLength-len(suffix)) + suffix
+	}
+	return "", fmt.Errorf("failed to pick unique username: %w", custom.ErrConflict)
+}
+
+func sanitizeUsername(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '.' || r == '-' {
+			b.WriteRune(r)
+		}
+	}
+	return truncate(b.String(), maxUsernameLength)
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+func randomDigits(n int) (string, error) {
+	maxVal := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+	v, err := rand.Int(rand.Reader, maxVal)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate username suffix: %w", err)
+	}
+	return fmt.Sprintf("%0*d", n, v), nil
+}
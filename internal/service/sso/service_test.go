@@ -0,0 +1,221 @@
+package sso_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"product-catalog/internal/adapters/oidc"
+	"product-catalog/internal/adapters/oidc/oidctest"
+	"product-catalog/internal/domain"
+	"product-catalog/internal/dto"
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/service/sso"
+)
+
+type fakeStore struct {
+	users      []domain.User
+	identities []domain.UserIdentity
+}
+
+func (f *fakeStore) GetByProviderSubject(_ context.Context, provider, subject string) (*domain.UserIdentity, error) {
+	for _, i := range f.identities {
+		if i.Provider == provider && i.Subject == subject {
+			return &i, nil
+		}
+	}
+	return nil, custom.ErrNotFound
+}
+
+func (f *fakeStore) ListByUserID(_ context.Context, userID int) ([]domain.UserIdentity, error) {
+	var out []domain.UserIdentity
+	for _, i := range f.identities {
+		if i.UserID == userID {
+			out = append(out, i)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeStore) Create(_ context.Context, identity *domain.UserIdentity) error {
+	identity.ID = len(f.identities) + 1
+	f.identities = append(f.identities, *identity)
+	return nil
+}
+
+func (f *fakeStore) CreateUserWithIdentity(ctx context.Context, user *domain.User, identity *domain.UserIdentity) error {
+	user.ID = len(f.users) + 1
+	f.users = append(f.users, *user)
+	identity.UserID = user.ID
+	return f.Create(ctx, identity)
+}
+
+func (f *fakeStore) TouchLastLogin(context.Context, int) error { return nil }
+
+func (f *fakeStore) GetByEmail(_ context.Context, email string) (*domain.User, error) {
+	for _, u := range f.users {
+		if u.Email == email {
+			return &u, nil
+		}
+	}
+	return nil, custom.ErrNotFound
+}
+
+func (f *fakeStore) ExistsByEmailOrUsername(_ context.Context, email, username string) (bool, error) {
+	for _, u := range f.users {
+		if (email != "" && u.Email == email) || u.Username == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type fakeHasher struct{}
+
+func (fakeHasher) Hash(password string) (string, error) { return "hash:" + password, nil }
+
+type fakeLogins struct{}
+
+func (fakeLogins) CompleteExternalLogin(_ context.Context, userID int, _ dto.ClientInfo) (*dto.LoginOutput, error) {
+	return &dto.LoginOutput{Token: fmt.Sprintf("token-%d", userID)}, nil
+}
+
+func newService(srv *oidctest.Server, store *fakeStore) *sso.Service {
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:      srv.Issuer(),
+		ClientID:    srv.ClientID,
+		RedirectURL: "http://localhost:1488/auth/oidc/test/callback",
+	}, srv.Client())
+	providers := map[string]sso.Provider{"test": provider}
+	return sso.NewSSOService(providers, store, store, fakeHasher{}, fakeLogins{}, "secret", time.Minute)
+}
+
+// login проходит полный цикл: BeginLogin, авторизация у мок-провайдера, CompleteLogin.
+func login(t *testing.T, svc *sso.Service) (*dto.LoginOutput, error) {
+	t.Helper()
+	ctx := context.Background()
+	authURL, flow, err := svc.BeginLogin(ctx, "test")
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	loc, _ := url.Parse(resp.Header.Get("Location"))
+	return svc.CompleteLogin(ctx, "test", loc.Query().Get("code"), loc.Query().Get("state"), flow, dto.ClientInfo{})
+}
+
+func TestCompleteLogin(t *testing.T) {
+	tests := []struct {
+		name          string
+		users         []domain.User
+		emailVerified bool
+		wantErr       error
+		wantUsers     int
+		wantUserID    int
+	}{
+		{name: "provisions new user", emailVerified: true, wantUsers: 1, wantUserID: 1},
+		{
+			name:          "links verified email to existing user",
+			users:         []domain.User{{ID: 1, Username: "existing", Email: "sso.user@example.com"}},
+			emailVerified: true,
+			wantUsers:     1,
+			wantUserID:    1,
+		},
+		{
+			name:          "refuses to link unverified email",
+			users:         []domain.User{{ID: 1, Username: "existing", Email: "sso.user@example.com"}},
+			emailVerified: false,
+			wantErr:       custom.ErrConflict,
+			wantUsers:     1,
+		},
+		{
+			name:          "picks another username when taken",
+			users:         []domain.User{{ID: 1, Username: "ssouser", Email: "other@example.com"}},
+			emailVerified: true,
+			wantUsers:     2,
+			wantUserID:    2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := oidctest.NewServer(t, "catalog")
+			srv.User.EmailVerified = tt.emailVerified
+			store := &fakeStore{users: tt.users}
+			svc := newService(srv, store)
+
+			out, err := login(t, svc)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if len(store.users) != tt.wantUsers {
+				t.Fatalf("users = %d, want %d", len(store.users), tt.wantUsers)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if len(store.identities) != 1 || store.identities[0].UserID != tt.wantUserID {
+				t.Fatalf("identities = %+v, want one linked to user %d", store.identities, tt.wantUserID)
+			}
+			if out.Token == "" {
+				t.Fatal("expected token")
+			}
+			usernames := map[string]bool{}
+			for _, u := range store.users {
+				if usernames[u.Username] {
+					t.Fatalf("duplicate username %q", u.Username)
+				}
+				usernames[u.Username] = true
+			}
+		})
+	}
+}
+
+func TestCompleteLoginReusesLinkedIdentity(t *testing.T) {
+	srv := oidctest.NewServer(t, "catalog")
+	store := &fakeStore{}
+	svc := newService(srv, store)
+
+	if _, err := login(t, svc); err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	if _, err := login(t, svc); err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	if len(store.users) != 1 || len(store.identities) != 1 {
+		t.Fatalf("users = %d, identities = %d, want 1 and 1", len(store.users), len(store.identities))
+	}
+}
+
+func TestCompleteLoginRejectsForgedState(t *testing.T) {
+	srv := oidctest.NewServer(t, "catalog")
+	svc := newService(srv, &fakeStore{})
+	ctx := context.Background()
+
+	_, flow, err := svc.BeginLogin(ctx, "test")
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	if _, err = svc.CompleteLogin(ctx, "test", "code", "other-state", flow, dto.ClientInfo{}); !errors.Is(err, custom.ErrUnauthorized) {
+		t.Fatalf("err = %v, want ErrUnauthorized", err)
+	}
+	if _, err = svc.CompleteLogin(ctx, "test", "code", "state", "garbage", dto.ClientInfo{}); !errors.Is(err, custom.ErrUnauthorized) {
+		t.Fatalf("err = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestBeginLoginUnknownProvider(t *testing.T) {
+	srv := oidctest.NewServer(t, "catalog")
+	svc := newService(srv, &fakeStore{})
+	if _, _, err := svc.BeginLogin(context.Background(), "missing"); !errors.Is(err, custom.ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+}
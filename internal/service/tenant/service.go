@@ -0,0 +1,106 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Repository interface {
+	List(ctx context.Context) ([]domain.Tenant, error)
+}
+
+// Service определяет арендатора запроса по хосту. Список арендаторов меняется
+// редко, поэтому держится в памяти и перечитывается не чаще раза в ttl.
+type Service struct {
+	repo        Repository
+	defaultSlug string
+	ttl         time.Duration
+
+	mu       sync.Mutex
+	tenants  []domain.Tenant
+	byHost   map[string]int
+	loadedAt time.Time
+}
+
+func NewTenantService(repo Repository, defaultSlug string, ttl time.Duration) *Service {
+	return &Service{repo: repo, defaultSlug: defaultSlug, ttl: ttl}
+}
+
+// Resolve возвращает арендатора для заголовка Host. pinned == true, если хост
+// закреплён за арендатором; иначе возвращается арендатор по умолчанию, а
+// окончательно арендатора определит токен.
+func (s *Service) Resolve(ctx context.Context, host string) (int, bool, error) {
+	byHost, tenants, err := s.snapshot(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	if id, ok := byHost[normalizeHost(host)]; ok {
+		return id, true, nil
+	}
+	for _, t := range tenants {
+		if t.Slug == s.defaultSlug {
+			return t.ID, false, nil
+		}
+	}
+	return 0, false, fmt.Errorf("no tenant for host %q: %w", host, custom.ErrNotFound)
+}
+
+// ForEach вызывает fn в контексте каждого арендатора по очереди. Ошибка одного
+// арендатора не останавливает обработку остальных.
+func (s *Service) ForEach(ctx context.Context, fn func(ctx context.Context) error) error {
+	_, tenants, err := s.snapshot(ctx)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, t := range tenants {
+		if err = fn(auth.WithTenant(ctx, t.ID)); err != nil {
+			errs = append(errs, fmt.Errorf("tenant %s: %w", t.Slug, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// snapshot возвращает закэшированный список арендаторов. Если перечитать его
+// не удалось, используется прежний: недоступность БД не должна менять арендатора запроса.
+func (s *Service) snapshot(ctx context.Context) (map[string]int, []domain.Tenant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.byHost != nil && time.Now().Sub(s.loadedAt) < s.ttl {
+		return s.byHost, s.tenants, nil
+	}
+
+	tenants, err := s.repo.List(ctx)
+	if err != nil {
+		if s.byHost != nil {
+			s.loadedAt = time.Now()
+			return s.byHost, s.tenants, nil
+		}
+		return nil, nil, err
+	}
+
+	byHost := make(map[string]int)
+	for _, t := range tenants {
+		for _, h := range t.Hosts {
+			byHost[normalizeHost(h)] = t.ID
+		}
+	}
+	s.tenants, s.byHost, s.loadedAt = tenants, byHost, time.Now()
+	return s.byHost, s.tenants, nil
+}
+
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
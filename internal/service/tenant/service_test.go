@@ -0,0 +1,82 @@
+package tenant_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/service/tenant"
+)
+
+type fakeRepo struct {
+	tenants []domain.Tenant
+	calls   int
+	err     error
+}
+
+func (r *fakeRepo) List(context.Context) ([]domain.Tenant, error) {
+	r.calls++
+	return r.tenants, r.err
+}
+
+func TestResolve(t *testing.T) {
+	repo := &fakeRepo{tenants: []domain.Tenant{
+		{ID: 1, Slug: "default"},
+		{ID: 2, Slug: "acme", Hosts: []string{"shop.acme.test"}},
+	}}
+	svc := tenant.NewTenantService(repo, "default", time.Hour)
+
+	tests := []struct {
+		host       string
+		wantID     int
+		wantPinned bool
+	}{
+		{"shop.acme.test", 2, true},
+		{"SHOP.ACME.TEST:8443", 2, true},
+		{"localhost:1488", 1, false},
+	}
+	for _, tt := range tests {
+		id, pinned, err := svc.Resolve(context.Background(), tt.host)
+		if err != nil || id != tt.wantID || pinned != tt.wantPinned {
+			t.Errorf("Resolve(%q) = %d, %v, %v; want %d, %v", tt.host, id, pinned, err, tt.wantID, tt.wantPinned)
+		}
+	}
+	if repo.calls != 1 {
+		t.Errorf("repo calls = %d, want 1: tenants must be cached", repo.calls)
+	}
+
+	// Перечитать список не удалось — продолжаем работать с прежним
+	stale := tenant.NewTenantService(repo, "missing", 0)
+	if _, _, err := stale.Resolve(context.Background(), "localhost"); !errors.Is(err, custom.ErrNotFound) {
+		t.Fatalf("unknown default tenant err = %v, want ErrNotFound", err)
+	}
+	repo.err = errors.New("db is down")
+	if id, _, err := stale.Resolve(context.Background(), "shop.acme.test"); err != nil || id != 2 {
+		t.Fatalf("Resolve with stale cache = %d, %v; want 2", id, err)
+	}
+}
+
+func TestForEach(t *testing.T) {
+	repo := &fakeRepo{tenants: []domain.Tenant{{ID: 1, Slug: "default"}, {ID: 2, Slug: "acme"}, {ID: 3, Slug: "globex"}}}
+	svc := tenant.NewTenantService(repo, "default", time.Hour)
+
+	var seen []int
+	err := svc.ForEach(context.Background(), func(ctx context.Context) error {
+		id, _ := auth.TenantIDFromContext(ctx)
+		seen = append(seen, id)
+		if id == 2 {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	if err == nil {
+		t.Fatal("expected error from tenant acme")
+	}
+	if len(seen) != 3 {
+		t.Fatalf("visited tenants = %v, want all three despite the error", seen)
+	}
+}
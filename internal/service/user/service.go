@@ -2,27 +2,45 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	auth "product-catalog/internal/auth"
 	"product-catalog/internal/domain"
 	"product-catalog/internal/dto"
 	custom "product-catalog/internal/errors"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+const (
+	maxPageSize = 200
+	maxBulkSize = 500
+)
+
 type Repository interface {
 	Create(ctx context.Context, user *domain.User) error
 	GetByID(ctx context.Context, id int) (*domain.User, error)
-	UpdateByID(ctx context.Context, id int, username, email string, role auth.Role, passwordHash string) error
-	DeleteByID(ctx context.Context, id int) error
-	GetAll(ctx context.Context) ([]domain.User, error)
+	// Patch срабатывает, только если версия пользователя в БД равна version
+	Patch(ctx context.Context, id, version int, patch domain.UserPatch) error
+	UpdateStatus(ctx context.Context, id int, from []domain.UserStatus, to domain.UserStatus) error
+	DeleteByID(ctx context.Context, id, version int) error
+	Erase(ctx context.Context, id int) error
+	ListDeletedBefore(ctx context.Context, before time.Time) ([]int, error)
+	List(ctx context.Context, filter domain.UserFilter) ([]domain.User, int, error)
+	UpdateRoleBulk(ctx context.Context, ids []int, role auth.Role) ([]int, error)
+	UpdateStatusBulk(ctx context.Context, ids []int, from []domain.UserStatus, to domain.UserStatus) ([]int, error)
 	ExistsByEmailOrUsername(ctx context.Context, email, username string) (bool, error)
 	GetUserCredsAndRoleByEmail(ctx context.Context, email string) (string, int, auth.Role, error)
+	UpdatePasswordHash(ctx context.Context, id int, passwordHash string) error
 }
 
 type JwtService interface {
-	GenerateToken(userID int, role auth.Role) (string, error)
+	GenerateToken(userID, tenantID int, role auth.Role, perms auth.Permissions, sessionID string) (string, error)
 	ParseToken(tokenStr string) (*auth.JWTClaims, error)
+	GenerateChallengeToken(userID, tenantID int) (string, error)
+	ParseChallengeToken(tokenStr string) (int, int, error)
 }
 
 type Hasher interface {
@@ -30,14 +48,61 @@ type Hasher interface {
 	Compare(hashedPassword, password string) error
 }
 
+// Rehasher реализуют хэшеры, умеющие определять устаревшие хэши.
+type Rehasher interface {
+	NeedsRehash(hashedPassword string) bool
+}
+
+type PasswordPolicy interface {
+	Validate(password string, related ...string) error
+}
+
+type RoleRepository interface {
+	GetPermissions(ctx context.Context, role auth.Role) (auth.Permissions, error)
+}
+
+type SessionManager interface {
+	StartSession(ctx context.Context, userID int, client dto.ClientInfo) (string, error)
+	RevokeAllSessions(ctx context.Context, userID int) error
+}
+
+type LoginLimiter interface {
+	RetryAfter(ctx context.Context, key string) (time.Duration, error)
+	RegisterFailure(ctx context.Context, key string) error
+	Reset(ctx context.Context, key string) error
+}
+
 type Service struct {
-	repo   Repository
-	hasher Hasher
-	jwtSvc JwtService
+	repo           Repository
+	roleRepo       RoleRepository
+	hasher         Hasher
+	jwtSvc         JwtService
+	accountLimiter LoginLimiter
+	ipLimiter      LoginLimiter
+	twoFactorRepo  TwoFactorRepository
+	twoFactorCfg   TwoFactorConfig
+	sessions       SessionManager
+	passwordPolicy PasswordPolicy
+	restoreWindow  time.Duration
+
+	dummyOnce sync.Once
+	dummy     string
 }
 
-func NewUserService(repo Repository, hasher Hasher, jwtSvc JwtService) *Service {
-	return &Service{repo: repo, hasher: hasher, jwtSvc: jwtSvc}
+func NewUserService(repo Repository, roleRepo RoleRepository, hasher Hasher, jwtSvc JwtService, accountLimiter, ipLimiter LoginLimiter, twoFactorRepo TwoFactorRepository, twoFactorCfg TwoFactorConfig, sessions SessionManager, passwordPolicy PasswordPolicy, restoreWindow time.Duration) *Service {
+	return &Service{
+		repo:           repo,
+		roleRepo:       roleRepo,
+		hasher:         hasher,
+		jwtSvc:         jwtSvc,
+		accountLimiter: accountLimiter,
+		ipLimiter:      ipLimiter,
+		twoFactorRepo:  twoFactorRepo,
+		twoFactorCfg:   twoFactorCfg,
+		sessions:       sessions,
+		passwordPolicy: passwordPolicy,
+		restoreWindow:  restoreWindow,
+	}
 }
 
 func (s *Service) CreateUser(ctx context.Context, input *dto.CreateUserInput) error {
@@ -49,6 +114,10 @@ func (s *Service) CreateUser(ctx context.Context, input *dto.CreateUserInput) er
 		return custom.ErrConflict
 	}
 
+	if err = s.passwordPolicy.Validate(input.Password, input.Username, input.Email); err != nil {
+		return fmt.Errorf("%w: %v", custom.ErrInvalidInput, err)
+	}
+
 	hash, err := s.hasher.Hash(input.Password)
 	if err != nil {
 		return fmt.Errorf("failed to hash password: %w", err)
@@ -69,8 +138,21 @@ func (s *Service) CreateUser(ctx context.Context, input *dto.CreateUserInput) er
 	return nil
 }
 
-func (s *Service) UpdateUserByID(ctx context.Context, requesterID, targetID int, input *dto.UpdateUserInput, role auth.Role) error {
-	if role != auth.RoleAdmin && requesterID != targetID {
+// UpdateUserByID применяет изменения, только если пользователь всё ещё в версии version.
+func (s *Service) UpdateUserByID(ctx context.Context, requesterID, targetID, version int, input *dto.UpdateUserInput, perms auth.Permissions) error {
+	return s.PatchUserByID(ctx, requesterID, targetID, perms, func(current *domain.User) (*dto.UpdateUserInput, error) {
+		if current.Version != version {
+			return nil, custom.ErrPreconditionFailed
+		}
+		return input, nil
+	})
+}
+
+// PatchUserByID меняет только поля, заданные в изменениях. apply получает текущего
+// пользователя уже после проверки прав, чтобы патч не раскрывал чужие данные.
+func (s *Service) PatchUserByID(ctx context.Context, requesterID, targetID int, perms auth.Permissions,
+	apply func(current *domain.User) (*dto.UpdateUserInput, error)) error {
+	if !perms.Has(auth.PermUserManage) && requesterID != targetID {
 		return custom.ErrForbidden
 	}
 
@@ -79,44 +161,179 @@ func (s *Service) UpdateUserByID(ctx context.Context, requesterID, targetID int,
 		return fmt.Errorf("failed to get user: %w", err)
 	}
 
-	newRole := userFromDB.Role
-	if role == auth.RoleAdmin && input.Role != nil {
-		newRole = *input.Role
+	input, err := apply(userFromDB)
+	if err != nil {
+		return err
+	}
+	if _, impersonating := auth.ActorIDFromContext(ctx); impersonating && input.Password != nil {
+		return fmt.Errorf("password change is not allowed while impersonating: %w", custom.ErrForbidden)
+	}
+
+	// Смена собственных email или пароля требует подтверждения текущим паролем,
+	// чтобы украденный токен нельзя было превратить в захват аккаунта.
+	if requesterID == targetID && changesCredentials(userFromDB, input) {
+		if input.CurrentPassword == nil || *input.CurrentPassword == "" {
+			return fmt.Errorf("current password is required: %w", custom.ErrInvalidInput)
+		}
+		if s.hasher.Compare(userFromDB.PasswordHash, *input.CurrentPassword) != nil {
+			return fmt.Errorf("current password is incorrect: %w", custom.ErrForbidden)
+		}
+	}
+
+	var patch domain.UserPatch
+	if input.Role != nil && *input.Role != userFromDB.Role {
+		if !perms.Has(auth.PermRoleManage) {
+			return custom.ErrForbidden
+		}
+		patch.Role = input.Role
 	}
 
 	username := userFromDB.Username
 	if input.Username != nil {
 		username = *input.Username
+		patch.Username = input.Username
 	}
 
 	email := userFromDB.Email
 	if input.Email != nil {
 		email = *input.Email
+		patch.Email = input.Email
 	}
 
-	passwordHash := userFromDB.PasswordHash
 	if input.Password != nil {
-		newPasswordHash, err := s.hasher.Hash(*input.Password)
+		if err = s.passwordPolicy.Validate(*input.Password, username, email); err != nil {
+			return fmt.Errorf("%w: %v", custom.ErrInvalidInput, err)
+		}
+		passwordHash, err := s.hasher.Hash(*input.Password)
 		if err != nil {
 			return fmt.Errorf("failed to hash password: %w", err)
 		}
-		passwordHash = newPasswordHash
+		patch.PasswordHash = &passwordHash
 	}
-	err = s.repo.UpdateByID(ctx, targetID, username, email, newRole, passwordHash)
+	// Версия прочитанного пользователя защищает от изменений между чтением и записью
+	err = s.repo.Patch(ctx, targetID, userFromDB.Version, patch)
 	if err != nil {
 		return fmt.Errorf("failed to update user: %w", err)
 	}
+	// Разрешения роли зашиты в выданные токены: без отзыва сессий старая роль
+	// действовала бы до их истечения
+	if patch.Role != nil {
+		if err = s.sessions.RevokeAllSessions(ctx, targetID); err != nil {
+			return fmt.Errorf("role changed, but failed to revoke sessions: %w", err)
+		}
+	}
 	return nil
 }
 
-func (s *Service) DeleteUserByID(ctx context.Context, requesterID, targetID int, role auth.Role) error {
-	if role != auth.RoleAdmin && requesterID != targetID {
+func changesCredentials(current *domain.User, input *dto.UpdateUserInput) bool {
+	if input.Password != nil {
+		return true
+	}
+	return input.Email != nil && !strings.EqualFold(*input.Email, current.Email)
+}
+
+// DeleteUserByID мягко удаляет аккаунт: вход блокируется, а данные хранятся
+// до истечения окна восстановления, после чего аккаунт обезличивается.
+func (s *Service) DeleteUserByID(ctx context.Context, requesterID, targetID, version int, perms auth.Permissions) error {
+	if !perms.Has(auth.PermUserManage) && requesterID != targetID {
 		return custom.ErrForbidden
 	}
-	err := s.repo.DeleteByID(ctx, targetID)
-	if err != nil {
+	if _, impersonating := auth.ActorIDFromContext(ctx); impersonating {
+		return fmt.Errorf("account deletion is not allowed while impersonating: %w", custom.ErrForbidden)
+	}
+	if err := s.repo.DeleteByID(ctx, targetID, version); err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
+	return s.sessions.RevokeAllSessions(ctx, targetID)
+}
+
+// DeactivateUser блокирует вход, сохраняя все данные аккаунта.
+func (s *Service) DeactivateUser(ctx context.Context, targetID int, perms auth.Permissions) error {
+	if !perms.Has(auth.PermUserManage) {
+		return custom.ErrForbidden
+	}
+	if err := s.repo.UpdateStatus(ctx, targetID, []domain.UserStatus{domain.UserStatusActive}, domain.UserStatusDeactivated); err != nil {
+		return fmt.Errorf("failed to deactivate user: %w", err)
+	}
+	return s.sessions.RevokeAllSessions(ctx, targetID)
+}
+
+func (s *Service) ReactivateUser(ctx context.Context, targetID int, perms auth.Permissions) error {
+	if !perms.Has(auth.PermUserManage) {
+		return custom.ErrForbidden
+	}
+	if err := s.repo.UpdateStatus(ctx, targetID, []domain.UserStatus{domain.UserStatusDeactivated}, domain.UserStatusActive); err != nil {
+		return fmt.Errorf("failed to reactivate user: %w", err)
+	}
+	return nil
+}
+
+// RestoreUser возвращает мягко удалённый аккаунт, пока не истекло окно восстановления.
+func (s *Service) RestoreUser(ctx context.Context, targetID int, perms auth.Permissions) error {
+	if !perms.Has(auth.PermUserManage) {
+		return custom.ErrForbidden
+	}
+
+	userFromDB, err := s.repo.GetByID(ctx, targetID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if userFromDB.Status != domain.UserStatusDeleted || userFromDB.DeletedAt == nil {
+		return fmt.Errorf("user is not deleted: %w", custom.ErrConflict)
+	}
+	if time.Since(*userFromDB.DeletedAt) > s.restoreWindow {
+		return fmt.Errorf("restore window has expired: %w", custom.ErrConflict)
+	}
+
+	if err = s.repo.UpdateStatus(ctx, targetID, []domain.UserStatus{domain.UserStatusDeleted}, domain.UserStatusActive); err != nil {
+		return fmt.Errorf("failed to restore user: %w", err)
+	}
+	return nil
+}
+
+// EraseUser безвозвратно обезличивает аккаунт (право на забвение).
+func (s *Service) EraseUser(ctx context.Context, targetID int, perms auth.Permissions) error {
+	if !perms.Has(auth.PermUserManage) {
+		return custom.ErrForbidden
+	}
+	return s.erase(ctx, targetID)
+}
+
+// PurgeExpiredDeletions обезличивает аккаунты, окно восстановления которых истекло.
+// Возвращает число обработанных аккаунтов.
+func (s *Service) PurgeExpiredDeletions(ctx context.Context) (int, error) {
+	ids, err := s.repo.ListDeletedBefore(ctx, time.Now().Add(-s.restoreWindow))
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	erased := 0
+	for _, id := range ids {
+		if err = s.erase(ctx, id); err != nil {
+			errs = append(errs, fmt.Errorf("user %d: %w", id, err))
+			continue
+		}
+		erased++
+	}
+	return erased, errors.Join(errs...)
+}
+
+func (s *Service) erase(ctx context.Context, id int) error {
+	userFromDB, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if userFromDB.Status == domain.UserStatusErased {
+		return fmt.Errorf("user is already erased: %w", custom.ErrConflict)
+	}
+	if err = s.repo.Erase(ctx, id); err != nil {
+		return fmt.Errorf("failed to erase user: %w", err)
+	}
+	// Счётчики неудачных входов хранятся по email — это тоже персональные данные.
+	if err = s.accountLimiter.Reset(ctx, lockoutKey(ctx, userFromDB.Email)); err != nil {
+		return fmt.Errorf("failed to erase login attempts: %w", err)
+	}
 	return nil
 }
 
@@ -128,28 +345,319 @@ func (s *Service) GetUserByID(ctx context.Context, id int) (*domain.User, error)
 	return userFromDB, nil
 }
 
-func (s *Service) GetAllUsers(ctx context.Context) ([]domain.User, error) {
-	users, err := s.repo.GetAll(ctx)
+// ListUsers возвращает страницу справочника и общее число найденных пользователей.
+// Без фильтра по статусу обезличенные аккаунты не показываются.
+func (s *Service) ListUsers(ctx context.Context, filter domain.UserFilter) ([]domain.User, int, error) {
+	switch filter.Sort {
+	case "":
+		filter.Sort = domain.UserSortID
+	case domain.UserSortID, domain.UserSortUsername, domain.UserSortEmail, domain.UserSortCreatedAt:
+	default:
+		return nil, 0, fmt.Errorf("unknown sort field %q: %w", filter.Sort, custom.ErrInvalidInput)
+	}
+	for _, st := range filter.Statuses {
+		if !isKnownStatus(st) {
+			return nil, 0, fmt.Errorf("unknown status %q: %w", st, custom.ErrInvalidInput)
+		}
+	}
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = []domain.UserStatus{domain.UserStatusActive, domain.UserStatusDeactivated, domain.UserStatusDeleted}
+	}
+	if filter.Limit <= 0 || filter.Limit > maxPageSize {
+		return nil, 0, fmt.Errorf("limit must be between 1 and %d: %w", maxPageSize, custom.ErrInvalidInput)
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	users, total, err := s.repo.List(ctx, filter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get all users: %w", err)
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
 	}
-	return users, nil
+	return users, total, nil
 }
 
-func (s *Service) Login(ctx context.Context, email, password string) (string, error) {
+// BulkUpdateUsers применяет действие администратора к списку пользователей.
+// Собственный аккаунт пропускается, чтобы администратор не заблокировал сам себя.
+func (s *Service) BulkUpdateUsers(ctx context.Context, actorID int, perms auth.Permissions, input *dto.BulkUserActionInput) (*dto.BulkUserActionOutput, error) {
+	if !perms.Has(auth.PermUserManage) {
+		return nil, custom.ErrForbidden
+	}
+	if len(input.UserIDs) == 0 || len(input.UserIDs) > maxBulkSize {
+		return nil, fmt.Errorf("user_ids must contain 1 to %d ids: %w", maxBulkSize, custom.ErrInvalidInput)
+	}
+
+	out := &dto.BulkUserActionOutput{Results: make([]dto.BulkUserActionResult, 0, len(input.UserIDs))}
+	seen := make(map[int]bool, len(input.UserIDs))
+	ids := make([]int, 0, len(input.UserIDs))
+	for _, id := range input.UserIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if id == actorID {
+			out.Results = append(out.Results, dto.BulkUserActionResult{UserID: id, Status: dto.BulkStatusSkipped, Error: "cannot apply bulk action to own account"})
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	var (
+		updated []int
+		err     error
+	)
+	switch input.Action {
+	case dto.BulkActionChangeRole:
+		if !perms.Has(auth.PermRoleManage) {
+			return nil, custom.ErrForbidden
+		}
+		if input.Role == nil || *input.Role == "" {
+			return nil, fmt.Errorf("role is required: %w", custom.ErrInvalidInput)
+		}
+		updated, err = s.repo.UpdateRoleBulk(ctx, ids, *input.Role)
+	case dto.BulkActionDeactivate:
+		updated, err = s.repo.UpdateStatusBulk(ctx, ids, []domain.UserStatus{domain.UserStatusActive}, domain.UserStatusDeactivated)
+	default:
+		return nil, fmt.Errorf("unknown action %q: %w", input.Action, custom.ErrInvalidInput)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply bulk action: %w", err)
+	}
+
+	done := make(map[int]bool, len(updated))
+	for _, id := range updated {
+		done[id] = true
+	}
+	for _, id := range ids {
+		if !done[id] {
+			out.Results = append(out.Results, dto.BulkUserActionResult{UserID: id, Status: dto.BulkStatusSkipped, Error: "user not found or not eligible"})
+			continue
+		}
+		// Обе операции должны подействовать сразу, а не когда истекут выданные токены
+		result := dto.BulkUserActionResult{UserID: id, Status: dto.BulkStatusUpdated}
+		if err = s.sessions.RevokeAllSessions(ctx, id); err != nil {
+			result.Error = "updated, but failed to revoke sessions: " + err.Error()
+		}
+		out.Updated++
+		out.Results = append(out.Results, result)
+	}
+	return out, nil
+}
+
+func isKnownStatus(st domain.UserStatus) bool {
+	switch st {
+	case domain.UserStatusActive, domain.UserStatusDeactivated, domain.UserStatusDeleted, domain.UserStatusErased:
+		return true
+	}
+	return false
+}
+
+func (s *Service) Login(ctx context.Context, email, password string, client dto.ClientInfo) (*dto.LoginOutput, error) {
+	accountKey := lockoutKey(ctx, email)
+	ip := client.IP
+
+	if err := s.checkLoginAllowed(ctx, accountKey, ip); err != nil {
+		return nil, err
+	}
+
 	pwHash, id, role, err := s.repo.GetUserCredsAndRoleByEmail(ctx, email)
+	if err != nil && !errors.Is(err, custom.ErrNotFound) {
+		return nil, fmt.Errorf("failed to check user exists: %w", err)
+	}
+
+	if err != nil {
+		// Неизвестный email проверяется так же долго, как известный: по времени
+		// ответа нельзя узнать, зарегистрирован ли адрес
+		pwHash = s.dummyHash()
+	}
+	if s.hasher.Compare(pwHash, password) != nil || err != nil {
+		if err = s.registerLoginFailure(ctx, accountKey, ip); err != nil {
+			return nil, err
+		}
+		return nil, custom.ErrUnauthorized
+	}
+
+	s.upgradePasswordHash(ctx, id, pwHash, password)
+
+	out, err := s.completeLogin(ctx, id, role, client)
 	if err != nil {
-		return "", fmt.Errorf("failed to check user exists: %w", err)
+		return nil, err
+	}
+
+	// Счётчики сбрасываем только после полного входа, иначе подбор TOTP-кода
+	// можно было бы чередовать с вводом верного пароля.
+	if out.Token != "" {
+		if err = s.resetLoginAttempts(ctx, accountKey); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// dummyHash — хэш текущим хэшером и его параметрами, с которым сравнивается пароль
+// при неизвестном email. Совпадение с ним ничего не даёт: вход всё равно отклоняется.
+func (s *Service) dummyHash() string {
+	s.dummyOnce.Do(func() {
+		s.dummy, _ = s.hasher.Hash("dummy password")
+	})
+	return s.dummy
+}
+
+// upgradePasswordHash перехэширует пароль, если хэш сделан устаревшим алгоритмом
+// или параметрами. Вызывается только после успешной проверки пароля; ошибка
+// не мешает входу — хэш обновится при следующем входе.
+func (s *Service) upgradePasswordHash(ctx context.Context, id int, pwHash, password string) {
+	rh, ok := s.hasher.(Rehasher)
+	if !ok || !rh.NeedsRehash(pwHash) {
+		return
+	}
+	newHash, err := s.hasher.Hash(password)
+	if err != nil {
+		return
+	}
+	_ = s.repo.UpdatePasswordHash(ctx, id, newHash)
+}
+
+// CompleteExternalLogin выдаёт токен пользователю, уже аутентифицированному
+// внешним провайдером (SSO). Требования 2FA при этом сохраняются.
+func (s *Service) CompleteExternalLogin(ctx context.Context, userID int, client dto.ClientInfo) (*dto.LoginOutput, error) {
+	userFromDB, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if userFromDB.Status != domain.UserStatusActive {
+		return nil, custom.ErrUnauthorized
+	}
+	return s.completeLogin(ctx, userFromDB.ID, userFromDB.Role, client)
+}
+
+func (s *Service) completeLogin(ctx context.Context, id int, role auth.Role, client dto.ClientInfo) (*dto.LoginOutput, error) {
+	perms, err := s.roleRepo.GetPermissions(ctx, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role permissions: %w", err)
+	}
+
+	tf, err := s.twoFactorState(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tf.Enabled || s.twoFactorRequired(tf, perms) {
+		tenantID, err := tenantFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		challenge, err := s.jwtSvc.GenerateChallengeToken(id, tenantID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate challenge token: %w", err)
+		}
+		return &dto.LoginOutput{ChallengeToken: challenge, TwoFactorRequired: true, EnrollmentRequired: !tf.Enabled}, nil
 	}
 
-	err = s.hasher.Compare(pwHash, password)
+	token, err := s.issueToken(ctx, id, role, perms, client)
 	if err != nil {
-		return "", custom.ErrUnauthorized
+		return nil, err
 	}
+	return &dto.LoginOutput{Token: token}, nil
+}
 
-	token, err := s.jwtSvc.GenerateToken(id, role)
+// issueToken открывает новую сессию и выдаёт привязанный к ней access-токен.
+func (s *Service) issueToken(ctx context.Context, id int, role auth.Role, perms auth.Permissions, client dto.ClientInfo) (string, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	sessionID, err := s.sessions.StartSession(ctx, id, client)
+	if err != nil {
+		return "", err
+	}
+	token, err := s.jwtSvc.GenerateToken(id, tenantID, role, perms, sessionID)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate token: %w", err)
 	}
 	return token, nil
 }
+
+func (s *Service) UnlockUser(ctx context.Context, targetID int, perms auth.Permissions) error {
+	if !perms.Has(auth.PermUserManage) {
+		return custom.ErrForbidden
+	}
+
+	userFromDB, err := s.repo.GetByID(ctx, targetID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	err = s.accountLimiter.Reset(ctx, lockoutKey(ctx, userFromDB.Email))
+	if err != nil {
+		return fmt.Errorf("failed to unlock user: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) checkLoginAllowed(ctx context.Context, accountKey, ip string) error {
+	accountWait, err := s.accountLimiter.RetryAfter(ctx, accountKey)
+	if err != nil {
+		return fmt.Errorf("failed to check login attempts: %w", err)
+	}
+	ipWait, err := s.ipLimiter.RetryAfter(ctx, ip)
+	if err != nil {
+		return fmt.Errorf("failed to check login attempts: %w", err)
+	}
+
+	wait := max(accountWait, ipWait)
+	if wait > 0 {
+		return &custom.RetryAfterError{RetryAfter: wait}
+	}
+	return nil
+}
+
+func (s *Service) registerLoginFailure(ctx context.Context, accountKey, ip string) error {
+	if err := s.accountLimiter.RegisterFailure(ctx, accountKey); err != nil {
+		return fmt.Errorf("failed to register login failure: %w", err)
+	}
+	if err := s.ipLimiter.RegisterFailure(ctx, ip); err != nil {
+		return fmt.Errorf("failed to register login failure: %w", err)
+	}
+	return nil
+}
+
+// resetLoginAttempts сбрасывает только счётчик аккаунта. Счётчик IP истекает сам
+// по окну: иначе с одного адреса можно было бы перебирать чужие пароли бесконечно,
+// время от времени входя в свой аккаунт.
+func (s *Service) resetLoginAttempts(ctx context.Context, accountKey string) error {
+	if err := s.accountLimiter.Reset(ctx, accountKey); err != nil {
+		return fmt.Errorf("failed to reset login attempts: %w", err)
+	}
+	return nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// lockoutKey — ключ счётчика неудачных входов по аккаунту. Один email может
+// быть зарегистрирован в нескольких каталогах, поэтому ключ включает арендатора.
+func lockoutKey(ctx context.Context, email string) string {
+	tenantID, _ := auth.TenantIDFromContext(ctx)
+	return strconv.Itoa(tenantID) + ":" + normalizeEmail(email)
+}
+
+func tenantFromContext(ctx context.Context) (int, error) {
+	tenantID, ok := auth.TenantIDFromContext(ctx)
+	if !ok {
+		return 0, errors.New("tenant is not set in context")
+	}
+	return tenantID, nil
+}
+
+// challengeUser разбирает challenge-токен и проверяет, что он выдан в каталоге текущего запроса.
+func (s *Service) challengeUser(ctx context.Context, challengeToken string) (int, error) {
+	userID, tenantID, err := s.jwtSvc.ParseChallengeToken(challengeToken)
+	if err != nil {
+		return 0, custom.ErrUnauthorized
+	}
+	if current, ok := auth.TenantIDFromContext(ctx); !ok || current != tenantID {
+		return 0, custom.ErrUnauthorized
+	}
+	return userID, nil
+}
@@ -0,0 +1,658 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	"product-catalog/internal/dto"
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/service/user"
+)
+
+type fakeRepo struct {
+	users map[int]*domain.User
+}
+
+func newFakeRepo(users ...domain.User) *fakeRepo {
+	r := &fakeRepo{users: map[int]*domain.User{}}
+	for i := range users {
+		u := users[i]
+		r.users[u.ID] = &u
+	}
+	return r
+}
+
+func (r *fakeRepo) Create(_ context.Context, u *domain.User) error {
+	u.ID = len(r.users) + 1
+	cp := *u
+	r.users[u.ID] = &cp
+	return nil
+}
+
+func (r *fakeRepo) GetByID(_ context.Context, id int) (*domain.User, error) {
+	u, ok := r.users[id]
+	if !ok {
+		return nil, custom.ErrNotFound
+	}
+	cp := *u
+	return &cp, nil
+}
+
+func (r *fakeRepo) Patch(_ context.Context, id, version int, patch domain.UserPatch) error {
+	u, ok := r.users[id]
+	if !ok {
+		return custom.ErrNotFound
+	}
+	if u.Version != version {
+		return custom.ErrPreconditionFailed
+	}
+	u.Version++
+	if patch.Username != nil {
+		u.Username = *patch.Username
+	}
+	if patch.Email != nil {
+		u.Email = *patch.Email
+	}
+	if patch.Role != nil {
+		u.Role = *patch.Role
+	}
+	if patch.PasswordHash != nil {
+		u.PasswordHash = *patch.PasswordHash
+	}
+	return nil
+}
+
+func (r *fakeRepo) UpdateStatus(_ context.Context, id int, from []domain.UserStatus, to domain.UserStatus) error {
+	u, ok := r.users[id]
+	if !ok {
+		return custom.ErrNotFound
+	}
+	for _, st := range from {
+		if u.Status == st {
+			u.Status = to
+			u.DeletedAt = nil
+			if to == domain.UserStatusDeleted {
+				now := time.Now()
+				u.DeletedAt = &now
+			}
+			return nil
+		}
+	}
+	return custom.ErrConflict
+}
+
+func (r *fakeRepo) DeleteByID(ctx context.Context, id, version int) error {
+	u, ok := r.users[id]
+	if !ok {
+		return custom.ErrNotFound
+	}
+	if u.Version != version {
+		return custom.ErrPreconditionFailed
+	}
+	from := []domain.UserStatus{domain.UserStatusActive, domain.UserStatusDeactivated}
+	if err := r.UpdateStatus(ctx, id, from, domain.UserStatusDeleted); err != nil {
+		return err
+	}
+	u.Version++
+	return nil
+}
+
+func (r *fakeRepo) Erase(_ context.Context, id int) error {
+	u := r.users[id]
+	u.Username, u.Email, u.PasswordHash, u.Status = "", "", "", domain.UserStatusErased
+	return nil
+}
+
+func (r *fakeRepo) ListDeletedBefore(_ context.Context, before time.Time) ([]int, error) {
+	var ids []int
+	for _, u := range r.users {
+		if u.Status == domain.UserStatusDeleted && u.DeletedAt.Before(before) {
+			ids = append(ids, u.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *fakeRepo) List(_ context.Context, f domain.UserFilter) ([]domain.User, int, error) {
+	var res []domain.User
+	for _, u := range r.users {
+		if slices.Contains(f.Statuses, u.Status) {
+			res = append(res, *u)
+		}
+	}
+	return res, len(res), nil
+}
+
+func (r *fakeRepo) UpdateRoleBulk(_ context.Context, ids []int, role auth.Role) ([]int, error) {
+	var updated []int
+	for _, id := range ids {
+		if u, ok := r.users[id]; ok && u.Status != domain.UserStatusErased {
+			u.Role = role
+			updated = append(updated, id)
+		}
+	}
+	return updated, nil
+}
+
+func (r *fakeRepo) UpdateStatusBulk(_ context.Context, ids []int, from []domain.UserStatus, to domain.UserStatus) ([]int, error) {
+	var updated []int
+	for _, id := range ids {
+		if u, ok := r.users[id]; ok && slices.Contains(from, u.Status) {
+			u.Status = to
+			updated = append(updated, id)
+		}
+	}
+	return updated, nil
+}
+
+func (r *fakeRepo) ExistsByEmailOrUsername(_ context.Context, email, username string) (bool, error) {
+	for _, u := range r.users {
+		if u.Email == email || u.Username == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRepo) GetUserCredsAndRoleByEmail(_ context.Context, email string) (string, int, auth.Role, error) {
+	for _, u := range r.users {
+		if u.Email == email {
+			return u.PasswordHash, u.ID, u.Role, nil
+		}
+	}
+	return "", 0, "", custom.ErrNotFound
+}
+
+func (r *fakeRepo) UpdatePasswordHash(_ context.Context, id int, passwordHash string) error {
+	r.users[id].PasswordHash = passwordHash
+	return nil
+}
+
+type fakeHasher struct{}
+
+func (fakeHasher) Hash(password string) (string, error) { return "hash:" + password, nil }
+
+func (fakeHasher) Compare(hashedPassword, password string) error {
+	if strings.TrimPrefix(hashedPassword, "hash:") != password {
+		return errors.New("mismatch")
+	}
+	return nil
+}
+
+type fakeSessions struct {
+	revoked map[int]bool
+}
+
+func (s *fakeSessions) StartSession(context.Context, int, dto.ClientInfo) (string, error) {
+	return "sid", nil
+}
+
+func (s *fakeSessions) RevokeAllSessions(_ context.Context, userID int) error {
+	s.revoked[userID] = true
+	return nil
+}
+
+func ptr(s string) *string { return &s }
+
+type fakeRoles struct{}
+
+func (fakeRoles) GetPermissions(context.Context, auth.Role) (auth.Permissions, error) {
+	return nil, nil
+}
+
+// fakeTwoFactor — 2FA ни у кого не настроена
+type fakeTwoFactor struct {
+	user.TwoFactorRepository
+}
+
+func (fakeTwoFactor) GetByUserID(context.Context, int) (*domain.TwoFactor, error) {
+	return nil, custom.ErrNotFound
+}
+
+type fakeJWT struct {
+	user.JwtService
+}
+
+func (fakeJWT) GenerateToken(int, int, auth.Role, auth.Permissions, string) (string, error) {
+	return "token", nil
+}
+
+// newLoginService собирает сервис для входа с отдельными счётчиками аккаунта и IP
+func newLoginService(repo *fakeRepo, hasher user.Hasher) (*user.Service, *auth.MemoryLimiter) {
+	policy := auth.LockoutPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour}
+	ipLimiter := auth.NewMemoryLimiter(policy)
+	svc := user.NewUserService(repo, fakeRoles{}, hasher, fakeJWT{}, auth.NewMemoryLimiter(policy), ipLimiter,
+		fakeTwoFactor{}, user.TwoFactorConfig{}, &fakeSessions{revoked: map[int]bool{}}, nil, time.Hour)
+	return svc, ipLimiter
+}
+
+func TestUpdateOwnCredentialsRequiresCurrentPassword(t *testing.T) {
+	tests := []struct {
+		name        string
+		requesterID int
+		perms       auth.Permissions
+		input       dto.UpdateUserInput
+		wantErr     error
+	}{
+		{name: "username without password", requesterID: 1, input: dto.UpdateUserInput{Username: ptr("renamed")}},
+		{name: "same email without password", requesterID: 1, input: dto.UpdateUserInput{Email: ptr("ALICE@example.com")}},
+		{name: "email without password", requesterID: 1, input: dto.UpdateUserInput{Email: ptr("new@example.com")}, wantErr: custom.ErrInvalidInput},
+		{name: "password with wrong current", requesterID: 1, input: dto.UpdateUserInput{Password: ptr("newpass"), CurrentPassword: ptr("wrong")}, wantErr: custom.ErrForbidden},
+		{name: "password with current", requesterID: 1, input: dto.UpdateUserInput{Password: ptr("newpass"), CurrentPassword: ptr("secret")}},
+		{name: "blocklisted password", requesterID: 1, input: dto.UpdateUserInput{Password: ptr("password1"), CurrentPassword: ptr("secret")}, wantErr: custom.ErrInvalidInput},
+		{name: "too short password", requesterID: 1, input: dto.UpdateUserInput{Password: ptr("short"), CurrentPassword: ptr("secret")}, wantErr: custom.ErrInvalidInput},
+		{name: "manager changes other user", requesterID: 2, perms: auth.Permissions{auth.PermUserManage}, input: dto.UpdateUserInput{Email: ptr("new@example.com")}},
+		{name: "other user without permission", requesterID: 2, input: dto.UpdateUserInput{Username: ptr("renamed")}, wantErr: custom.ErrForbidden},
+	}
+
+	policy, _ := auth.NewPasswordPolicy(6, 64, "")
+	policy.Block("password1")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepo(domain.User{ID: 1, Username: "alice", Email: "alice@example.com", PasswordHash: "hash:secret", Role: auth.RoleUser, Version: 1})
+			svc := user.NewUserService(repo, nil, fakeHasher{}, nil, nil, nil, nil, user.TwoFactorConfig{}, nil, policy, time.Hour)
+
+			err := svc.UpdateUserByID(context.Background(), tt.requesterID, 1, 1, &tt.input, tt.perms)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && repo.users[1].PasswordHash == "" {
+				t.Fatal("password hash was wiped")
+			}
+		})
+	}
+}
+
+func TestUpdateUserRequiresCurrentVersion(t *testing.T) {
+	repo := newFakeRepo(domain.User{ID: 1, Username: "alice", Email: "alice@example.com", PasswordHash: "hash:secret", Role: auth.RoleUser, Version: 2})
+	svc := user.NewUserService(repo, nil, fakeHasher{}, nil, nil, nil, nil, user.TwoFactorConfig{}, nil, nil, time.Hour)
+
+	err := svc.UpdateUserByID(context.Background(), 1, 1, 1, &dto.UpdateUserInput{Username: ptr("alicia")}, nil)
+	if !errors.Is(err, custom.ErrPreconditionFailed) {
+		t.Fatalf("err = %v; want ErrPreconditionFailed", err)
+	}
+	if u := repo.users[1]; u.Username != "alice" || u.Version != 2 {
+		t.Fatalf("after stale update: %+v; want unchanged", u)
+	}
+}
+
+func TestPatchUserAuthorizesBeforeApply(t *testing.T) {
+	repo := newFakeRepo(domain.User{ID: 1, Username: "alice", Email: "alice@example.com", PasswordHash: "hash:secret", Role: auth.RoleUser})
+	svc := user.NewUserService(repo, nil, fakeHasher{}, nil, nil, nil, nil, user.TwoFactorConfig{}, nil, nil, time.Hour)
+
+	called := false
+	err := svc.PatchUserByID(context.Background(), 2, 1, nil, func(*domain.User) (*dto.UpdateUserInput, error) {
+		called = true
+		return &dto.UpdateUserInput{}, nil
+	})
+	if !errors.Is(err, custom.ErrForbidden) || called {
+		t.Fatalf("err = %v, apply called = %v; want ErrForbidden before apply", err, called)
+	}
+
+	err = svc.PatchUserByID(context.Background(), 1, 1, nil, func(cur *domain.User) (*dto.UpdateUserInput, error) {
+		return &dto.UpdateUserInput{Username: ptr("alicia")}, nil
+	})
+	if err != nil {
+		t.Fatalf("patch username: %v", err)
+	}
+	if u := repo.users[1]; u.Username != "alicia" || u.Email != "alice@example.com" || u.PasswordHash != "hash:secret" {
+		t.Fatalf("after patch: %+v; only username must change", u)
+	}
+}
+
+func TestAccountStatusTransitions(t *testing.T) {
+	manager := auth.Permissions{auth.PermUserManage}
+	expired := time.Now().Add(-2 * time.Hour)
+	recent := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name        string
+		user        domain.User
+		action      func(svc *user.Service) error
+		wantErr     error
+		wantStatus  domain.UserStatus
+		wantRevoked bool
+	}{
+		{
+			name:        "deactivate active user",
+			user:        domain.User{Status: domain.UserStatusActive},
+			action:      func(svc *user.Service) error { return svc.DeactivateUser(context.Background(), 1, manager) },
+			wantStatus:  domain.UserStatusDeactivated,
+			wantRevoked: true,
+		},
+		{
+			name:       "deactivate without permission",
+			user:       domain.User{Status: domain.UserStatusActive},
+			action:     func(svc *user.Service) error { return svc.DeactivateUser(context.Background(), 1, nil) },
+			wantErr:    custom.ErrForbidden,
+			wantStatus: domain.UserStatusActive,
+		},
+		{
+			name:       "reactivate deactivated user",
+			user:       domain.User{Status: domain.UserStatusDeactivated},
+			action:     func(svc *user.Service) error { return svc.ReactivateUser(context.Background(), 1, manager) },
+			wantStatus: domain.UserStatusActive,
+		},
+		{
+			name:       "reactivate deleted user",
+			user:       domain.User{Status: domain.UserStatusDeleted, DeletedAt: &recent},
+			action:     func(svc *user.Service) error { return svc.ReactivateUser(context.Background(), 1, manager) },
+			wantErr:    custom.ErrConflict,
+			wantStatus: domain.UserStatusDeleted,
+		},
+		{
+			name:        "self delete",
+			user:        domain.User{Status: domain.UserStatusActive},
+			action:      func(svc *user.Service) error { return svc.DeleteUserByID(context.Background(), 1, 1, 0, nil) },
+			wantStatus:  domain.UserStatusDeleted,
+			wantRevoked: true,
+		},
+		{
+			name:       "delete with stale version",
+			user:       domain.User{Status: domain.UserStatusActive, Version: 2},
+			action:     func(svc *user.Service) error { return svc.DeleteUserByID(context.Background(), 1, 1, 1, nil) },
+			wantErr:    custom.ErrPreconditionFailed,
+			wantStatus: domain.UserStatusActive,
+		},
+		{
+			name:       "delete already deleted",
+			user:       domain.User{Status: domain.UserStatusDeleted, DeletedAt: &recent},
+			action:     func(svc *user.Service) error { return svc.DeleteUserByID(context.Background(), 1, 1, 0, nil) },
+			wantErr:    custom.ErrConflict,
+			wantStatus: domain.UserStatusDeleted,
+		},
+		{
+			name:       "delete other user without permission",
+			user:       domain.User{Status: domain.UserStatusActive},
+			action:     func(svc *user.Service) error { return svc.DeleteUserByID(context.Background(), 2, 1, 0, nil) },
+			wantErr:    custom.ErrForbidden,
+			wantStatus: domain.UserStatusActive,
+		},
+		{
+			name:       "restore within window",
+			user:       domain.User{Status: domain.UserStatusDeleted, DeletedAt: &recent},
+			action:     func(svc *user.Service) error { return svc.RestoreUser(context.Background(), 1, manager) },
+			wantStatus: domain.UserStatusActive,
+		},
+		{
+			name:       "restore after window",
+			user:       domain.User{Status: domain.UserStatusDeleted, DeletedAt: &expired},
+			action:     func(svc *user.Service) error { return svc.RestoreUser(context.Background(), 1, manager) },
+			wantErr:    custom.ErrConflict,
+			wantStatus: domain.UserStatusDeleted,
+		},
+		{
+			name:       "erase",
+			user:       domain.User{Status: domain.UserStatusDeactivated},
+			action:     func(svc *user.Service) error { return svc.EraseUser(context.Background(), 1, manager) },
+			wantStatus: domain.UserStatusErased,
+		},
+		{
+			name:       "erase twice",
+			user:       domain.User{Status: domain.UserStatusErased},
+			action:     func(svc *user.Service) error { return svc.EraseUser(context.Background(), 1, manager) },
+			wantErr:    custom.ErrConflict,
+			wantStatus: domain.UserStatusErased,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.user.ID, tt.user.Email = 1, "alice@example.com"
+			repo := newFakeRepo(tt.user)
+			sessions := &fakeSessions{revoked: map[int]bool{}}
+			limiter := auth.NewMemoryLimiter(auth.LockoutPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: time.Minute, Window: time.Minute})
+			svc := user.NewUserService(repo, nil, fakeHasher{}, nil, limiter, limiter, nil, user.TwoFactorConfig{}, sessions, nil, time.Hour)
+
+			err := tt.action(svc)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got := repo.users[1].Status; got != tt.wantStatus {
+				t.Fatalf("status = %q, want %q", got, tt.wantStatus)
+			}
+			if sessions.revoked[1] != tt.wantRevoked {
+				t.Fatalf("sessions revoked = %v, want %v", sessions.revoked[1], tt.wantRevoked)
+			}
+		})
+	}
+}
+
+func TestPurgeExpiredDeletions(t *testing.T) {
+	expired := time.Now().Add(-2 * time.Hour)
+	recent := time.Now().Add(-time.Minute)
+	repo := newFakeRepo(
+		domain.User{ID: 1, Email: "old@example.com", Status: domain.UserStatusDeleted, DeletedAt: &expired},
+		domain.User{ID: 2, Email: "new@example.com", Status: domain.UserStatusDeleted, DeletedAt: &recent},
+		domain.User{ID: 3, Email: "live@example.com", Status: domain.UserStatusActive},
+	)
+	limiter := auth.NewMemoryLimiter(auth.LockoutPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: time.Minute, Window: time.Minute})
+	svc := user.NewUserService(repo, nil, fakeHasher{}, nil, limiter, limiter, nil, user.TwoFactorConfig{}, nil, nil, time.Hour)
+
+	n, err := svc.PurgeExpiredDeletions(context.Background())
+	if err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("erased = %d, want 1", n)
+	}
+	want := map[int]domain.UserStatus{1: domain.UserStatusErased, 2: domain.UserStatusDeleted, 3: domain.UserStatusActive}
+	for id, status := range want {
+		if repo.users[id].Status != status {
+			t.Errorf("user %d status = %q, want %q", id, repo.users[id].Status, status)
+		}
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	repo := newFakeRepo(
+		domain.User{ID: 1, Status: domain.UserStatusActive},
+		domain.User{ID: 2, Status: domain.UserStatusDeactivated},
+		domain.User{ID: 3, Status: domain.UserStatusErased},
+	)
+	svc := user.NewUserService(repo, nil, fakeHasher{}, nil, nil, nil, nil, user.TwoFactorConfig{}, nil, nil, time.Hour)
+
+	users, total, err := svc.ListUsers(context.Background(), domain.UserFilter{Limit: 50})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if total != 2 || len(users) != 2 {
+		t.Fatalf("total = %d, want 2: erased users must be hidden by default", total)
+	}
+
+	_, total, err = svc.ListUsers(context.Background(), domain.UserFilter{Limit: 50, Statuses: []domain.UserStatus{domain.UserStatusErased}})
+	if err != nil || total != 1 {
+		t.Fatalf("erased filter: total = %d, err = %v", total, err)
+	}
+
+	invalid := []domain.UserFilter{
+		{Limit: 50, Sort: "password_hash"},
+		{Limit: 50, Statuses: []domain.UserStatus{"banned"}},
+		{Limit: 1000},
+	}
+	for _, f := range invalid {
+		if _, _, err = svc.ListUsers(context.Background(), f); !errors.Is(err, custom.ErrInvalidInput) {
+			t.Errorf("filter %+v: err = %v, want ErrInvalidInput", f, err)
+		}
+	}
+}
+
+func TestPatchUserRoleRevokesSessions(t *testing.T) {
+	editor, same := auth.Role("editor"), auth.RoleUser
+	admin := auth.Permissions{auth.PermUserManage, auth.PermRoleManage}
+
+	tests := []struct {
+		name        string
+		input       dto.UpdateUserInput
+		wantRevoked bool
+	}{
+		{name: "role change", input: dto.UpdateUserInput{Role: &editor}, wantRevoked: true},
+		{name: "same role", input: dto.UpdateUserInput{Role: &same}},
+		{name: "username only", input: dto.UpdateUserInput{Username: ptr("renamed")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepo(domain.User{ID: 1, Username: "alice", Email: "alice@example.com", PasswordHash: "hash:secret", Role: auth.RoleUser, Version: 1})
+			sessions := &fakeSessions{revoked: map[int]bool{}}
+			svc := user.NewUserService(repo, nil, fakeHasher{}, nil, nil, nil, nil, user.TwoFactorConfig{}, sessions, nil, time.Hour)
+
+			if err := svc.UpdateUserByID(context.Background(), 2, 1, 1, &tt.input, admin); err != nil {
+				t.Fatalf("update: %v", err)
+			}
+			if sessions.revoked[1] != tt.wantRevoked {
+				t.Fatalf("sessions revoked = %v, want %v", sessions.revoked[1], tt.wantRevoked)
+			}
+		})
+	}
+}
+
+func TestBulkUpdateUsers(t *testing.T) {
+	manager := auth.Permissions{auth.PermUserManage}
+	role := auth.Role("editor")
+
+	tests := []struct {
+		name        string
+		perms       auth.Permissions
+		input       dto.BulkUserActionInput
+		wantErr     error
+		wantUpdated int
+		wantRevoked []int
+	}{
+		{
+			name:        "deactivate skips self and ineligible users",
+			perms:       manager,
+			input:       dto.BulkUserActionInput{Action: dto.BulkActionDeactivate, UserIDs: []int{1, 2, 2, 3, 99}},
+			wantUpdated: 1,
+			wantRevoked: []int{2},
+		},
+		{
+			name:        "change role",
+			perms:       auth.Permissions{auth.PermUserManage, auth.PermRoleManage},
+			input:       dto.BulkUserActionInput{Action: dto.BulkActionChangeRole, UserIDs: []int{2, 3}, Role: &role},
+			wantUpdated: 2,
+			wantRevoked: []int{2, 3},
+		},
+		{
+			name:    "change role without role:manage",
+			perms:   manager,
+			input:   dto.BulkUserActionInput{Action: dto.BulkActionChangeRole, UserIDs: []int{2}, Role: &role},
+			wantErr: custom.ErrForbidden,
+		},
+		{
+			name:    "without user:manage",
+			perms:   auth.Permissions{auth.PermUserRead},
+			input:   dto.BulkUserActionInput{Action: dto.BulkActionDeactivate, UserIDs: []int{2}},
+			wantErr: custom.ErrForbidden,
+		},
+		{
+			name:    "unknown action",
+			perms:   manager,
+			input:   dto.BulkUserActionInput{Action: "delete", UserIDs: []int{2}},
+			wantErr: custom.ErrInvalidInput,
+		},
+		{
+			name:    "empty ids",
+			perms:   manager,
+			input:   dto.BulkUserActionInput{Action: dto.BulkActionDeactivate},
+			wantErr: custom.ErrInvalidInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepo(
+				domain.User{ID: 1, Status: domain.UserStatusActive},
+				domain.User{ID: 2, Status: domain.UserStatusActive},
+				domain.User{ID: 3, Status: domain.UserStatusDeactivated},
+			)
+			sessions := &fakeSessions{revoked: map[int]bool{}}
+			svc := user.NewUserService(repo, nil, fakeHasher{}, nil, nil, nil, nil, user.TwoFactorConfig{}, sessions, nil, time.Hour)
+
+			out, err := svc.BulkUpdateUsers(context.Background(), 1, tt.perms, &tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if out.Updated != tt.wantUpdated {
+				t.Fatalf("updated = %d, want %d (results %+v)", out.Updated, tt.wantUpdated, out.Results)
+			}
+			if len(sessions.revoked) != len(tt.wantRevoked) {
+				t.Fatalf("revoked sessions of %v, want %v", sessions.revoked, tt.wantRevoked)
+			}
+			for _, id := range tt.wantRevoked {
+				if !sessions.revoked[id] {
+					t.Errorf("sessions of user %d were not revoked", id)
+				}
+			}
+			if repo.users[1].Status != domain.UserStatusActive || repo.users[1].Role != "" {
+				t.Error("bulk action changed the actor's own account")
+			}
+		})
+	}
+}
+
+func TestLoginKeepsIPFailures(t *testing.T) {
+	repo := newFakeRepo(
+		domain.User{ID: 1, Email: "victim@example.com", PasswordHash: "hash:secret", Status: domain.UserStatusActive},
+		domain.User{ID: 2, Email: "own@example.com", PasswordHash: "hash:mine", Status: domain.UserStatusActive},
+	)
+	svc, ipLimiter := newLoginService(repo, fakeHasher{})
+	ctx := auth.WithTenant(context.Background(), 1)
+	client := dto.ClientInfo{IP: "10.0.0.1"}
+
+	for range 2 {
+		if _, err := svc.Login(ctx, "victim@example.com", "guess", client); !errors.Is(err, custom.ErrUnauthorized) {
+			t.Fatalf("wrong password err = %v, want ErrUnauthorized", err)
+		}
+	}
+	// Вход в свой аккаунт с того же адреса не обнуляет неудачи по IP
+	if out, err := svc.Login(ctx, "own@example.com", "mine", client); err != nil || out.Token == "" {
+		t.Fatalf("own login = %+v, %v", out, err)
+	}
+	_, _ = svc.Login(ctx, "victim@example.com", "guess", client)
+	if d, _ := ipLimiter.RetryAfter(ctx, client.IP); d <= 0 {
+		t.Fatal("expected the IP to be locked after three failures despite a successful login in between")
+	}
+}
+
+// countingHasher считает сравнения паролей
+type countingHasher struct {
+	fakeHasher
+	compares int
+}
+
+func (h *countingHasher) Compare(hashedPassword, password string) error {
+	h.compares++
+	return h.fakeHasher.Compare(hashedPassword, password)
+}
+
+func TestLoginUnknownEmailComparesHash(t *testing.T) {
+	repo := newFakeRepo(domain.User{ID: 1, Email: "alice@example.com", PasswordHash: "hash:secret", Status: domain.UserStatusActive})
+	hasher := &countingHasher{}
+	svc, _ := newLoginService(repo, hasher)
+	ctx := auth.WithTenant(context.Background(), 1)
+
+	for _, email := range []string{"alice@example.com", "nobody@example.com"} {
+		hasher.compares = 0
+		if _, err := svc.Login(ctx, email, "guess", dto.ClientInfo{IP: "10.0.0.1"}); !errors.Is(err, custom.ErrUnauthorized) {
+			t.Fatalf("%s: err = %v, want ErrUnauthorized", email, err)
+		}
+		if hasher.compares != 1 {
+			t.Errorf("%s: compares = %d, want 1 so both cases take the same time", email, hasher.compares)
+		}
+	}
+	// Пароль, из которого сделан служебный хэш, не открывает вход по неизвестному email
+	if _, err := svc.Login(ctx, "nobody@example.com", "dummy password", dto.ClientInfo{IP: "10.0.0.2"}); !errors.Is(err, custom.ErrUnauthorized) {
+		t.Errorf("dummy password err = %v, want ErrUnauthorized", err)
+	}
+}
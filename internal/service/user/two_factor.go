@@ -0,0 +1,279 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	"product-catalog/internal/dto"
+	custom "product-catalog/internal/errors"
+	"time"
+)
+
+type TwoFactorRepository interface {
+	GetByUserID(ctx context.Context, userID int) (*domain.TwoFactor, error)
+	SaveSecret(ctx context.Context, userID int, secret string) error
+	Enable(ctx context.Context, userID int) error
+	Disable(ctx context.Context, userID int) error
+	SetRequired(ctx context.Context, userID int, required bool) error
+	MarkStepUsed(ctx context.Context, userID int, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID int, hashes []string) error
+	UseRecoveryCode(ctx context.Context, userID int, hash string) (bool, error)
+}
+
+type TwoFactorConfig struct {
+	Issuer           string
+	RequireForAdmins bool
+}
+
+// VerifyTwoFactor завершает двухшаговый вход: обменивает challenge-токен и код
+// (TOTP или резервный) на обычный access-токен. Если 2FA обязательна, но ещё
+// не подтверждена, успешный код одновременно завершает подключение.
+func (s *Service) VerifyTwoFactor(ctx context.Context, input *dto.TwoFactorLoginInput, client dto.ClientInfo) (*dto.TwoFactorLoginOutput, error) {
+	ip := client.IP
+	userID, err := s.challengeUser(ctx, input.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	userFromDB, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if userFromDB.Status != domain.UserStatusActive {
+		return nil, custom.ErrUnauthorized
+	}
+	accountKey := lockoutKey(ctx, userFromDB.Email)
+
+	if err = s.checkLoginAllowed(ctx, accountKey, ip); err != nil {
+		return nil, err
+	}
+
+	tf, err := s.twoFactorState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf.Secret == "" {
+		return nil, custom.ErrUnauthorized
+	}
+
+	ok, err := s.verifyCode(ctx, tf, input.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err = s.registerLoginFailure(ctx, accountKey, ip); err != nil {
+			return nil, err
+		}
+		return nil, custom.ErrUnauthorized
+	}
+
+	out := &dto.TwoFactorLoginOutput{}
+	if !tf.Enabled {
+		if err = s.twoFactorRepo.Enable(ctx, userID); err != nil {
+			return nil, fmt.Errorf("failed to enable two factor: %w", err)
+		}
+		if out.RecoveryCodes, err = s.issueRecoveryCodes(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = s.resetLoginAttempts(ctx, accountKey); err != nil {
+		return nil, err
+	}
+
+	perms, err := s.roleRepo.GetPermissions(ctx, userFromDB.Role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role permissions: %w", err)
+	}
+	out.Token, err = s.issueToken(ctx, userFromDB.ID, userFromDB.Role, perms, client)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *Service) EnrollTwoFactor(ctx context.Context, userID int) (*dto.TOTPEnrollment, error) {
+	userFromDB, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	tf, err := s.twoFactorState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf.Enabled {
+		return nil, custom.ErrConflict
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err = s.twoFactorRepo.SaveSecret(ctx, userID, secret); err != nil {
+		return nil, fmt.Errorf("failed to save totp secret: %w", err)
+	}
+
+	return &dto.TOTPEnrollment{
+		Secret:     secret,
+		OTPAuthURI: auth.TOTPURI(s.twoFactorCfg.Issuer, userFromDB.Email, secret),
+	}, nil
+}
+
+func (s *Service) EnrollTwoFactorWithChallenge(ctx context.Context, challengeToken string) (*dto.TOTPEnrollment, error) {
+	userID, err := s.challengeUser(ctx, challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.EnrollTwoFactor(ctx, userID)
+}
+
+func (s *Service) ConfirmTwoFactor(ctx context.Context, userID int, code string) ([]string, error) {
+	tf, err := s.twoFactorState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf.Enabled {
+		return nil, custom.ErrConflict
+	}
+	if tf.Secret == "" {
+		return nil, custom.ErrNotFound
+	}
+
+	ok, err := s.verifyCode(ctx, tf, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, custom.ErrUnauthorized
+	}
+
+	if err = s.twoFactorRepo.Enable(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to enable two factor: %w", err)
+	}
+	return s.issueRecoveryCodes(ctx, userID)
+}
+
+func (s *Service) DisableTwoFactor(ctx context.Context, userID int, code string) error {
+	userFromDB, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	tf, err := s.twoFactorState(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !tf.Enabled {
+		return custom.ErrNotFound
+	}
+	perms, err := s.roleRepo.GetPermissions(ctx, userFromDB.Role)
+	if err != nil {
+		return fmt.Errorf("failed to get role permissions: %w", err)
+	}
+	if s.twoFactorRequired(tf, perms) {
+		return custom.ErrForbidden
+	}
+
+	ok, err := s.verifyCode(ctx, tf, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return custom.ErrUnauthorized
+	}
+
+	if err = s.twoFactorRepo.Disable(ctx, userID); err != nil {
+		return fmt.Errorf("failed to disable two factor: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error) {
+	tf, err := s.twoFactorState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !tf.Enabled {
+		return nil, custom.ErrNotFound
+	}
+
+	ok, err := s.verifyCode(ctx, tf, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, custom.ErrUnauthorized
+	}
+	return s.issueRecoveryCodes(ctx, userID)
+}
+
+func (s *Service) SetTwoFactorRequired(ctx context.Context, targetID int, required bool, perms auth.Permissions) error {
+	if !perms.Has(auth.PermUserManage) {
+		return custom.ErrForbidden
+	}
+
+	if _, err := s.repo.GetByID(ctx, targetID); err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := s.twoFactorRepo.SetRequired(ctx, targetID, required); err != nil {
+		return fmt.Errorf("failed to set two factor requirement: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) twoFactorState(ctx context.Context, userID int) (*domain.TwoFactor, error) {
+	tf, err := s.twoFactorRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, custom.ErrNotFound) {
+			return &domain.TwoFactor{UserID: userID}, nil
+		}
+		return nil, fmt.Errorf("failed to get two factor settings: %w", err)
+	}
+	return tf, nil
+}
+
+// twoFactorRequired считает администратором любого, кто может управлять пользователями.
+func (s *Service) twoFactorRequired(tf *domain.TwoFactor, perms auth.Permissions) bool {
+	return tf.Required || (perms.Has(auth.PermUserManage) && s.twoFactorCfg.RequireForAdmins)
+}
+
+// verifyCode принимает TOTP-код (однократно в пределах шага) или, если 2FA
+// уже включена, один из неиспользованных резервных кодов.
+func (s *Service) verifyCode(ctx context.Context, tf *domain.TwoFactor, code string) (bool, error) {
+	if step, ok := auth.ValidateTOTP(tf.Secret, code, time.Now()); ok {
+		fresh, err := s.twoFactorRepo.MarkStepUsed(ctx, tf.UserID, step)
+		if err != nil {
+			return false, fmt.Errorf("failed to mark totp step used: %w", err)
+		}
+		return fresh, nil
+	}
+
+	if !tf.Enabled {
+		return false, nil
+	}
+	used, err := s.twoFactorRepo.UseRecoveryCode(ctx, tf.UserID, auth.HashRecoveryCode(code))
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	return used, nil
+}
+
+func (s *Service) issueRecoveryCodes(ctx context.Context, userID int) ([]string, error) {
+	codes, err := auth.GenerateRecoveryCodes(auth.RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = auth.HashRecoveryCode(c)
+	}
+	if err = s.twoFactorRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to save recovery codes: %w", err)
+	}
+	return codes, nil
+}
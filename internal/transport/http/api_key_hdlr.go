@@ -0,0 +1,113 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"net/http"
+	"product-catalog/internal/auth"
+	"product-catalog/internal/dto"
+	custom "product-catalog/internal/errors"
+	"strconv"
+)
+
+type APIKeyService interface {
+	CreateKey(ctx context.Context, userID int, perms auth.Permissions, input *dto.CreateAPIKeyInput) (*dto.CreatedAPIKeyOutput, error)
+	ListKeys(ctx context.Context, userID int) ([]dto.APIKeyOutput, error)
+	RevokeKey(ctx context.Context, userID, keyID int) error
+}
+
+type APIKeyHandler struct {
+	svc            APIKeyService
+	logger         *zap.Logger
+	authMiddleware func(http.Handler) http.Handler
+}
+
+func NewAPIKeyHandler(svc APIKeyService, logger *zap.Logger, authMiddleware *auth.Middleware) *APIKeyHandler {
+	return &APIKeyHandler{svc: svc, logger: logger, authMiddleware: authMiddleware.AuthMiddleware}
+}
+
+func (h *APIKeyHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(h.authMiddleware)
+	r.Use(auth.DenyAPIKeys)
+	r.Use(auth.DenyImpersonation)
+	r.Post("/", h.CreateKey)
+	r.Get("/", h.ListKeys)
+	r.Delete("/{id}", h.RevokeKey)
+	return r
+}
+
+func (h *APIKeyHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	perms, ok := auth.PermissionsFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	var input dto.CreateAPIKeyInput
+	if !decodeJSON(w, r, h.logger, &input) {
+		return
+	}
+
+	key, err := h.svc.CreateKey(r.Context(), userID, perms, &input)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to create api key", err, zap.Int("user_id", userID))
+		return
+	}
+
+	h.logger.Info("api key created", zap.Int("user_id", userID), zap.Int("key_id", key.ID), zap.String("prefix", key.Prefix))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(key)
+}
+
+func (h *APIKeyHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	keys, err := h.svc.ListKeys(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to list api keys", err, zap.Int("user_id", userID))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(keys)
+}
+
+func (h *APIKeyHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	keyID, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeError(w, r, h.logger, "invalid api key id", fmt.Errorf("%w: invalid api key id %q", custom.ErrInvalidInput, idStr))
+		return
+	}
+
+	err = h.svc.RevokeKey(r.Context(), userID, keyID)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to revoke api key", err, zap.Int("key_id", keyID))
+		return
+	}
+
+	h.logger.Info("api key revoked", zap.Int("user_id", userID), zap.Int("key_id", keyID))
+	w.WriteHeader(http.StatusNoContent)
+}
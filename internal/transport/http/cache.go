@@ -0,0 +1,76 @@
+package http
+
+import (
+	"net/http"
+	"product-catalog/internal/auth"
+	"strings"
+	"time"
+)
+
+// CachePolicy — заголовки кэширования одного публичного маршрута. SurrogateControl
+// читает только CDN и снимает его перед отдачей клиенту.
+type CachePolicy struct {
+	CacheControl     string
+	SurrogateControl string
+}
+
+// CacheConfig задаёт политики публичных маршрутов каталога. Ключи суррогатов
+// отдаются в заголовке SurrogateKeyHeader, чтобы CDN мог сбросить отдельный товар.
+type CacheConfig struct {
+	SurrogateKeyHeader string
+	ProductList        CachePolicy
+	Product            CachePolicy
+}
+
+// privateCache — политика ответов аутентифицированным: в них могут быть
+// неопубликованные товары, которые нельзя класть в общий кэш
+var privateCache = CachePolicy{CacheControl: "private, no-cache"}
+
+// setCacheHeaders выставляет валидаторы и политику кэширования. Их нужно
+// выставить до проверки условий: ответ 304 несёт те же заголовки, что и 200.
+func setCacheHeaders(w http.ResponseWriter, cfg CacheConfig, policy CachePolicy, tag string, modified time.Time, keys ...string) {
+	// Ответ зависит от того, кто спрашивает: публичный каталог или каталог редактора
+	w.Header().Set("Vary", "Authorization, "+auth.APIKeyHeader)
+	w.Header().Set("ETag", tag)
+	if !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if policy.CacheControl != "" {
+		w.Header().Set("Cache-Control", policy.CacheControl)
+	}
+	if policy.SurrogateControl != "" {
+		w.Header().Set("Surrogate-Control", policy.SurrogateControl)
+	}
+	if cfg.SurrogateKeyHeader != "" && len(keys) > 0 {
+		w.Header().Set(cfg.SurrogateKeyHeader, strings.Join(keys, " "))
+	}
+}
+
+// notModified проверяет If-None-Match и If-Modified-Since (RFC 9110, 13.2.2).
+// If-None-Match сравнивается слабо и, если передан, отменяет If-Modified-Since.
+func notModified(r *http.Request, tag string, modified time.Time) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		for _, candidate := range strings.Split(header, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || weakTag(candidate) == weakTag(tag) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if modified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	// Last-Modified передаётся с точностью до секунды
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// weakTag отбрасывает признак слабого ETag: при слабом сравнении W/"1" и "1" равны
+func weakTag(tag string) string {
+	return strings.TrimPrefix(tag, "W/")
+}
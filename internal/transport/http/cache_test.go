@@ -0,0 +1,220 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	h "product-catalog/internal/transport/http"
+)
+
+// fakeCatalog отдаёт одну ревизию каталога и один товар
+type fakeCatalog struct {
+	h.ProductService
+	rev     domain.CatalogRevision
+	product domain.Product
+}
+
+func (s *fakeCatalog) GetCatalogRevision(context.Context) (domain.CatalogRevision, error) {
+	return s.rev, nil
+}
+
+func (s *fakeCatalog) GetAllProducts(context.Context, auth.Permissions) ([]domain.Product, error) {
+	return []domain.Product{s.product}, nil
+}
+
+func (s *fakeCatalog) GetProductByID(context.Context, int, auth.Permissions) (*domain.Product, error) {
+	p := s.product
+	return &p, nil
+}
+
+var (
+	catalogModified = time.Date(2026, 3, 1, 12, 0, 0, 500_000_000, time.UTC)
+	catalogCache    = h.CacheConfig{
+		SurrogateKeyHeader: "Surrogate-Key",
+		ProductList:        h.CachePolicy{CacheControl: "public, max-age=60", SurrogateControl: "max-age=600"},
+		Product:            h.CachePolicy{CacheControl: "public, max-age=300", SurrogateControl: "max-age=3600"},
+	}
+)
+
+func newCatalogHandler() *h.ProductHandler {
+	svc := &fakeCatalog{
+		rev:     domain.CatalogRevision{Revision: 5, UpdatedAt: catalogModified},
+		product: domain.Product{ID: 3, Title: "lamp", Version: 2, UpdatedAt: catalogModified},
+	}
+	return h.NewProductHandler(svc, nil, zap.NewNop(), nil, nil, catalogCache)
+}
+
+// catalogRequest — запрос к каталогу арендатора 1; с perms он приходит от вошедшего редактора
+func catalogRequest(target string, headers map[string]string, perms auth.Permissions) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	ctx := auth.WithTenant(req.Context(), 1)
+	if perms != nil {
+		ctx = auth.WithUserContext(ctx, 7, auth.RoleUser, perms)
+	}
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "3")
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+	return req.WithContext(ctx)
+}
+
+func TestProductListConditionalGet(t *testing.T) {
+	lastModified := catalogModified.Format(http.TimeFormat)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"no validators", nil, http.StatusOK},
+		{"matching weak tag", map[string]string{"If-None-Match": `W/"5"`}, http.StatusNotModified},
+		{"strong tag matches weakly", map[string]string{"If-None-Match": `"5"`}, http.StatusNotModified},
+		{"tag in list", map[string]string{"If-None-Match": `"4", W/"5"`}, http.StatusNotModified},
+		{"any tag", map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+		{"stale tag", map[string]string{"If-None-Match": `W/"4"`}, http.StatusOK},
+		{
+			name:    "If-None-Match overrides If-Modified-Since",
+			headers: map[string]string{"If-None-Match": `W/"4"`, "If-Modified-Since": lastModified},
+			want:    http.StatusOK,
+		},
+		{"modified since truncated to seconds", map[string]string{"If-Modified-Since": lastModified}, http.StatusNotModified},
+		{
+			name:    "modified after since",
+			headers: map[string]string{"If-Modified-Since": catalogModified.Add(-time.Second).Format(http.TimeFormat)},
+			want:    http.StatusOK,
+		},
+		{"malformed since", map[string]string{"If-Modified-Since": "yesterday"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			newCatalogHandler().GetAllProducts(rec, catalogRequest("/products", tt.headers, nil))
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d; want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Errorf("304 with body %q", rec.Body)
+			}
+			// 304 несёт те же валидаторы и политику, что и 200
+			want := map[string]string{
+				"ETag":              `W/"5"`,
+				"Last-Modified":     lastModified,
+				"Cache-Control":     "public, max-age=60",
+				"Surrogate-Control": "max-age=600",
+				"Surrogate-Key":     "tenant-1-products",
+				"Vary":              "Authorization, " + auth.APIKeyHeader,
+			}
+			for k, v := range want {
+				if got := rec.Header().Get(k); got != v {
+					t.Errorf("%s = %q; want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestCachePolicyByViewer(t *testing.T) {
+	editor := auth.Permissions{auth.PermProductWrite}
+
+	tests := []struct {
+		name        string
+		get         func(hdlr *h.ProductHandler) http.HandlerFunc
+		perms       auth.Permissions
+		ifNoneMatch string
+		wantStatus  int
+		wantTag     string
+		wantCache   string
+		wantSurCtl  string
+		wantSurKey  string
+	}{
+		{
+			name:       "public list",
+			get:        func(hdlr *h.ProductHandler) http.HandlerFunc { return hdlr.GetAllProducts },
+			wantStatus: http.StatusOK,
+			wantTag:    `W/"5"`,
+			wantCache:  "public, max-age=60",
+			wantSurCtl: "max-age=600",
+			wantSurKey: "tenant-1-products",
+		},
+		{
+			name:       "editor list",
+			get:        func(hdlr *h.ProductHandler) http.HandlerFunc { return hdlr.GetAllProducts },
+			perms:      editor,
+			wantStatus: http.StatusOK,
+			wantTag:    `W/"5-all"`,
+			wantCache:  "private, no-cache",
+		},
+		{
+			name:        "editor list ignores public tag",
+			get:         func(hdlr *h.ProductHandler) http.HandlerFunc { return hdlr.GetAllProducts },
+			perms:       editor,
+			ifNoneMatch: `W/"5"`,
+			wantStatus:  http.StatusOK,
+			wantTag:     `W/"5-all"`,
+			wantCache:   "private, no-cache",
+		},
+		{
+			name:        "editor list not modified",
+			get:         func(hdlr *h.ProductHandler) http.HandlerFunc { return hdlr.GetAllProducts },
+			perms:       editor,
+			ifNoneMatch: `W/"5-all"`,
+			wantStatus:  http.StatusNotModified,
+			wantTag:     `W/"5-all"`,
+			wantCache:   "private, no-cache",
+		},
+		{
+			name:       "public product",
+			get:        func(hdlr *h.ProductHandler) http.HandlerFunc { return hdlr.GetProductByID },
+			wantStatus: http.StatusOK,
+			wantTag:    `"2"`,
+			wantCache:  "public, max-age=300",
+			wantSurCtl: "max-age=3600",
+			wantSurKey: "tenant-1-product-3",
+		},
+		{
+			name:        "editor product not modified",
+			get:         func(hdlr *h.ProductHandler) http.HandlerFunc { return hdlr.GetProductByID },
+			perms:       editor,
+			ifNoneMatch: `W/"2"`,
+			wantStatus:  http.StatusNotModified,
+			wantTag:     `"2"`,
+			wantCache:   "private, no-cache",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tt.ifNoneMatch != "" {
+				headers["If-None-Match"] = tt.ifNoneMatch
+			}
+			rec := httptest.NewRecorder()
+			tt.get(newCatalogHandler())(rec, catalogRequest("/products/3", headers, tt.perms))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d; want %d", rec.Code, tt.wantStatus)
+			}
+			want := map[string]string{
+				"ETag":              tt.wantTag,
+				"Cache-Control":     tt.wantCache,
+				"Surrogate-Control": tt.wantSurCtl,
+				"Surrogate-Key":     tt.wantSurKey,
+			}
+			for k, v := range want {
+				if got := rec.Header().Get(k); got != v {
+					t.Errorf("%s = %q; want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/transport/patch"
+	"product-catalog/internal/validation"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+const (
+	// maxUploadSize ограничивает multipart-запрос и каждый загружаемый файл
+	maxUploadSize = 10 << 20
+	// maxPatchSize ограничивает тело PATCH-запроса
+	maxPatchSize = 1 << 20
+)
+
+var requestValidator = newValidator()
+
+func newValidator() *validation.Validator {
+	v := validation.New()
+	v.Register("filesize", func(fv reflect.Value, _ string) error {
+		fh, ok := fv.Interface().(*multipart.FileHeader)
+		if !ok {
+			return validation.BadTag("filesize is not applicable to %s", fv.Type())
+		}
+		if fh.Size > maxUploadSize {
+			return fmt.Errorf("must be at most %d bytes", maxUploadSize)
+		}
+		return nil
+	})
+	return v
+}
+
+// decodeJSON разбирает JSON-тело в dst и проверяет его теги validate. Если вернулось
+// false, ответ problem+json уже записан: 400 для нечитаемого тела, 422 со списком ошибок полей.
+func decodeJSON(w http.ResponseWriter, r *http.Request, logger *zap.Logger, dst any) bool {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			writeValidationError(w, r, logger, validation.Errors{{Field: typeErr.Field, Rule: "type", Message: typeMessage(typeErr.Type)}})
+			return false
+		}
+		writeError(w, r, logger, "invalid request body", fmt.Errorf("%w: invalid request body", custom.ErrInvalidInput))
+		return false
+	}
+	return validate(w, r, logger, dst, nil)
+}
+
+// decodeForm заполняет dst из multipart-формы по тегам form (включая файлы
+// *multipart.FileHeader) и проверяет теги validate
+func decodeForm(w http.ResponseWriter, r *http.Request, logger *zap.Logger, dst any) bool {
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		writeError(w, r, logger, "failed to parse multipart form", fmt.Errorf("%w: invalid multipart form: %v", custom.ErrInvalidInput, err))
+		return false
+	}
+	return validate(w, r, logger, dst, bindForm(r.MultipartForm, dst))
+}
+
+// decodeBody выбирает разбор по Content-Type: JSON или multipart-форма. Остальные
+// типы отклоняются с 415.
+func decodeBody(w http.ResponseWriter, r *http.Request, logger *zap.Logger, dst any) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		return decodeJSON(w, r, logger, dst)
+	case "multipart/form-data":
+		return decodeForm(w, r, logger, dst)
+	default:
+		writeError(w, r, logger, "unsupported content type",
+			fmt.Errorf("%w: expected application/json or multipart/form-data", custom.ErrUnsupportedMediaType),
+			zap.String("content_type", r.Header.Get("Content-Type")))
+		return false
+	}
+}
+
+// readPatch читает тело PATCH-запроса и его тип: merge patch или JSON Patch.
+// Сам патч применяется позже, через applyPatch, когда сервис проверит права.
+func readPatch(w http.ResponseWriter, r *http.Request, logger *zap.Logger) ([]byte, string, bool) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != patch.MergePatchType && mediaType != patch.JSONPatchType {
+		writeError(w, r, logger, "unsupported patch type",
+			fmt.Errorf("%w: expected %s or %s", custom.ErrUnsupportedMediaType, patch.MergePatchType, patch.JSONPatchType),
+			zap.String("content_type", r.Header.Get("Content-Type")))
+		return nil, "", false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPatchSize+1))
+	if err != nil {
+		writeError(w, r, logger, "failed to read patch", fmt.Errorf("%w: invalid request body", custom.ErrInvalidInput))
+		return nil, "", false
+	}
+	if len(body) > maxPatchSize {
+		writeError(w, r, logger, "patch is too large", fmt.Errorf("%w: patch exceeds %d bytes", custom.ErrInvalidInput, maxPatchSize))
+		return nil, "", false
+	}
+	return body, mediaType, true
+}
+
+// applyPatch применяет патч к документу current и разбирает результат в dst. Патч
+// либо применяется целиком и проходит проверку тегов validate, либо возвращается
+// ошибка и ничего не меняется.
+func applyPatch(mediaType string, body []byte, current, dst any) error {
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return fmt.Errorf("failed to encode document: %w", err)
+	}
+	patched, err := patch.Apply(mediaType, doc, body)
+	if err != nil {
+		return err
+	}
+
+	if errs, err := unknownFields(patched, dst); err != nil {
+		return err
+	} else if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", custom.ErrValidation, errs)
+	}
+	if err = json.Unmarshal(patched, dst); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			errs := validation.Errors{{Field: typeErr.Field, Rule: "type", Message: typeMessage(typeErr.Type)}}
+			return fmt.Errorf("%w: %w", custom.ErrValidation, errs)
+		}
+		return fmt.Errorf("%w: invalid patched document", custom.ErrInvalidInput)
+	}
+
+	err = requestValidator.Struct(dst)
+	var fieldErrs validation.Errors
+	if errors.As(err, &fieldErrs) {
+		return fmt.Errorf("%w: %w", custom.ErrValidation, fieldErrs)
+	}
+	return err
+}
+
+// unknownFields находит поля, которых нет в dst: патч не может добавить
+// в ресурс произвольные ключи
+func unknownFields(doc []byte, dst any) (validation.Errors, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &fields); err != nil {
+		return nil, fmt.Errorf("%w: patched document must be an object", custom.ErrInvalidInput)
+	}
+
+	known := map[string]bool{}
+	t := reflect.TypeOf(dst).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		known[validation.FieldName(t.Field(i))] = true
+	}
+
+	var errs validation.Errors
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		if !known[name] {
+			errs = append(errs, validation.FieldError{Field: name, Rule: "unknown", Message: "is not a known field"})
+		}
+	}
+	return errs, nil
+}
+
+// validate проверяет dst и дополняет ошибки привязки; поля, которые не удалось
+// разобрать, повторно не проверяются
+func validate(w http.ResponseWriter, r *http.Request, logger *zap.Logger, dst any, bindErrs validation.Errors) bool {
+	err := requestValidator.Struct(dst)
+	var fieldErrs validation.Errors
+	if err != nil && !errors.As(err, &fieldErrs) {
+		writeError(w, r, logger, "failed to validate request", err)
+		return false
+	}
+
+	errs := bindErrs
+	for _, fe := range fieldErrs {
+		if !hasField(bindErrs, fe.Field) {
+			errs = append(errs, fe)
+		}
+	}
+	if len(errs) > 0 {
+		writeValidationError(w, r, logger, errs)
+		return false
+	}
+	return true
+}
+
+func writeValidationError(w http.ResponseWriter, r *http.Request, logger *zap.Logger, errs validation.Errors) {
+	writeError(w, r, logger, "request validation failed", fmt.Errorf("%w: %w", custom.ErrValidation, errs))
+}
+
+func bindForm(form *multipart.Form, dst any) validation.Errors {
+	var errs validation.Errors
+	rv := reflect.ValueOf(dst).Elem()
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := f.Tag.Get("form")
+		if name == "" || name == "-" {
+			continue
+		}
+		fv := rv.Field(i)
+
+		if f.Type == reflect.TypeOf(&multipart.FileHeader{}) {
+			if files := form.File[name]; len(files) > 0 {
+				fv.Set(reflect.ValueOf(files[0]))
+			}
+			continue
+		}
+
+		values := form.Value[name]
+		if len(values) == 0 {
+			continue
+		}
+		if fv.Kind() == reflect.Pointer {
+			fv.Set(reflect.New(f.Type.Elem()))
+			fv = fv.Elem()
+		}
+		if err := setFormValue(fv, values[0]); err != nil {
+			errs = append(errs, validation.FieldError{Field: name, Rule: "type", Message: typeMessage(fv.Type())})
+		}
+	}
+	return errs
+}
+
+func setFormValue(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported form field type %s", v.Type())
+	}
+	return nil
+}
+
+func typeMessage(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "must be an integer"
+	case reflect.Float32, reflect.Float64:
+		return "must be a number"
+	case reflect.Bool:
+		return "must be a boolean"
+	case reflect.Slice, reflect.Array:
+		return "must be an array"
+	case reflect.Struct, reflect.Map:
+		return "must be an object"
+	default:
+		return "must be a " + t.Kind().String()
+	}
+}
+
+func hasField(errs validation.Errors, field string) bool {
+	for _, fe := range errs {
+		if fe.Field == field {
+			return true
+		}
+	}
+	return false
+}
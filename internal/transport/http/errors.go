@@ -0,0 +1,19 @@
+package http
+
+import (
+	"go.uber.org/zap"
+	"net/http"
+	"product-catalog/internal/transport/problem"
+)
+
+// writeError отвечает problem+json: статус и код выбираются по сентинелам из
+// internal/errors. Серверные ошибки логируются как Error, клиентские — как Warn.
+func writeError(w http.ResponseWriter, r *http.Request, logger *zap.Logger, msg string, err error, fields ...zap.Field) {
+	fields = append(fields, zap.Error(err))
+	if problem.Status(err) >= http.StatusInternalServerError {
+		logger.Error(msg, fields...)
+	} else {
+		logger.Warn(msg, fields...)
+	}
+	problem.Error(w, r, err)
+}
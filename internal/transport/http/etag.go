@@ -0,0 +1,33 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	custom "product-catalog/internal/errors"
+	"strconv"
+	"strings"
+)
+
+// etag — сильный ETag версии ресурса
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ifMatch возвращает версию из заголовка If-Match. Без заголовка изменение не
+// принимается (428); слабый ETag, список или "*" не могут подтвердить конкретную
+// версию и дают 412.
+func ifMatch(r *http.Request) (int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return 0, fmt.Errorf("%w: send If-Match with the ETag from GET", custom.ErrPreconditionRequired)
+	}
+	tag, ok := strings.CutPrefix(header, `"`)
+	if ok {
+		tag, ok = strings.CutSuffix(tag, `"`)
+	}
+	version, err := strconv.Atoi(tag)
+	if !ok || err != nil || version < 1 {
+		return 0, fmt.Errorf("%w: If-Match %s does not match the current ETag", custom.ErrPreconditionFailed, header)
+	}
+	return version, nil
+}
@@ -0,0 +1,217 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBody — тело читается целиком ради отпечатка: файл и поля формы
+	maxIdempotentBody = maxUploadSize + 1<<20
+)
+
+// replayedHeaders — заголовки ответа, которые сохраняются вместе с телом
+var replayedHeaders = []string{"Content-Type", "Location", "ETag"}
+
+type IdempotencyService interface {
+	Begin(ctx context.Context, userID int, key, fingerprint string) (*domain.IdempotencyRecord, error)
+	Complete(ctx context.Context, rec *domain.IdempotencyRecord) error
+	Release(ctx context.Context, userID int, key string) error
+}
+
+// IdempotencyMiddleware делает повтор запроса с тем же Idempotency-Key безопасным:
+// повтор получает сохранённый ответ первого запроса, а не выполняется заново.
+// Ставится после AuthMiddleware: ключи действуют в пределах пользователя.
+type IdempotencyMiddleware struct {
+	svc    IdempotencyService
+	logger *zap.Logger
+}
+
+func NewIdempotencyMiddleware(svc IdempotencyService, logger *zap.Logger) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{svc: svc, logger: logger}
+}
+
+func (m *IdempotencyMiddleware) Idempotency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			writeError(w, r, m.logger, "invalid idempotency key",
+				fmt.Errorf("%w: %s must be at most %d characters", custom.ErrInvalidInput, IdempotencyKeyHeader, maxIdempotencyKeyLength))
+			return
+		}
+		userID, ok := auth.UserIDFromContext(r.Context())
+		if !ok {
+			writeError(w, r, m.logger, "user id not found in context", custom.ErrUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBody))
+		if err != nil {
+			writeError(w, r, m.logger, "failed to read request body", fmt.Errorf("%w: request body is too large or unreadable", custom.ErrInvalidInput))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := requestFingerprint(r, body)
+
+		stored, err := m.svc.Begin(r.Context(), userID, key, fingerprint)
+		if err != nil {
+			writeError(w, r, m.logger, "idempotency key rejected", err, zap.Int("user_id", userID))
+			return
+		}
+		if stored != nil {
+			replay(w, stored)
+			return
+		}
+
+		// Ключ освобождается, если ответ не сохранён: при ошибке сервера и панике
+		// повтор должен выполниться заново
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := m.svc.Release(context.WithoutCancel(r.Context()), userID, key); err != nil {
+				m.logger.Error("failed to release idempotency key", zap.Error(err), zap.Int("user_id", userID))
+			}
+		}()
+
+		rec := &recordingWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if rec.statusCode >= http.StatusInternalServerError {
+			return
+		}
+
+		header := make(map[string]string)
+		for _, name := range replayedHeaders {
+			if v := w.Header().Get(name); v != "" {
+				header[name] = v
+			}
+		}
+		err = m.svc.Complete(context.WithoutCancel(r.Context()), &domain.IdempotencyRecord{
+			UserID:      userID,
+			Key:         key,
+			Fingerprint: fingerprint,
+			StatusCode:  rec.statusCode,
+			Header:      header,
+			Body:        rec.body.Bytes(),
+		})
+		if err != nil {
+			m.logger.Error("failed to store idempotent response", zap.Error(err), zap.Int("user_id", userID))
+			return
+		}
+		completed = true
+	})
+}
+
+// requestFingerprint отличает повтор от другого запроса с тем же ключом. Тело
+// приводится к канонической форме: повтор той же формы приходит с новой границей
+// multipart, а JSON может быть сериализован с другими пробелами и порядком ключей.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(canonicalBody(r.Header.Get("Content-Type"), body))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// canonicalBody возвращает тело в виде, не зависящем от способа сериализации.
+// Нечитаемое тело остаётся как есть: такой запрос всё равно отклонит обработчик.
+func canonicalBody(contentType string, body []byte) []byte {
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/json":
+		var doc any
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return body
+		}
+		// json.Marshal сортирует ключи объектов
+		canonical, err := json.Marshal(doc)
+		if err != nil {
+			return body
+		}
+		return canonical
+	case "multipart/form-data":
+		if canonical, err := canonicalForm(body, params["boundary"]); err == nil {
+			return canonical
+		}
+	}
+	return body
+}
+
+// canonicalForm описывает каждую часть формы именем, именем файла и хэшем
+// содержимого; части сортируются, а граница в отпечаток не попадает
+func canonicalForm(body []byte, boundary string) ([]byte, error) {
+	mr := multipart.NewReader(bytes.NewReader(body), boundary)
+	var parts []string
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.New()
+		if _, err = io.Copy(sum, part); err != nil {
+			return nil, err
+		}
+		parts = append(parts, strconv.Quote(part.FormName())+" "+strconv.Quote(part.FileName())+" "+hex.EncodeToString(sum.Sum(nil)))
+	}
+	slices.Sort(parts)
+	return []byte(strings.Join(parts, "\n")), nil
+}
+
+func replay(w http.ResponseWriter, rec *domain.IdempotencyRecord) {
+	for name, v := range rec.Header {
+		w.Header().Set(name, v)
+	}
+	w.Header().Set(IdempotentReplayedHeader, strconv.FormatBool(true))
+	w.WriteHeader(rec.StatusCode)
+	_, _ = w.Write(rec.Body)
+}
+
+// recordingWriter пишет ответ клиенту и копирует его для повторов
+type recordingWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.statusCode, rw.wroteHeader = code, true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
@@ -0,0 +1,255 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+	h "product-catalog/internal/transport/http"
+)
+
+// fakeIdempotency повторяет правила сервиса idempotency в памяти
+type fakeIdempotency struct {
+	records  map[string]*domain.IdempotencyRecord
+	begun    int
+	released []string
+}
+
+func (s *fakeIdempotency) Begin(_ context.Context, userID int, key, fingerprint string) (*domain.IdempotencyRecord, error) {
+	s.begun++
+	existing, ok := s.records[key]
+	if !ok {
+		s.records[key] = &domain.IdempotencyRecord{UserID: userID, Key: key, Fingerprint: fingerprint}
+		return nil, nil
+	}
+	if existing.Fingerprint != fingerprint {
+		return nil, fmt.Errorf("%w: key was used with a different request", custom.ErrIdempotencyKeyReused)
+	}
+	if existing.StatusCode == 0 {
+		return nil, fmt.Errorf("%w: request with this Idempotency-Key is still in progress", custom.ErrConflict)
+	}
+	return existing, nil
+}
+
+func (s *fakeIdempotency) Complete(_ context.Context, rec *domain.IdempotencyRecord) error {
+	s.records[rec.Key] = rec
+	return nil
+}
+
+func (s *fakeIdempotency) Release(_ context.Context, _ int, key string) error {
+	delete(s.records, key)
+	s.released = append(s.released, key)
+	return nil
+}
+
+// countingHandler отвечает status и считает, сколько раз запрос дошёл до обработчика
+type countingHandler struct {
+	status int
+	calls  int
+}
+
+func (c *countingHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	c.calls++
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/products/1")
+	w.Header().Set("ETag", `"1"`)
+	w.Header().Set("X-Request-Id", fmt.Sprint(c.calls))
+	w.WriteHeader(c.status)
+	_, _ = fmt.Fprintf(w, `{"call":%d}`, c.calls)
+}
+
+func idempotentRequest(key, body string) *http.Request {
+	return typedIdempotentRequest(key, body, "application/json")
+}
+
+func typedIdempotentRequest(key, body, contentType string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	if key != "" {
+		req.Header.Set(h.IdempotencyKeyHeader, key)
+	}
+	ctx := auth.WithUserContext(req.Context(), 7, auth.RoleUser, auth.Permissions{auth.PermProductWrite})
+	return req.WithContext(ctx)
+}
+
+func newIdempotency(status int) (http.Handler, *fakeIdempotency, *countingHandler) {
+	svc := &fakeIdempotency{records: map[string]*domain.IdempotencyRecord{}}
+	next := &countingHandler{status: status}
+	return h.NewIdempotencyMiddleware(svc, zap.NewNop()).Idempotency(next), svc, next
+}
+
+func TestIdempotencyReplaysStoredResponse(t *testing.T) {
+	mw, _, next := newIdempotency(http.StatusCreated)
+
+	first := httptest.NewRecorder()
+	mw.ServeHTTP(first, idempotentRequest("create-1", `{"title":"lamp"}`))
+	if first.Code != http.StatusCreated || first.Header().Get(h.IdempotentReplayedHeader) != "" {
+		t.Fatalf("first = %d, replayed %q; want 201 without replay", first.Code, first.Header().Get(h.IdempotentReplayedHeader))
+	}
+
+	retry := httptest.NewRecorder()
+	mw.ServeHTTP(retry, idempotentRequest("create-1", `{"title":"lamp"}`))
+	if next.calls != 1 {
+		t.Fatalf("handler called %d times; want 1", next.calls)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != `{"call":1}` {
+		t.Errorf("retry = %d %s; want 201 {\"call\":1}", retry.Code, retry.Body)
+	}
+	want := map[string]string{
+		"Content-Type":             "application/json",
+		"Location":                 "/products/1",
+		"ETag":                     `"1"`,
+		h.IdempotentReplayedHeader: "true",
+		// Заголовки вне списка не сохраняются
+		"X-Request-Id": "",
+	}
+	for k, v := range want {
+		if got := retry.Header().Get(k); got != v {
+			t.Errorf("%s = %q; want %q", k, got, v)
+		}
+	}
+}
+
+func TestIdempotencyStatusHandling(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		wantCalls    int
+		wantReleased bool
+	}{
+		{"success is stored", http.StatusCreated, 1, false},
+		{"client error is stored", http.StatusUnprocessableEntity, 1, false},
+		{"server error releases key", http.StatusInternalServerError, 2, true},
+		{"unavailable releases key", http.StatusServiceUnavailable, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw, svc, next := newIdempotency(tt.status)
+			for range 2 {
+				rec := httptest.NewRecorder()
+				mw.ServeHTTP(rec, idempotentRequest("create-1", `{"title":"lamp"}`))
+				if rec.Code != tt.status {
+					t.Fatalf("status = %d; want %d", rec.Code, tt.status)
+				}
+			}
+			if next.calls != tt.wantCalls {
+				t.Errorf("handler called %d times; want %d", next.calls, tt.wantCalls)
+			}
+			if got := slices.Contains(svc.released, "create-1"); got != tt.wantReleased {
+				t.Errorf("released = %v; want %v", svc.released, tt.wantReleased)
+			}
+		})
+	}
+}
+
+func TestIdempotencyRejectsRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		key        string
+		body       string
+		wantStatus int
+		wantBegun  int
+	}{
+		{"key too long", strings.Repeat("k", 256), `{"title":"lamp"}`, http.StatusBadRequest, 0},
+		{"key reused with other body", "create-1", `{"title":"desk"}`, http.StatusUnprocessableEntity, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw, svc, next := newIdempotency(http.StatusCreated)
+			mw.ServeHTTP(httptest.NewRecorder(), idempotentRequest("create-1", `{"title":"lamp"}`))
+			svc.begun = 0
+
+			rec := httptest.NewRecorder()
+			mw.ServeHTTP(rec, idempotentRequest(tt.key, tt.body))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, body %s; want %d", rec.Code, rec.Body, tt.wantStatus)
+			}
+			if svc.begun != tt.wantBegun || next.calls != 1 {
+				t.Errorf("begun %d, handler called %d times; want %d and 1", svc.begun, next.calls, tt.wantBegun)
+			}
+		})
+	}
+}
+
+func TestIdempotencyWithoutKey(t *testing.T) {
+	mw, svc, next := newIdempotency(http.StatusCreated)
+	for range 2 {
+		mw.ServeHTTP(httptest.NewRecorder(), idempotentRequest("", `{"title":"lamp"}`))
+	}
+	if next.calls != 2 || svc.begun != 0 {
+		t.Errorf("handler called %d times, begun %d; want 2 and 0", next.calls, svc.begun)
+	}
+}
+
+// formWithBoundary собирает форму товара с картинкой и заданной границей multipart
+func formWithBoundary(t *testing.T, boundary, title string, image []byte) (string, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if err := mw.SetBoundary(boundary); err != nil {
+		t.Fatalf("set boundary: %v", err)
+	}
+	_ = mw.WriteField("title", title)
+	_ = mw.WriteField("price", "100")
+	fw, err := mw.CreateFormFile("image", "lamp.png")
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	_, _ = fw.Write(image)
+	if err = mw.Close(); err != nil {
+		t.Fatalf("close multipart: %v", err)
+	}
+	return buf.String(), mw.FormDataContentType()
+}
+
+func TestIdempotencyFingerprint(t *testing.T) {
+	form, formType := formWithBoundary(t, "first-boundary", "lamp", pngHeader)
+	sameForm, sameFormType := formWithBoundary(t, "retry-boundary", "lamp", pngHeader)
+	otherTitle, otherTitleType := formWithBoundary(t, "retry-boundary", "desk", pngHeader)
+	otherImage, otherImageType := formWithBoundary(t, "retry-boundary", "lamp", append(pngHeader, 0))
+
+	tests := []struct {
+		name       string
+		first      string
+		firstType  string
+		retry      string
+		retryType  string
+		wantReplay bool
+	}{
+		{"multipart retry with new boundary", form, formType, sameForm, sameFormType, true},
+		{"multipart with other field", form, formType, otherTitle, otherTitleType, false},
+		{"multipart with other file", form, formType, otherImage, otherImageType, false},
+		{"json reformatted", `{"title":"lamp","price":100}`, "application/json", "{ \"price\": 100,\n \"title\": \"lamp\" }", "application/json; charset=utf-8", true},
+		{"json with other value", `{"title":"lamp","price":100}`, "application/json", `{"title":"lamp","price":101}`, "application/json", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw, _, next := newIdempotency(http.StatusCreated)
+			mw.ServeHTTP(httptest.NewRecorder(), typedIdempotentRequest("create-1", tt.first, tt.firstType))
+
+			rec := httptest.NewRecorder()
+			mw.ServeHTTP(rec, typedIdempotentRequest("create-1", tt.retry, tt.retryType))
+			if tt.wantReplay {
+				if rec.Code != http.StatusCreated || rec.Header().Get(h.IdempotentReplayedHeader) != "true" {
+					t.Errorf("retry = %d, replayed %q; want replayed 201", rec.Code, rec.Header().Get(h.IdempotentReplayedHeader))
+				}
+			} else if rec.Code != http.StatusUnprocessableEntity {
+				t.Errorf("retry = %d; want 422 for a different request", rec.Code)
+			}
+			if next.calls != 1 {
+				t.Errorf("handler called %d times; want 1", next.calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"net/http"
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	"product-catalog/internal/dto"
+	custom "product-catalog/internal/errors"
+	"strconv"
+)
+
+type ImpersonationService interface {
+	Impersonate(ctx context.Context, actorID int, perms auth.Permissions, input *dto.ImpersonateInput, client dto.ClientInfo) (*dto.ImpersonationOutput, error)
+	ListAudit(ctx context.Context, userID, actorID, limit int) ([]domain.ImpersonationAuditRecord, error)
+}
+
+type ImpersonationHandler struct {
+	svc            ImpersonationService
+	logger         *zap.Logger
+	authMiddleware func(http.Handler) http.Handler
+}
+
+func NewImpersonationHandler(svc ImpersonationService, logger *zap.Logger, authMiddleware *auth.Middleware) *ImpersonationHandler {
+	return &ImpersonationHandler{svc: svc, logger: logger, authMiddleware: authMiddleware.AuthMiddleware}
+}
+
+func (h *ImpersonationHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(h.authMiddleware)
+	r.Use(auth.DenyAPIKeys)
+	r.Use(auth.DenyImpersonation)
+	r.With(auth.RequirePermission(auth.PermUserImpersonate)).Post("/", h.Impersonate)
+	r.With(auth.RequirePermission(auth.PermUserManage)).Get("/audit", h.ListAudit)
+	return r
+}
+
+func (h *ImpersonationHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	perms, ok := auth.PermissionsFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	var input dto.ImpersonateInput
+	if !decodeJSON(w, r, h.logger, &input) {
+		return
+	}
+
+	out, err := h.svc.Impersonate(r.Context(), actorID, perms, &input, clientInfo(r))
+	if err != nil {
+		writeError(w, r, h.logger, "impersonation refused", err, zap.Int("actor_id", actorID), zap.Int("user_id", input.UserID))
+		return
+	}
+
+	h.logger.Warn("impersonation started", zap.Int("actor_id", actorID), zap.Int("user_id", input.UserID), zap.String("reason", input.Reason))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (h *ImpersonationHandler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var filters [3]int
+	for i, name := range []string{"user_id", "actor_id", "limit"} {
+		v := query.Get(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, r, h.logger, "invalid audit filter", fmt.Errorf("%w: invalid %s", custom.ErrInvalidInput, name))
+			return
+		}
+		filters[i] = n
+	}
+
+	records, err := h.svc.ListAudit(r.Context(), filters[0], filters[1], filters[2])
+	if err != nil {
+		writeError(w, r, h.logger, "failed to list impersonation audit", err)
+		return
+	}
+
+	out := make([]dto.ImpersonationAuditOutput, 0, len(records))
+	for _, rec := range records {
+		out = append(out, dto.ImpersonationAuditOutput{
+			ID:        rec.ID,
+			ActorID:   rec.ActorID,
+			UserID:    rec.UserID,
+			SessionID: rec.SessionID,
+			Action:    rec.Action,
+			Method:    rec.Method,
+			Path:      rec.Path,
+			Status:    rec.Status,
+			IP:        rec.IP,
+			Reason:    rec.Reason,
+			CreatedAt: rec.CreatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(out)
+}
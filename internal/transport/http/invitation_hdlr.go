@@ -0,0 +1,170 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"net/http"
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	"product-catalog/internal/dto"
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/transport/problem"
+	"strconv"
+)
+
+type InvitationService interface {
+	CreateInvitation(ctx context.Context, actorID int, perms auth.Permissions, input *dto.CreateInvitationInput) (*dto.CreatedInvitationOutput, error)
+	ListInvitations(ctx context.Context, perms auth.Permissions) ([]dto.InvitationOutput, error)
+	ResendInvitation(ctx context.Context, perms auth.Permissions, id int) (*dto.CreatedInvitationOutput, error)
+	RevokeInvitation(ctx context.Context, perms auth.Permissions, id int) error
+	AcceptInvitation(ctx context.Context, input *dto.AcceptInvitationInput) (*domain.User, error)
+}
+
+type InvitationHandler struct {
+	svc            InvitationService
+	logger         *zap.Logger
+	authMiddleware func(http.Handler) http.Handler
+}
+
+func NewInvitationHandler(svc InvitationService, logger *zap.Logger, authMiddleware *auth.Middleware) *InvitationHandler {
+	return &InvitationHandler{svc: svc, logger: logger, authMiddleware: authMiddleware.AuthMiddleware}
+}
+
+func (h *InvitationHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Post("/accept", h.Accept)
+	r.Group(func(r chi.Router) {
+		r.Use(h.authMiddleware)
+		r.Use(auth.DenyImpersonation)
+		r.Use(auth.RequirePermission(auth.PermUserManage))
+		r.Post("/", h.Create)
+		r.Get("/", h.List)
+		r.Post("/{id}/resend", h.Resend)
+		r.Delete("/{id}", h.Revoke)
+	})
+	return r
+}
+
+func (h *InvitationHandler) Create(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
+		return
+	}
+	perms, ok := auth.PermissionsFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	var input dto.CreateInvitationInput
+	if !decodeJSON(w, r, h.logger, &input) {
+		return
+	}
+
+	out, err := h.svc.CreateInvitation(r.Context(), actorID, perms, &input)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to create invitation", err, zap.Int("actor_id", actorID))
+		return
+	}
+
+	h.logger.Info("invitation created", zap.Int("actor_id", actorID), zap.Int("invitation_id", out.ID), zap.String("role", string(out.Role)))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (h *InvitationHandler) List(w http.ResponseWriter, r *http.Request) {
+	perms, ok := auth.PermissionsFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	out, err := h.svc.ListInvitations(r.Context(), perms)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to list invitations", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (h *InvitationHandler) Resend(w http.ResponseWriter, r *http.Request) {
+	perms, ok := auth.PermissionsFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeError(w, r, h.logger, "invalid invitation id", fmt.Errorf("%w: invalid invitation id %q", custom.ErrInvalidInput, idStr))
+		return
+	}
+
+	out, err := h.svc.ResendInvitation(r.Context(), perms, id)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to resend invitation", err, zap.Int("invitation_id", id))
+		return
+	}
+
+	h.logger.Info("invitation reissued", zap.Int("invitation_id", id))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (h *InvitationHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	perms, ok := auth.PermissionsFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeError(w, r, h.logger, "invalid invitation id", fmt.Errorf("%w: invalid invitation id %q", custom.ErrInvalidInput, idStr))
+		return
+	}
+
+	if err = h.svc.RevokeInvitation(r.Context(), perms, id); err != nil {
+		writeError(w, r, h.logger, "failed to revoke invitation", err, zap.Int("invitation_id", id))
+		return
+	}
+
+	h.logger.Info("invitation revoked", zap.Int("invitation_id", id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *InvitationHandler) Accept(w http.ResponseWriter, r *http.Request) {
+	var input dto.AcceptInvitationInput
+	if !decodeJSON(w, r, h.logger, &input) {
+		return
+	}
+
+	u, err := h.svc.AcceptInvitation(r.Context(), &input)
+	if err != nil {
+		switch {
+		case errors.Is(err, custom.ErrNotFound):
+			err = problem.WithDetail(err, "invitation is invalid or expired")
+		case errors.Is(err, custom.ErrConflict):
+			err = problem.WithDetail(err, "username or email already taken")
+		}
+		writeError(w, r, h.logger, "failed to accept invitation", err)
+		return
+	}
+
+	h.logger.Info("invitation accepted", zap.Int("user_id", u.ID), zap.String("role", string(u.Role)))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(toUserOutput(u))
+}
@@ -1,7 +1,13 @@
 package http
 
 import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
 	"net/http"
+	"product-catalog/internal/auth"
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/transport/problem"
 	"time"
 
 	"go.uber.org/zap"
@@ -19,19 +25,68 @@ func (m *LoggingMiddleware) LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
+		// Идентификатор запроса возвращается клиенту и попадает в trace_id ошибок
+		reqID := middleware.GetReqID(r.Context())
+		if reqID != "" {
+			w.Header().Set(middleware.RequestIDHeader, reqID)
+		}
+
 		ww := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 
 		next.ServeHTTP(ww, r)
 
 		duration := time.Since(start)
 
-		m.logger.Info("HTTP request",
+		fields := []zap.Field{
 			zap.String("method", r.Method),
 			zap.String("path", r.URL.Path),
 			zap.Int("status", ww.statusCode),
 			zap.Duration("duration", duration),
 			zap.String("remote_addr", r.RemoteAddr),
 			zap.String("user_agent", r.UserAgent()),
-		)
+			zap.String("request_id", reqID),
+		}
+
+		// Запросы под имперсонацией пишем отдельным уровнем, чтобы их было легко найти.
+		if actor := ww.Header().Get(auth.ImpersonatedByHeader); actor != "" {
+			m.logger.Warn("HTTP request (impersonated)", append(fields, zap.String("impersonated_by", actor))...)
+			return
+		}
+		m.logger.Info("HTTP request", fields...)
+	})
+}
+
+type TenantResolver interface {
+	Resolve(ctx context.Context, host string) (int, bool, error)
+}
+
+// TenantMiddleware определяет арендатора по заголовку Host. Хост, закреплённый
+// за арендатором, фиксирует его для всего запроса; на остальных хостах действует
+// арендатор по умолчанию, который AuthMiddleware заменит арендатором из токена.
+type TenantMiddleware struct {
+	resolver TenantResolver
+	logger   *zap.Logger
+}
+
+func NewTenantMiddleware(resolver TenantResolver, logger *zap.Logger) *TenantMiddleware {
+	return &TenantMiddleware{resolver: resolver, logger: logger}
+}
+
+func (m *TenantMiddleware) TenantMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenantID, pinned, err := m.resolver.Resolve(r.Context(), r.Host)
+		if err != nil {
+			if errors.Is(err, custom.ErrNotFound) {
+				err = problem.WithDetail(err, "unknown tenant")
+			}
+			writeError(w, r, m.logger, "failed to resolve tenant", err, zap.String("host", r.Host))
+			return
+		}
+
+		ctx := auth.WithTenant(r.Context(), tenantID)
+		if pinned {
+			ctx = auth.WithHostTenant(r.Context(), tenantID)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
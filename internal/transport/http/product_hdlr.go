@@ -3,26 +3,38 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 	"mime/multipart"
 	"net/http"
 	"product-catalog/internal/auth"
 	"product-catalog/internal/domain"
+	"product-catalog/internal/dto"
+	custom "product-catalog/internal/errors"
 	"strconv"
 	"time"
 )
 
 type ProductService interface {
-	CreateProduct(ctx context.Context, product *domain.Product) (int, error)
-	GetProductByID(ctx context.Context, id int) (*domain.Product, error)
-	GetAllProducts(ctx context.Context) ([]domain.Product, error)
-	UpdateProductByID(ctx context.Context, id int, product *domain.Product) error
-	DeleteProductByID(ctx context.Context, id int) error
+	CreateProduct(ctx context.Context, requesterID int, product *domain.Product, perms auth.Permissions) (int, error)
+	GetProductByID(ctx context.Context, id int, perms auth.Permissions) (*domain.Product, error)
+	GetAllProducts(ctx context.Context, perms auth.Permissions) ([]domain.Product, error)
+	GetCatalogRevision(ctx context.Context) (domain.CatalogRevision, error)
+	UpdateProductByID(ctx context.Context, requesterID, id int, product *domain.Product, perms auth.Permissions) error
+	PatchProductByID(ctx context.Context, requesterID, id, version int, perms auth.Permissions,
+		apply func(current *domain.Product) (domain.ProductPatch, error)) (*domain.Product, error)
+	DeleteProductByID(ctx context.Context, requesterID, id, version int, perms auth.Permissions) error
+	ChangeStatus(ctx context.Context, requesterID, id, version int, status domain.ProductStatus, perms auth.Permissions) (*domain.Product, error)
+	ScheduleProduct(ctx context.Context, id, version int, schedule domain.ProductSchedule, perms auth.Permissions) (*domain.Product, error)
+	ListTrash(ctx context.Context, perms auth.Permissions) ([]domain.Product, error)
+	RestoreProduct(ctx context.Context, id int, perms auth.Permissions) (*domain.Product, error)
 }
 
 type FileService interface {
-	Upload(ctx context.Context, fh *multipart.FileHeader) (string, error)
+	Store(ctx context.Context, fh *multipart.FileHeader) (string, error)
+	URLForKey(ctx context.Context, key string) (string, error)
+	Delete(ctx context.Context, key string) error
 }
 
 type ProductHandler struct {
@@ -30,10 +42,15 @@ type ProductHandler struct {
 	fileSvc        FileService
 	logger         *zap.Logger
 	authMiddleware func(http.Handler) http.Handler
+	optionalAuth   func(http.Handler) http.Handler
+	idempotency    func(http.Handler) http.Handler
+	cache          CacheConfig
 }
 
-func NewProductHandler(productCvc ProductService, fileSvc FileService, logger *zap.Logger, authMiddleware *auth.Middleware) *ProductHandler {
-	return &ProductHandler{productSvc: productCvc, fileSvc: fileSvc, logger: logger, authMiddleware: authMiddleware.AuthMiddleware}
+func NewProductHandler(productCvc ProductService, fileSvc FileService, logger *zap.Logger, authMiddleware *auth.Middleware,
+	idempotency *IdempotencyMiddleware, cache CacheConfig) *ProductHandler {
+	return &ProductHandler{productSvc: productCvc, fileSvc: fileSvc, logger: logger, authMiddleware: authMiddleware.AuthMiddleware,
+		optionalAuth: authMiddleware.OptionalAuth, idempotency: idempotency.Idempotency, cache: cache}
 }
 
 func (h *ProductHandler) Routes() chi.Router {
@@ -41,101 +58,170 @@ func (h *ProductHandler) Routes() chi.Router {
 
 	r.Group(func(r chi.Router) {
 		r.Use(h.authMiddleware)
-		r.Post("/", h.CreateProduct)
+		// Свои товары меняет product:write, любые — product:manage; остальное решает политика сервиса
+		r.Use(auth.RequireAnyPermission(auth.PermProductWrite, auth.PermProductManage))
+		// Повтор создания по таймауту не должен плодить товары и картинки
+		r.With(h.idempotency).Post("/", h.CreateProduct)
+		r.With(h.idempotency).Post("/images", h.UploadImage)
 		r.Put("/{id}", h.UpdateProductByID)
+		r.Patch("/{id}", h.PatchProductByID)
 		r.Delete("/{id}", h.DeleteProductByID)
+		r.Put("/{id}/status", h.ChangeProductStatus)
 	})
 
-	r.Get("/", h.GetAllProducts)
-	r.Get("/{id}", h.GetProductByID)
+	// Корзина и расписание публикации доступны только администраторам
+	r.Group(func(r chi.Router) {
+		r.Use(h.authMiddleware)
+		r.Use(auth.RequirePermission(auth.PermProductManage))
+		r.Get("/trash", h.ListTrash)
+		r.Post("/trash/{id}/restore", h.RestoreProduct)
+		r.Put("/{id}/schedule", h.ScheduleProduct)
+	})
+
+	// Каталог публичный; аутентифицированные редакторы видят в нём и неопубликованные товары
+	r.Group(func(r chi.Router) {
+		r.Use(h.optionalAuth)
+		r.Get("/", h.GetAllProducts)
+		r.Get("/{id}", h.GetProductByID)
+	})
 	return r
 }
 
 func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		h.logger.Warn("failed to parse multipart form", zap.Error(err))
-		http.Error(w, "invalid request", http.StatusBadRequest)
+	requesterID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
 		return
 	}
 
-	title := r.FormValue("title")
-	if title == "" {
-		h.logger.Warn("invalid request", zap.String("title", title))
-		http.Error(w, "title is required", http.StatusBadRequest)
+	perms, ok := auth.PermissionsFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
 		return
 	}
 
-	priceStr := r.FormValue("price")
-	if priceStr == "" {
-		h.logger.Warn("invalid request", zap.String("price", priceStr))
-		http.Error(w, "price is required", http.StatusBadRequest)
-	}
-
-	price, err := strconv.ParseFloat(priceStr, 64)
-	if priceStr == "" {
-		h.logger.Warn("invalid request", zap.String("price", priceStr))
-		http.Error(w, "price is required", http.StatusBadRequest)
+	var input dto.CreateProductInput
+	if !decodeBody(w, r, h.logger, &input) {
 		return
 	}
 
-	description := r.FormValue("description")
-	if description == "" {
-		h.logger.Warn("invalid request", zap.String("description", description))
-		http.Error(w, "description is required", http.StatusBadRequest)
+	imageURL, uploaded, err := h.resolveImage(r.Context(), input.Image, &input.ImageKey)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to resolve image", err)
 		return
 	}
+	input.ImageURL = imageURL
 
-	files := r.MultipartForm.File["image"]
-	if len(files) == 0 {
-		h.logger.Warn("request without image")
-		http.Error(w, "invalid request", http.StatusBadRequest)
-		return
+	available := true
+	if input.Available != nil {
+		available = *input.Available
+	}
+	prod := &domain.Product{
+		Title:       input.Title,
+		Price:       input.Price,
+		Description: input.Description,
+		Available:   available,
+		ImageURL:    input.ImageURL,
+		CreatedAt:   time.Now(),
 	}
-	fileHeader := files[0]
 
-	key, err := h.fileSvc.Upload(r.Context(), fileHeader)
+	id, err := h.productSvc.CreateProduct(r.Context(), requesterID, prod, perms)
 	if err != nil {
-		h.logger.Error("failed to upload image", zap.Error(err))
-		http.Error(w, "upload error", http.StatusInternalServerError)
+		h.discardUpload(r.Context(), uploaded)
+		writeError(w, r, h.logger, "failed to create product", err, zap.Int("user_id", requesterID))
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(map[string]int{"id": id})
+	if err != nil {
+		h.logger.Warn("failed to encode response", zap.Error(err))
+	}
+}
 
-	//url, err := h.fileSvc.GetPresignedURL(r.Context(), key)
-	//if err != nil {
-	//	h.logger.Error("failed to get presigned url", zap.Error(err))
-	//	http.Error(w, "upload error", http.StatusInternalServerError)
-	//	return
-	//}
-
-	prod := &domain.Product{
-		Title:       title,
-		Price:       int(price),
-		Description: description,
-		ImageURL:    key,
-		CreatedAt:   time.Now(),
+// UploadImage сохраняет картинку отдельно от товара; возвращённый ключ передаётся
+// в image_key JSON-запроса на создание или изменение товара
+func (h *ProductHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
+	var input dto.UploadImageInput
+	if !decodeForm(w, r, h.logger, &input) {
+		return
 	}
 
-	id, err := h.productSvc.CreateProduct(r.Context(), prod)
+	key, err := h.fileSvc.Store(r.Context(), input.Image)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to store image", err)
+		return
+	}
+	url, err := h.fileSvc.URLForKey(r.Context(), key)
 	if err != nil {
-		h.logger.Error("failed to create product", zap.Error(err))
-		http.Error(w, "creation error", http.StatusInternalServerError)
+		writeError(w, r, h.logger, "failed to presign image", err, zap.String("key", key))
 		return
 	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	err = json.NewEncoder(w).Encode(map[string]int{"id": id})
+	err = json.NewEncoder(w).Encode(map[string]string{"key": key, "url": url})
 	if err != nil {
 		h.logger.Warn("failed to encode response", zap.Error(err))
-		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+// resolveImage возвращает URL картинки: файл из формы загружается, ключ проверяется
+// в хранилище. Файл важнее ключа, если пришло и то и другое. uploaded — ключ
+// загруженного файла; его нужно удалить, если товар так и не сохранится.
+func (h *ProductHandler) resolveImage(ctx context.Context, fh *multipart.FileHeader, key *string) (url, uploaded string, err error) {
+	if fh == nil {
+		url, err = h.fileSvc.URLForKey(ctx, *key)
+		return url, "", err
+	}
+	if uploaded, err = h.fileSvc.Store(ctx, fh); err != nil {
+		return "", "", err
+	}
+	if url, err = h.fileSvc.URLForKey(ctx, uploaded); err != nil {
+		h.discardUpload(ctx, uploaded)
+		return "", "", err
+	}
+	return url, uploaded, nil
+}
+
+// discardUpload удаляет файл, загруженный для отклонённого запроса: права на товар
+// проверяет сервис уже после загрузки, и без этого 403 или 404 оставляли бы в
+// хранилище объект, на который не ссылается ни один товар.
+func (h *ProductHandler) discardUpload(ctx context.Context, key string) {
+	if key == "" {
 		return
 	}
+	if err := h.fileSvc.Delete(context.WithoutCancel(ctx), key); err != nil {
+		h.logger.Warn("failed to delete orphaned upload", zap.String("key", key), zap.Error(err))
+	}
 }
 
+// GetAllProducts отдаёт список товаров с ETag по ревизии каталога. Ревизия читается
+// до товаров: если между чтениями товар изменится, клиент получит более новый список
+// со старым ETag и при следующем запросе просто скачает его заново.
 func (h *ProductHandler) GetAllProducts(w http.ResponseWriter, r *http.Request) {
-	products, err := h.productSvc.GetAllProducts(r.Context())
+	rev, err := h.productSvc.GetCatalogRevision(r.Context())
 	if err != nil {
-		h.logger.Error("failed to get products", zap.Error(err))
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		writeError(w, r, h.logger, "failed to get catalog revision", err)
+		return
+	}
+	perms, viewer := auth.PermissionsFromContext(r.Context())
+	tag := `W/"` + strconv.FormatInt(rev.Revision, 10) + `"`
+	if viewer {
+		// Список редактора отличается от публичного при той же ревизии
+		tag = `W/"` + strconv.FormatInt(rev.Revision, 10) + `-all"`
+		setCacheHeaders(w, h.cache, privateCache, tag, rev.UpdatedAt)
+	} else {
+		setCacheHeaders(w, h.cache, h.cache.ProductList, tag, rev.UpdatedAt, surrogateKey(r.Context(), "products"))
+	}
+	if notModified(r, tag, rev.UpdatedAt) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	products, err := h.productSvc.GetAllProducts(r.Context(), perms)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to get products", err)
 		return
 	}
 
@@ -144,30 +230,37 @@ func (h *ProductHandler) GetAllProducts(w http.ResponseWriter, r *http.Request)
 	err = json.NewEncoder(w).Encode(products)
 	if err != nil {
 		h.logger.Warn("failed to encode response", zap.Error(err))
-		http.Error(w, "encode error", http.StatusInternalServerError)
-		return
 	}
 }
 
 func (h *ProductHandler) GetProductByID(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	if idStr == "" {
-		h.logger.Warn("invalid request with empty id", zap.String("id", idStr))
-		http.Error(w, "id is required", http.StatusBadRequest)
+		writeError(w, r, h.logger, "invalid request with empty id", fmt.Errorf("%w: id is required", custom.ErrInvalidInput))
 		return
 	}
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		h.logger.Warn("invalid product id", zap.String("id", idStr), zap.Error(err))
-		http.Error(w, "invalid product id", http.StatusBadRequest)
+		writeError(w, r, h.logger, "invalid product id", fmt.Errorf("%w: invalid product id %q", custom.ErrInvalidInput, idStr))
 		return
 	}
 
-	product, err := h.productSvc.GetProductByID(r.Context(), id)
+	perms, viewer := auth.PermissionsFromContext(r.Context())
+	product, err := h.productSvc.GetProductByID(r.Context(), id, perms)
 	if err != nil {
-		h.logger.Error("failed to get product", zap.Error(err))
-		http.Error(w, "get product error", http.StatusInternalServerError)
+		writeError(w, r, h.logger, "failed to get product", err, zap.Int("product_id", id))
+		return
+	}
+
+	if viewer {
+		setCacheHeaders(w, h.cache, privateCache, etag(product.Version), product.UpdatedAt)
+	} else {
+		setCacheHeaders(w, h.cache, h.cache.Product, etag(product.Version), product.UpdatedAt,
+			surrogateKey(r.Context(), "product-"+strconv.Itoa(product.ID)))
+	}
+	if notModified(r, etag(product.Version), product.UpdatedAt) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
@@ -176,121 +269,336 @@ func (h *ProductHandler) GetProductByID(w http.ResponseWriter, r *http.Request)
 	err = json.NewEncoder(w).Encode(product)
 	if err != nil {
 		h.logger.Warn("failed to encode response", zap.Error(err))
-		http.Error(w, "encode error", http.StatusInternalServerError)
 	}
 }
 
+// surrogateKey добавляет к ключу арендатора: CDN общий для всех хостов каталога.
+// Изменение товара сбрасывает ключи product-<id> и products.
+func surrogateKey(ctx context.Context, name string) string {
+	tenantID, _ := auth.TenantIDFromContext(ctx)
+	return "tenant-" + strconv.Itoa(tenantID) + "-" + name
+}
+
 func (h *ProductHandler) UpdateProductByID(w http.ResponseWriter, r *http.Request) {
+	requesterID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	perms, ok := auth.PermissionsFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
+		return
+	}
+
 	idStr := chi.URLParam(r, "id")
 	if idStr == "" {
-		h.logger.Warn("invalid request with empty id", zap.String("id", idStr))
-		http.Error(w, "id is required", http.StatusBadRequest)
+		writeError(w, r, h.logger, "invalid request with empty id", fmt.Errorf("%w: id is required", custom.ErrInvalidInput))
 		return
 	}
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		h.logger.Warn("invalid product id", zap.String("id", idStr), zap.Error(err))
-		http.Error(w, "invalid product id", http.StatusBadRequest)
+		writeError(w, r, h.logger, "invalid product id", fmt.Errorf("%w: invalid product id %q", custom.ErrInvalidInput, idStr))
+		return
+	}
+
+	version, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, h.logger, "missing or invalid If-Match", err, zap.Int("product_id", id))
+		return
 	}
 
-	if err = r.ParseMultipartForm(10 << 20); err != nil {
-		h.logger.Warn("failed to parse multipart form", zap.Error(err))
-		http.Error(w, "invalid request", http.StatusBadRequest)
+	var input dto.UpdateProductInput
+	if !decodeBody(w, r, h.logger, &input) {
 		return
 	}
 
-	title := r.FormValue("title")
-	if title == "" {
-		h.logger.Warn("invalid request", zap.String("title", title))
-		http.Error(w, "title is required", http.StatusBadRequest)
+	var uploaded string
+	if input.Image != nil || input.ImageKey != nil {
+		var imageURL string
+		imageURL, uploaded, err = h.resolveImage(r.Context(), input.Image, input.ImageKey)
+		if err != nil {
+			writeError(w, r, h.logger, "failed to resolve image", err)
+			return
+		}
+		input.ImageURL = &imageURL
 	}
 
-	priceStr := r.FormValue("price")
-	if priceStr == "" {
-		h.logger.Warn("invalid request", zap.String("price", priceStr))
-		http.Error(w, "price is required", http.StatusBadRequest)
+	product := &domain.Product{
+		Title:       *input.Title,
+		Price:       *input.Price,
+		Description: *input.Description,
+		Available:   *input.Available,
+		Version:     version,
+	}
+	if input.ImageURL != nil {
+		product.ImageURL = *input.ImageURL
+	}
+	err = h.productSvc.UpdateProductByID(r.Context(), requesterID, id, product, perms)
+	if err != nil {
+		h.discardUpload(r.Context(), uploaded)
+		writeError(w, r, h.logger, "failed to update product", err, zap.Int("product_id", id))
+		return
 	}
+	w.Header().Set("ETag", etag(product.Version))
+	w.WriteHeader(http.StatusOK)
+	return
+}
 
-	price, err := strconv.ParseFloat(priceStr, 64)
-	if priceStr == "" {
-		h.logger.Warn("invalid request", zap.String("price", priceStr))
-		http.Error(w, "price is required", http.StatusBadRequest)
+// PatchProductByID частично изменяет товар: application/merge-patch+json (RFC 7396)
+// или application/json-patch+json (RFC 6902). Патч применяется к текущему товару,
+// и в БД записываются только изменившиеся поля.
+func (h *ProductHandler) PatchProductByID(w http.ResponseWriter, r *http.Request) {
+	requesterID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
 		return
 	}
 
-	description := r.FormValue("description")
-	if description == "" {
-		h.logger.Warn("invalid request", zap.String("description", description))
-		http.Error(w, "description is required", http.StatusBadRequest)
+	perms, ok := auth.PermissionsFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
+		return
 	}
 
-	availableStr := r.FormValue("available")
-	available, err := strconv.ParseBool(availableStr)
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		available = false
+		writeError(w, r, h.logger, "invalid product id", fmt.Errorf("%w: invalid product id %q", custom.ErrInvalidInput, idStr))
+		return
 	}
 
-	files := r.MultipartForm.File["image"]
-	if len(files) != 0 {
-		fileHeader := files[0]
-		imageURL, err := h.fileSvc.Upload(r.Context(), fileHeader)
-		if err != nil {
-			h.logger.Error("failed to upload image", zap.Error(err))
-			http.Error(w, "upload error", http.StatusInternalServerError)
-			return
-		}
-		product := &domain.Product{
-			Title:       title,
-			Price:       int(price),
-			Description: description,
-			Available:   available,
-			ImageURL:    imageURL,
+	version, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, h.logger, "missing or invalid If-Match", err, zap.Int("product_id", id))
+		return
+	}
+
+	body, mediaType, ok := readPatch(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	product, err := h.productSvc.PatchProductByID(r.Context(), requesterID, id, version, perms, func(current *domain.Product) (domain.ProductPatch, error) {
+		doc := dto.PatchProductInput{Title: &current.Title, Price: &current.Price, Description: &current.Description, Available: &current.Available}
+		var input dto.PatchProductInput
+		if err := applyPatch(mediaType, body, doc, &input); err != nil {
+			return domain.ProductPatch{}, err
 		}
-		err = h.productSvc.UpdateProductByID(r.Context(), id, product)
+		return h.productChanges(r.Context(), current, &input)
+	})
+	if err != nil {
+		writeError(w, r, h.logger, "failed to patch product", err, zap.Int("product_id", id))
+		return
+	}
+
+	w.Header().Set("ETag", etag(product.Version))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(product)
+	if err != nil {
+		h.logger.Warn("failed to encode response", zap.Error(err))
+	}
+}
+
+// productChanges оставляет в патче только поля, отличающиеся от текущего товара
+func (h *ProductHandler) productChanges(ctx context.Context, current *domain.Product, input *dto.PatchProductInput) (domain.ProductPatch, error) {
+	var p domain.ProductPatch
+	if *input.Title != current.Title {
+		p.Title = input.Title
+	}
+	if *input.Price != current.Price {
+		p.Price = input.Price
+	}
+	if *input.Description != current.Description {
+		p.Description = input.Description
+	}
+	if *input.Available != current.Available {
+		p.Available = input.Available
+	}
+	if input.ImageKey != nil {
+		imageURL, err := h.fileSvc.URLForKey(ctx, *input.ImageKey)
 		if err != nil {
-			h.logger.Error("failed to update product", zap.Error(err))
-			http.Error(w, "update error", http.StatusInternalServerError)
-			return
+			return domain.ProductPatch{}, err
 		}
-		w.WriteHeader(http.StatusOK)
+		p.ImageURL = &imageURL
+	}
+	return p, nil
+}
+
+func (h *ProductHandler) DeleteProductByID(w http.ResponseWriter, r *http.Request) {
+	requesterID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
 		return
 	}
 
-	product := &domain.Product{
-		Title:       title,
-		Price:       int(price),
-		Description: description,
-		Available:   available,
+	perms, ok := auth.PermissionsFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	if idStr == "" {
+		writeError(w, r, h.logger, "invalid request with empty id", fmt.Errorf("%w: id is required", custom.ErrInvalidInput))
+		return
 	}
-	err = h.productSvc.UpdateProductByID(r.Context(), id, product)
+
+	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		h.logger.Error("failed to update product", zap.Error(err))
-		http.Error(w, "update error", http.StatusInternalServerError)
+		writeError(w, r, h.logger, "invalid product id", fmt.Errorf("%w: invalid product id %q", custom.ErrInvalidInput, idStr))
+		return
+	}
+	version, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, h.logger, "missing or invalid If-Match", err, zap.Int("product_id", id))
+		return
+	}
+	err = h.productSvc.DeleteProductByID(r.Context(), requesterID, id, version, perms)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to delete product", err, zap.Int("product_id", id))
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 	return
 }
 
-func (h *ProductHandler) DeleteProductByID(w http.ResponseWriter, r *http.Request) {
+func (h *ProductHandler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	perms, ok := auth.PermissionsFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	products, err := h.productSvc.ListTrash(r.Context(), perms)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to list trash", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(products)
+	if err != nil {
+		h.logger.Warn("failed to encode response", zap.Error(err))
+	}
+}
+
+func (h *ProductHandler) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	perms, ok := auth.PermissionsFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
+		return
+	}
+
 	idStr := chi.URLParam(r, "id")
-	if idStr == "" {
-		h.logger.Warn("invalid request with empty id", zap.String("id", idStr))
-		http.Error(w, "id is required", http.StatusBadRequest)
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeError(w, r, h.logger, "invalid product id", fmt.Errorf("%w: invalid product id %q", custom.ErrInvalidInput, idStr))
+		return
+	}
+
+	product, err := h.productSvc.RestoreProduct(r.Context(), id, perms)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to restore product", err, zap.Int("product_id", id))
+		return
 	}
 
+	w.Header().Set("ETag", etag(product.Version))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(product)
+	if err != nil {
+		h.logger.Warn("failed to encode response", zap.Error(err))
+	}
+}
+
+// ChangeProductStatus переводит товар по жизненному циклу: черновик, проверка,
+// публикация, архив
+func (h *ProductHandler) ChangeProductStatus(w http.ResponseWriter, r *http.Request) {
+	requesterID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	perms, ok := auth.PermissionsFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		h.logger.Warn("invalid product id", zap.String("id", idStr), zap.Error(err))
-		http.Error(w, "invalid product id", http.StatusBadRequest)
+		writeError(w, r, h.logger, "invalid product id", fmt.Errorf("%w: invalid product id %q", custom.ErrInvalidInput, idStr))
+		return
 	}
-	err = h.productSvc.DeleteProductByID(r.Context(), id)
+
+	version, err := ifMatch(r)
 	if err != nil {
-		h.logger.Error("failed to delete product", zap.Error(err))
-		http.Error(w, "delete error", http.StatusInternalServerError)
+		writeError(w, r, h.logger, "missing or invalid If-Match", err, zap.Int("product_id", id))
 		return
 	}
+
+	var input dto.ProductStatusInput
+	if !decodeJSON(w, r, h.logger, &input) {
+		return
+	}
+
+	product, err := h.productSvc.ChangeStatus(r.Context(), requesterID, id, version, domain.ProductStatus(input.Status), perms)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to change product status", err, zap.Int("product_id", id), zap.String("status", input.Status))
+		return
+	}
+	h.writeProduct(w, product)
+}
+
+// ScheduleProduct задаёт время автоматической публикации и снятия с публикации
+func (h *ProductHandler) ScheduleProduct(w http.ResponseWriter, r *http.Request) {
+	perms, ok := auth.PermissionsFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeError(w, r, h.logger, "invalid product id", fmt.Errorf("%w: invalid product id %q", custom.ErrInvalidInput, idStr))
+		return
+	}
+
+	version, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, h.logger, "missing or invalid If-Match", err, zap.Int("product_id", id))
+		return
+	}
+
+	var input dto.ProductScheduleInput
+	if !decodeJSON(w, r, h.logger, &input) {
+		return
+	}
+
+	schedule := domain.ProductSchedule{PublishAt: input.PublishAt, UnpublishAt: input.UnpublishAt}
+	product, err := h.productSvc.ScheduleProduct(r.Context(), id, version, schedule, perms)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to schedule product", err, zap.Int("product_id", id))
+		return
+	}
+	h.writeProduct(w, product)
+}
+
+// writeProduct отдаёт изменённый товар с его новой версией в ETag
+func (h *ProductHandler) writeProduct(w http.ResponseWriter, product *domain.Product) {
+	w.Header().Set("ETag", etag(product.Version))
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	return
+	err := json.NewEncoder(w).Encode(product)
+	if err != nil {
+		h.logger.Warn("failed to encode response", zap.Error(err))
+	}
 }
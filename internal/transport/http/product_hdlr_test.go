@@ -0,0 +1,246 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/service/file"
+	h "product-catalog/internal/transport/http"
+)
+
+// fakeProducts запоминает созданный товар или отказывает с err; остальные методы
+// сервиса тесты не вызывают
+type fakeProducts struct {
+	h.ProductService
+	created *domain.Product
+	err     error
+}
+
+func (s *fakeProducts) CreateProduct(_ context.Context, requesterID int, p *domain.Product, _ auth.Permissions) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	p.CreatedBy = requesterID
+	s.created = p
+	return 1, nil
+}
+
+func (s *fakeProducts) UpdateProductByID(context.Context, int, int, *domain.Product, auth.Permissions) error {
+	return s.err
+}
+
+// memStorage — хранилище файлов в памяти
+type memStorage struct {
+	objects map[string][]byte
+}
+
+func (s *memStorage) Upload(_ context.Context, key string, body io.Reader, _ int64, _ string) error {
+	b, err := io.ReadAll(body)
+	s.objects[key] = b
+	return err
+}
+
+func (s *memStorage) GetPresignedURL(_ context.Context, key string, _ time.Duration) (string, error) {
+	return "http://minio:9000/uploads/" + key + "?X-Amz-Signature=test", nil
+}
+
+func (s *memStorage) Download(context.Context, string) (io.ReadCloser, error) { return nil, nil }
+
+func (s *memStorage) Delete(_ context.Context, key string) error {
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *memStorage) Exists(_ context.Context, key string) (bool, error) {
+	_, ok := s.objects[key]
+	return ok, nil
+}
+
+// pngHeader достаточно, чтобы файл определился как image/png
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func newProductHandler(svc h.ProductService) (*h.ProductHandler, *memStorage) {
+	sto := &memStorage{objects: map[string][]byte{
+		"tenants/1/lamp.png": pngHeader,
+		"tenants/2/lamp.png": pngHeader,
+	}}
+	return h.NewProductHandler(svc, file.NewFileService(sto), zap.NewNop(), nil, nil, h.CacheConfig{}), sto
+}
+
+// sellerRequest — запрос продавца арендатора 1, как его видит обработчик после AuthMiddleware
+func sellerRequest(method, target string, body io.Reader, contentType string) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	req.Header.Set("Content-Type", contentType)
+	ctx := auth.WithTenant(req.Context(), 1)
+	ctx = auth.WithUserContext(ctx, 7, auth.RoleUser, auth.Permissions{auth.PermProductWrite})
+	return req.WithContext(ctx)
+}
+
+func multipartBody(t *testing.T, fields map[string]string, image []byte) (*bytes.Buffer, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for name, v := range fields {
+		if err := mw.WriteField(name, v); err != nil {
+			t.Fatalf("write field: %v", err)
+		}
+	}
+	if image != nil {
+		fw, err := mw.CreateFormFile("image", "lamp.png")
+		if err != nil {
+			t.Fatalf("create form file: %v", err)
+		}
+		_, _ = fw.Write(image)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatalf("close multipart: %v", err)
+	}
+	return &buf, mw.FormDataContentType()
+}
+
+func TestCreateProductAvailable(t *testing.T) {
+	form, formType := multipartBody(t, map[string]string{"title": "lamp", "price": "100", "description": "desk lamp", "image_key": "tenants/1/lamp.png"}, nil)
+	formOff, formOffType := multipartBody(t, map[string]string{"title": "lamp", "price": "100", "description": "desk lamp", "image_key": "tenants/1/lamp.png", "available": "false"}, nil)
+
+	tests := []struct {
+		name        string
+		body        io.Reader
+		contentType string
+		want        bool
+	}{
+		{"json available", strings.NewReader(`{"title":"lamp","price":100,"description":"desk lamp","image_key":"tenants/1/lamp.png","available":true}`), "application/json", true},
+		{"json unavailable", strings.NewReader(`{"title":"lamp","price":100,"description":"desk lamp","image_key":"tenants/1/lamp.png","available":false}`), "application/json", false},
+		{"json omitted defaults to available", strings.NewReader(`{"title":"lamp","price":100,"description":"desk lamp","image_key":"tenants/1/lamp.png"}`), "application/json", true},
+		{"multipart omitted defaults to available", form, formType, true},
+		{"multipart unavailable", formOff, formOffType, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeProducts{}
+			hdlr, _ := newProductHandler(svc)
+			rec := httptest.NewRecorder()
+			hdlr.CreateProduct(rec, sellerRequest(http.MethodPost, "/products", tt.body, tt.contentType))
+
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("status = %d, body %s; want 201", rec.Code, rec.Body)
+			}
+			if svc.created == nil || svc.created.Available != tt.want {
+				t.Fatalf("created = %+v; want available %v", svc.created, tt.want)
+			}
+			var out map[string]int
+			if err := json.NewDecoder(rec.Body).Decode(&out); err != nil || out["id"] != 1 {
+				t.Errorf("response = %v, %v; want id 1", out, err)
+			}
+		})
+	}
+}
+
+func TestCreateProductBody(t *testing.T) {
+	fields := map[string]string{"title": "lamp", "price": "100", "description": "desk lamp"}
+	upload, uploadType := multipartBody(t, fields, pngHeader)
+	// Файл важнее ключа, даже если ключ чужой
+	both, bothType := multipartBody(t, map[string]string{"title": "lamp", "price": "100", "description": "desk lamp", "image_key": "tenants/2/lamp.png"}, pngHeader)
+	noImage, noImageType := multipartBody(t, fields, nil)
+	jsonWithKey := func(key string) io.Reader {
+		return strings.NewReader(`{"title":"lamp","price":100,"description":"desk lamp","image_key":"` + key + `"}`)
+	}
+
+	tests := []struct {
+		name        string
+		body        io.Reader
+		contentType string
+		wantStatus  int
+		wantImage   string
+		wantStored  int
+	}{
+		{"json with own key", jsonWithKey("tenants/1/lamp.png"), "application/json", http.StatusCreated, "/uploads/tenants/1/lamp.png?", 2},
+		{"json with charset", jsonWithKey("tenants/1/lamp.png"), "application/json; charset=utf-8", http.StatusCreated, "/uploads/tenants/1/lamp.png?", 2},
+		{"multipart upload", upload, uploadType, http.StatusCreated, "/uploads/tenants/1/", 3},
+		{"multipart file wins over key", both, bothType, http.StatusCreated, "/uploads/tenants/1/", 3},
+		{"multipart without image", noImage, noImageType, http.StatusUnprocessableEntity, "", 2},
+		{"json key of other tenant", jsonWithKey("tenants/2/lamp.png"), "application/json", http.StatusBadRequest, "", 2},
+		{"json key escaping tenant", jsonWithKey("tenants/1/../2/lamp.png"), "application/json", http.StatusBadRequest, "", 2},
+		{"json unknown key", jsonWithKey("tenants/1/desk.png"), "application/json", http.StatusBadRequest, "", 2},
+		{"malformed json", strings.NewReader(`{"title":`), "application/json", http.StatusBadRequest, "", 2},
+		{"form urlencoded", strings.NewReader("title=lamp&price=100"), "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType, "", 2},
+		{"plain text", strings.NewReader("lamp"), "text/plain", http.StatusUnsupportedMediaType, "", 2},
+		{"no content type", jsonWithKey("tenants/1/lamp.png"), "", http.StatusUnsupportedMediaType, "", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeProducts{}
+			hdlr, sto := newProductHandler(svc)
+			rec := httptest.NewRecorder()
+			hdlr.CreateProduct(rec, sellerRequest(http.MethodPost, "/products", tt.body, tt.contentType))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, body %s; want %d", rec.Code, rec.Body, tt.wantStatus)
+			}
+			if len(sto.objects) != tt.wantStored {
+				t.Errorf("stored %d objects; want %d", len(sto.objects), tt.wantStored)
+			}
+			if tt.wantImage == "" {
+				if svc.created != nil {
+					t.Errorf("product created on rejected request: %+v", svc.created)
+				}
+				return
+			}
+			if svc.created == nil || !strings.Contains(svc.created.ImageURL, tt.wantImage) {
+				t.Fatalf("created = %+v; want image URL containing %q", svc.created, tt.wantImage)
+			}
+		})
+	}
+}
+
+func TestRejectedProductDiscardsUpload(t *testing.T) {
+	fields := map[string]string{"title": "lamp", "price": "100", "description": "desk lamp", "available": "true"}
+
+	tests := []struct {
+		name       string
+		method     string
+		err        error
+		wantStatus int
+	}{
+		{"create forbidden", http.MethodPost, custom.ErrForbidden, http.StatusForbidden},
+		{"update forbidden", http.MethodPut, custom.ErrForbidden, http.StatusForbidden},
+		{"update missing product", http.MethodPut, custom.ErrNotFound, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hdlr, sto := newProductHandler(&fakeProducts{err: tt.err})
+			body, contentType := multipartBody(t, fields, pngHeader)
+			req := sellerRequest(tt.method, "/products/3", body, contentType)
+
+			rec := httptest.NewRecorder()
+			if tt.method == http.MethodPost {
+				hdlr.CreateProduct(rec, req)
+			} else {
+				req.Header.Set("If-Match", `"1"`)
+				rctx := chi.NewRouteContext()
+				rctx.URLParams.Add("id", "3")
+				hdlr.UpdateProductByID(rec, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, body %s; want %d", rec.Code, rec.Body, tt.wantStatus)
+			}
+			// Остаются только две заранее загруженные картинки
+			if len(sto.objects) != 2 {
+				t.Errorf("stored %d objects; want 2", len(sto.objects))
+			}
+		})
+	}
+}
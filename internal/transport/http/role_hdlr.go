@@ -0,0 +1,92 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"net/http"
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	"product-catalog/internal/dto"
+)
+
+type RoleService interface {
+	GetAllRoles(ctx context.Context) ([]domain.Role, error)
+	SaveRole(ctx context.Context, role *domain.Role) error
+	DeleteRole(ctx context.Context, name auth.Role) error
+}
+
+type RoleHandler struct {
+	svc            RoleService
+	logger         *zap.Logger
+	authMiddleware func(http.Handler) http.Handler
+}
+
+func NewRoleHandler(svc RoleService, logger *zap.Logger, authMiddleware *auth.Middleware) *RoleHandler {
+	return &RoleHandler{svc: svc, logger: logger, authMiddleware: authMiddleware.AuthMiddleware}
+}
+
+func (h *RoleHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(h.authMiddleware)
+	r.Use(auth.RequirePermission(auth.PermRoleManage))
+	r.Get("/", h.GetAllRoles)
+	r.Get("/permissions", h.GetAllPermissions)
+	r.Put("/{name}", h.SaveRole)
+	r.Delete("/{name}", h.DeleteRole)
+	return r
+}
+
+func (h *RoleHandler) GetAllRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.svc.GetAllRoles(r.Context())
+	if err != nil {
+		writeError(w, r, h.logger, "failed to get roles", err)
+		return
+	}
+
+	out := make([]dto.RoleOutput, 0, len(roles))
+	for _, role := range roles {
+		out = append(out, dto.RoleOutput{Name: role.Name, Description: role.Description, Permissions: role.Permissions})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (h *RoleHandler) GetAllPermissions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(auth.AllPermissions)
+}
+
+func (h *RoleHandler) SaveRole(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var input dto.SaveRoleInput
+	if !decodeJSON(w, r, h.logger, &input) {
+		return
+	}
+
+	role := &domain.Role{Name: auth.Role(name), Description: input.Description, Permissions: input.Permissions}
+	if err := h.svc.SaveRole(r.Context(), role); err != nil {
+		writeError(w, r, h.logger, "failed to save role", err, zap.String("role", name))
+		return
+	}
+
+	h.logger.Info("role saved", zap.String("role", name), zap.Any("permissions", input.Permissions))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *RoleHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	if err := h.svc.DeleteRole(r.Context(), auth.Role(name)); err != nil {
+		writeError(w, r, h.logger, "failed to delete role", err, zap.String("role", name))
+		return
+	}
+
+	h.logger.Info("role deleted", zap.String("role", name))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,107 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"net/http"
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	"product-catalog/internal/dto"
+	custom "product-catalog/internal/errors"
+)
+
+type SessionService interface {
+	ListSessions(ctx context.Context, userID int) ([]domain.Session, error)
+	RevokeSession(ctx context.Context, userID int, sessionID string) error
+	RevokeOtherSessions(ctx context.Context, userID int, currentID string) error
+}
+
+type SessionHandler struct {
+	svc            SessionService
+	logger         *zap.Logger
+	authMiddleware func(http.Handler) http.Handler
+}
+
+func NewSessionHandler(svc SessionService, logger *zap.Logger, authMiddleware *auth.Middleware) *SessionHandler {
+	return &SessionHandler{svc: svc, logger: logger, authMiddleware: authMiddleware.AuthMiddleware}
+}
+
+func (h *SessionHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(h.authMiddleware)
+	r.Use(auth.DenyAPIKeys)
+	r.Get("/", h.ListSessions)
+	r.Delete("/", h.RevokeOtherSessions)
+	r.Delete("/{id}", h.RevokeSession)
+	return r
+}
+
+func (h *SessionHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
+		return
+	}
+	currentID, _ := auth.SessionIDFromContext(r.Context())
+
+	sessions, err := h.svc.ListSessions(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to list sessions", err, zap.Int("user_id", userID))
+		return
+	}
+
+	out := make([]dto.SessionOutput, 0, len(sessions))
+	for _, s := range sessions {
+		out = append(out, dto.SessionOutput{
+			ID:             s.ID,
+			Device:         s.Device,
+			IP:             s.IP,
+			UserAgent:      s.UserAgent,
+			CreatedAt:      s.CreatedAt,
+			LastSeenAt:     s.LastSeenAt,
+			Current:        s.ID == currentID,
+			ImpersonatedBy: s.ImpersonatorID,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (h *SessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	sessionID := chi.URLParam(r, "id")
+	err := h.svc.RevokeSession(r.Context(), userID, sessionID)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to revoke session", err, zap.Int("user_id", userID))
+		return
+	}
+
+	h.logger.Info("session revoked", zap.Int("user_id", userID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SessionHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
+		return
+	}
+	currentID, _ := auth.SessionIDFromContext(r.Context())
+
+	if err := h.svc.RevokeOtherSessions(r.Context(), userID, currentID); err != nil {
+		writeError(w, r, h.logger, "failed to revoke sessions", err, zap.Int("user_id", userID))
+		return
+	}
+
+	h.logger.Info("other sessions revoked", zap.Int("user_id", userID))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,147 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"net/http"
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	"product-catalog/internal/dto"
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/transport/problem"
+	"time"
+)
+
+const oidcFlowCookie = "oidc_flow"
+
+type SSOService interface {
+	BeginLogin(ctx context.Context, provider string) (string, string, error)
+	CompleteLogin(ctx context.Context, provider, code, state, flow string, client dto.ClientInfo) (*dto.LoginOutput, error)
+	ListIdentities(ctx context.Context, userID int) ([]domain.UserIdentity, error)
+}
+
+type SSOHandler struct {
+	svc            SSOService
+	logger         *zap.Logger
+	authMiddleware func(http.Handler) http.Handler
+	cookiePath     string
+	flowTTL        time.Duration
+}
+
+func NewSSOHandler(svc SSOService, logger *zap.Logger, authMiddleware *auth.Middleware, cookiePath string, flowTTL time.Duration) *SSOHandler {
+	return &SSOHandler{svc: svc, logger: logger, authMiddleware: authMiddleware.AuthMiddleware, cookiePath: cookiePath, flowTTL: flowTTL}
+}
+
+func (h *SSOHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/{provider}/login", h.BeginLogin)
+	r.Get("/{provider}/callback", h.Callback)
+	r.With(h.authMiddleware).Get("/identities", h.ListIdentities)
+	return r
+}
+
+func (h *SSOHandler) BeginLogin(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+
+	authURL, flow, err := h.svc.BeginLogin(r.Context(), provider)
+	if err != nil {
+		if errors.Is(err, custom.ErrNotFound) {
+			err = problem.WithDetail(err, "unknown provider")
+		}
+		writeError(w, r, h.logger, "failed to begin oidc login", err, zap.String("provider", provider))
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    flow,
+		Path:     h.cookiePath,
+		MaxAge:   int(h.flowTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (h *SSOHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+	query := r.URL.Query()
+
+	// Cookie одноразовая: удаляем её при любом исходе.
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    "",
+		Path:     h.cookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	if errCode := query.Get("error"); errCode != "" {
+		writeError(w, r, h.logger, "oidc provider returned error", custom.ErrUnauthorized, zap.String("provider", provider), zap.String("error", errCode))
+		return
+	}
+
+	code, state := query.Get("code"), query.Get("state")
+	if code == "" || state == "" {
+		writeError(w, r, h.logger, "oidc callback without code or state", fmt.Errorf("%w: code and state are required", custom.ErrInvalidInput), zap.String("provider", provider))
+		return
+	}
+
+	cookie, err := r.Cookie(oidcFlowCookie)
+	if err != nil {
+		writeError(w, r, h.logger, "oidc flow cookie missing", custom.ErrUnauthorized, zap.String("provider", provider))
+		return
+	}
+
+	out, err := h.svc.CompleteLogin(r.Context(), provider, code, state, cookie.Value, clientInfo(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, custom.ErrNotFound):
+			err = problem.WithDetail(err, "unknown provider")
+		case errors.Is(err, custom.ErrConflict):
+			err = problem.WithDetail(err, "account with this email already exists")
+		}
+		writeError(w, r, h.logger, "oidc login failed", err, zap.String("provider", provider))
+		return
+	}
+
+	h.logger.Info("oidc login successful", zap.String("provider", provider))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (h *SSOHandler) ListIdentities(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	identities, err := h.svc.ListIdentities(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to list identities", err, zap.Int("user_id", userID))
+		return
+	}
+
+	out := make([]dto.IdentityOutput, 0, len(identities))
+	for _, identity := range identities {
+		out = append(out, dto.IdentityOutput{
+			Provider:    identity.Provider,
+			Email:       identity.Email,
+			CreatedAt:   identity.CreatedAt,
+			LastLoginAt: identity.LastLoginAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(out)
+}
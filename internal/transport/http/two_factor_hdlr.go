@@ -0,0 +1,165 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"net/http"
+	"product-catalog/internal/auth"
+	"product-catalog/internal/dto"
+	custom "product-catalog/internal/errors"
+	"strconv"
+)
+
+func (h *UserHandler) VerifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var input dto.TwoFactorLoginInput
+	if !decodeJSON(w, r, h.logger, &input) {
+		return
+	}
+
+	out, err := h.svc.VerifyTwoFactor(r.Context(), &input, clientInfo(r))
+	if err != nil {
+		if !errors.Is(err, custom.ErrTooManyRequests) {
+			err = fmt.Errorf("%w: %v", custom.ErrUnauthorized, err)
+		}
+		writeError(w, r, h.logger, "two factor verification failed", err, zap.String("ip", auth.ClientIP(r)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (h *UserHandler) EnrollTwoFactorWithChallenge(w http.ResponseWriter, r *http.Request) {
+	var input dto.ChallengeInput
+	if !decodeJSON(w, r, h.logger, &input) {
+		return
+	}
+
+	enrollment, err := h.svc.EnrollTwoFactorWithChallenge(r.Context(), input.ChallengeToken)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to enroll two factor", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(enrollment)
+}
+
+func (h *UserHandler) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	enrollment, err := h.svc.EnrollTwoFactor(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to enroll two factor", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(enrollment)
+}
+
+func (h *UserHandler) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	var input dto.TwoFactorCodeInput
+	if !decodeJSON(w, r, h.logger, &input) {
+		return
+	}
+
+	codes, err := h.svc.ConfirmTwoFactor(r.Context(), userID, input.Code)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to confirm two factor", err)
+		return
+	}
+
+	h.logger.Info("two factor enabled", zap.Int("user_id", userID))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(dto.RecoveryCodesOutput{RecoveryCodes: codes})
+}
+
+func (h *UserHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	var input dto.TwoFactorCodeInput
+	if !decodeJSON(w, r, h.logger, &input) {
+		return
+	}
+
+	codes, err := h.svc.RegenerateRecoveryCodes(r.Context(), userID, input.Code)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to regenerate recovery codes", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(dto.RecoveryCodesOutput{RecoveryCodes: codes})
+}
+
+func (h *UserHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	var input dto.TwoFactorCodeInput
+	if !decodeJSON(w, r, h.logger, &input) {
+		return
+	}
+
+	if err := h.svc.DisableTwoFactor(r.Context(), userID, input.Code); err != nil {
+		writeError(w, r, h.logger, "failed to disable two factor", err)
+		return
+	}
+
+	h.logger.Info("two factor disabled", zap.Int("user_id", userID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserHandler) SetTwoFactorRequired(w http.ResponseWriter, r *http.Request) {
+	perms, ok := auth.PermissionsFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	targetID, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeError(w, r, h.logger, "invalid user id", fmt.Errorf("%w: invalid user id %q", custom.ErrInvalidInput, idStr))
+		return
+	}
+
+	var input dto.TwoFactorRequiredInput
+	if !decodeJSON(w, r, h.logger, &input) {
+		return
+	}
+
+	if err = h.svc.SetTwoFactorRequired(r.Context(), targetID, input.Required, perms); err != nil {
+		writeError(w, r, h.logger, "failed to set two factor requirement", err)
+		return
+	}
+
+	h.logger.Info("two factor requirement changed", zap.Int("user_id", targetID), zap.Bool("required", input.Required))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"fmt"
+	"net/url"
+	"product-catalog/internal/auth"
+	"product-catalog/internal/domain"
+	custom "product-catalog/internal/errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultUserPageSize = 50
+
+// parseUserFilter разбирает параметры справочника пользователей:
+// q, role, status (через запятую или повтором), created_after, created_before,
+// sort (поле, с минусом — по убыванию), limit и offset.
+func parseUserFilter(q url.Values) (domain.UserFilter, error) {
+	filter := domain.UserFilter{Query: strings.TrimSpace(q.Get("q"))}
+
+	for _, role := range listParam(q, "role") {
+		filter.Roles = append(filter.Roles, auth.Role(role))
+	}
+	for _, st := range listParam(q, "status") {
+		filter.Statuses = append(filter.Statuses, domain.UserStatus(st))
+	}
+
+	var err error
+	if filter.CreatedAfter, err = timeParam(q, "created_after"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedBefore, err = timeParam(q, "created_before"); err != nil {
+		return filter, err
+	}
+
+	sort := q.Get("sort")
+	if strings.HasPrefix(sort, "-") {
+		filter.Desc = true
+		sort = sort[1:]
+	}
+	filter.Sort = domain.UserSort(sort)
+
+	if filter.Limit, err = intParam(q, "limit"); err != nil {
+		return filter, err
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultUserPageSize
+	}
+	if filter.Offset, err = intParam(q, "offset"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func listParam(q url.Values, name string) []string {
+	var res []string
+	for _, v := range q[name] {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				res = append(res, item)
+			}
+		}
+	}
+	return res
+}
+
+// timeParam принимает RFC 3339 или дату вида 2006-01-02.
+func timeParam(q url.Values, name string) (*time.Time, error) {
+	v := q.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: invalid %s: expected RFC 3339 timestamp or YYYY-MM-DD", custom.ErrInvalidInput, name)
+}
+
+func intParam(q url.Values, name string) (int, error) {
+	v := q.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w: invalid %s: expected non-negative integer", custom.ErrInvalidInput, name)
+	}
+	return n, nil
+}
+
+// paginationLinks строит заголовок Link (RFC 8288) со ссылками next и prev.
+func paginationLinks(u *url.URL, offset, limit, pageLen, total int) string {
+	link := func(rel string, off int) string {
+		next := *u
+		q := next.Query()
+		q.Set("offset", strconv.Itoa(off))
+		q.Set("limit", strconv.Itoa(limit))
+		next.RawQuery = q.Encode()
+		return fmt.Sprintf(`<%s>; rel="%s"`, next.RequestURI(), rel)
+	}
+
+	var links []string
+	if offset+pageLen < total {
+		links = append(links, link("next", offset+pageLen))
+	}
+	if offset > 0 {
+		links = append(links, link("prev", max(offset-limit, 0)))
+	}
+	return strings.Join(links, ", ")
+}
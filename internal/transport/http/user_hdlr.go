@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 	"net/http"
@@ -11,59 +12,102 @@ import (
 	"product-catalog/internal/domain"
 	"product-catalog/internal/dto"
 	custom "product-catalog/internal/errors"
+	"product-catalog/internal/transport/problem"
 	"strconv"
 )
 
 type UserService interface {
 	CreateUser(ctx context.Context, user *dto.CreateUserInput) error
 	GetUserByID(ctx context.Context, id int) (*domain.User, error)
-	GetAllUsers(ctx context.Context) ([]domain.User, error)
-	UpdateUserByID(ctx context.Context, requesterID, targetID int, input *dto.UpdateUserInput, role auth.Role) error
-	DeleteUserByID(ctx context.Context, requesterID, targetID int, role auth.Role) error
-	Login(ctx context.Context, email, password string) (string, error)
+	ListUsers(ctx context.Context, filter domain.UserFilter) ([]domain.User, int, error)
+	BulkUpdateUsers(ctx context.Context, actorID int, perms auth.Permissions, input *dto.BulkUserActionInput) (*dto.BulkUserActionOutput, error)
+	UpdateUserByID(ctx context.Context, requesterID, targetID, version int, input *dto.UpdateUserInput, perms auth.Permissions) error
+	PatchUserByID(ctx context.Context, requesterID, targetID int, perms auth.Permissions,
+		apply func(current *domain.User) (*dto.UpdateUserInput, error)) error
+	DeleteUserByID(ctx context.Context, requesterID, targetID, version int, perms auth.Permissions) error
+	Login(ctx context.Context, email, password string, client dto.ClientInfo) (*dto.LoginOutput, error)
+	UnlockUser(ctx context.Context, targetID int, perms auth.Permissions) error
+	DeactivateUser(ctx context.Context, targetID int, perms auth.Permissions) error
+	ReactivateUser(ctx context.Context, targetID int, perms auth.Permissions) error
+	RestoreUser(ctx context.Context, targetID int, perms auth.Permissions) error
+	EraseUser(ctx context.Context, targetID int, perms auth.Permissions) error
+	VerifyTwoFactor(ctx context.Context, input *dto.TwoFactorLoginInput, client dto.ClientInfo) (*dto.TwoFactorLoginOutput, error)
+	EnrollTwoFactor(ctx context.Context, userID int) (*dto.TOTPEnrollment, error)
+	EnrollTwoFactorWithChallenge(ctx context.Context, challengeToken string) (*dto.TOTPEnrollment, error)
+	ConfirmTwoFactor(ctx context.Context, userID int, code string) ([]string, error)
+	DisableTwoFactor(ctx context.Context, userID int, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error)
+	SetTwoFactorRequired(ctx context.Context, targetID int, required bool, perms auth.Permissions) error
+}
+
+type DataExportService interface {
+	RequestExport(ctx context.Context, userID int) (*dto.DataExportOutput, error)
 }
 
 type UserHandler struct {
 	svc            UserService
+	exportSvc      DataExportService
 	logger         *zap.Logger
 	authMiddleware func(http.Handler) http.Handler
 }
 
-func NewUserHandler(svc UserService, logger *zap.Logger, authMiddleware *auth.Middleware) *UserHandler {
-	return &UserHandler{svc: svc, logger: logger, authMiddleware: authMiddleware.AuthMiddleware}
+func NewUserHandler(svc UserService, exportSvc DataExportService, logger *zap.Logger, authMiddleware *auth.Middleware) *UserHandler {
+	return &UserHandler{svc: svc, exportSvc: exportSvc, logger: logger, authMiddleware: authMiddleware.AuthMiddleware}
 }
 
 func (h *UserHandler) Routes() chi.Router {
 	r := chi.NewRouter()
 	r.Post("/register", h.Register)
 	r.Post("/login", h.Login)
+	r.Post("/login/2fa", h.VerifyTwoFactor)
+	r.Post("/login/2fa/enroll", h.EnrollTwoFactorWithChallenge)
 	r.Group(func(r chi.Router) {
 		r.Use(h.authMiddleware)
-		r.Get("/", h.GetAllUsers)
+		r.With(auth.RequirePermission(auth.PermUserRead)).Get("/", h.GetAllUsers)
+		r.Get("/me", h.GetMe)
+		r.With(auth.DenyAPIKeys).Put("/me", h.UpdateMe)
+		r.With(auth.RequirePermission(auth.PermUserRead)).Get("/{id}", h.GetUserByID)
 		r.Put("/{id}", h.UpdateUserByID)
+		r.Patch("/{id}", h.PatchUserByID)
 		r.Delete("/{id}", h.DeleteUserByID)
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequirePermission(auth.PermUserManage))
+			r.Post("/bulk", h.BulkUpdateUsers)
+			r.Post("/{id}/unlock", h.UnlockUser)
+			r.Put("/{id}/2fa/required", h.SetTwoFactorRequired)
+			r.Post("/{id}/deactivate", h.changeStatus("deactivate", h.svc.DeactivateUser))
+			r.Post("/{id}/reactivate", h.changeStatus("reactivate", h.svc.ReactivateUser))
+			r.Post("/{id}/restore", h.changeStatus("restore", h.svc.RestoreUser))
+			r.With(auth.DenyImpersonation).Post("/{id}/erase", h.changeStatus("erase", h.svc.EraseUser))
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.DenyAPIKeys)
+			r.Use(auth.DenyImpersonation)
+			r.Post("/me/2fa/enroll", h.EnrollTwoFactor)
+			r.Post("/me/2fa/confirm", h.ConfirmTwoFactor)
+			r.Post("/me/2fa/recovery-codes", h.RegenerateRecoveryCodes)
+			r.Delete("/me/2fa", h.DisableTwoFactor)
+			r.Get("/me/export", h.ExportMe)
+		})
 	})
 	return r
 }
 
 func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		h.logger.Warn("invalid method", zap.String("method", r.Method))
-		return
-	}
-
 	var input dto.CreateUserInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		h.logger.Warn("failed to decode request body", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !decodeJSON(w, r, h.logger, &input) {
+		return
 	}
 	defer r.Body.Close()
 
 	err := h.svc.CreateUser(r.Context(), &input)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		h.logger.Error("failed to create user", zap.Error(err), zap.String("email", input.Email), zap.String("username", input.Username))
+		if errors.Is(err, custom.ErrConflict) {
+			err = problem.WithDetail(err, "username or email already taken")
+		}
+		writeError(w, r, h.logger, "failed to create user", err, zap.String("email", input.Email), zap.String("username", input.Username))
 		return
 	}
 
@@ -74,160 +118,394 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 	_, err = w.Write([]byte(`{"message": "user created"}`))
 	if err != nil {
 		h.logger.Error("failed to write response", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
 	}
 }
 
 func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		h.logger.Warn("invalid method", zap.String("method", r.Method))
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var input dto.LoginInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, h.logger, &input) {
 		return
 	}
 
 	h.logger.Info("login attempt", zap.String("email", input.Email))
 
-	token, err := h.svc.Login(r.Context(), input.Email, input.Password)
+	out, err := h.svc.Login(r.Context(), input.Email, input.Password, clientInfo(r))
 	if err != nil {
-		h.logger.Warn("unauthorized login", zap.String("email", input.Email), zap.Error(err))
-		http.Error(w, custom.ErrUnauthorized.Error(), http.StatusUnauthorized)
+		// Причину отказа клиенту не раскрываем: кроме блокировки, любой сбой входа — 401
+		if !errors.Is(err, custom.ErrTooManyRequests) {
+			err = fmt.Errorf("%w: %v", custom.ErrUnauthorized, err)
+		}
+		writeError(w, r, h.logger, "login failed", err, zap.String("email", input.Email), zap.String("ip", auth.ClientIP(r)))
 		return
 	}
 
-	h.logger.Info("login successful", zap.String("email", input.Email))
+	if out.TwoFactorRequired {
+		h.logger.Info("login requires second factor", zap.String("email", input.Email))
+	} else {
+		h.logger.Info("login successful", zap.String("email", input.Email))
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]string{"token": token})
+	_ = json.NewEncoder(w).Encode(out)
 }
 
+// GetAllUsers отдаёт страницу справочника пользователей. Общее число найденных
+// передаётся в X-Total-Count, ссылки на соседние страницы — в заголовке Link.
 func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		h.logger.Warn("invalid method", zap.String("method", r.Method))
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	filter, err := parseUserFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, r, h.logger, "invalid user filter", err)
+		return
+	}
+
+	users, total, err := h.svc.ListUsers(r.Context(), filter)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to get users", err)
+		return
+	}
+
+	out := make([]dto.UserOutput, 0, len(users))
+	for i := range users {
+		out = append(out, toUserOutput(&users[i]))
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if link := paginationLinks(r.URL, filter.Offset, filter.Limit, len(users), total); link != "" {
+		w.Header().Set("Link", link)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (h *UserHandler) BulkUpdateUsers(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
 		return
 	}
-	role, ok := auth.RoleFromContext(r.Context())
+	perms, ok := auth.PermissionsFromContext(r.Context())
 	if !ok {
-		h.logger.Warn("role not found in context")
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
 		return
 	}
-	if role != auth.RoleAdmin {
-		h.logger.Warn("unauthorized access")
-		http.Error(w, "forbidden", http.StatusForbidden)
+
+	var input dto.BulkUserActionInput
+	if !decodeJSON(w, r, h.logger, &input) {
 		return
 	}
-	users, err := h.svc.GetAllUsers(r.Context())
+
+	out, err := h.svc.BulkUpdateUsers(r.Context(), actorID, perms, &input)
 	if err != nil {
-		h.logger.Error("failed to get users", zap.Error(err))
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		writeError(w, r, h.logger, "failed to apply bulk action", userUpdateError(err), zap.String("action", input.Action))
 		return
 	}
+
+	h.logger.Info("bulk action applied", zap.Int("actor_id", actorID), zap.String("action", input.Action),
+		zap.Int("requested", len(input.UserIDs)), zap.Int("updated", out.Updated))
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(users)
+	_ = json.NewEncoder(w).Encode(out)
 }
 
-func (h *UserHandler) UpdateUserByID(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPut {
-		h.logger.Warn("invalid method", zap.String("method", r.Method))
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+func (h *UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeError(w, r, h.logger, "invalid user id", fmt.Errorf("%w: invalid user id %q", custom.ErrInvalidInput, idStr))
+		return
+	}
+	h.writeUser(w, r, id)
+}
+
+func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
 		return
 	}
+	h.writeUser(w, r, userID)
+}
+
+func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	perms, ok := auth.PermissionsFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	version, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, h.logger, "missing or invalid If-Match", err, zap.Int("user_id", userID))
+		return
+	}
+
+	var input dto.UpdateUserInput
+	if !decodeJSON(w, r, h.logger, &input) {
+		return
+	}
+
+	if err = h.svc.UpdateUserByID(r.Context(), userID, userID, version, &input, perms); err != nil {
+		writeError(w, r, h.logger, "failed to update profile", userUpdateError(err), zap.Int("user_id", userID))
+		return
+	}
+
+	h.logger.Info("profile updated", zap.Int("user_id", userID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ExportMe отдаёт выгрузку персональных данных: 202, пока архив собирается,
+// и 200 со ссылкой на скачивание, когда он готов.
+func (h *UserHandler) ExportMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	out, err := h.exportSvc.RequestExport(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to request data export", err, zap.Int("user_id", userID))
+		return
+	}
+
+	status := http.StatusOK
+	if out.DownloadURL == "" {
+		h.logger.Info("data export in progress", zap.Int("user_id", userID), zap.Int("export_id", out.ID))
+		w.Header().Set("Retry-After", "10")
+		status = http.StatusAccepted
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (h *UserHandler) writeUser(w http.ResponseWriter, r *http.Request, id int) {
+	u, err := h.svc.GetUserByID(r.Context(), id)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to get user", err, zap.Int("user_id", id))
+		return
+	}
+
+	w.Header().Set("ETag", etag(u.Version))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(toUserOutput(u))
+}
+
+// toUserOutput отдаёт наружу только публичные поля: хэш пароля в ответы не попадает.
+func toUserOutput(u *domain.User) dto.UserOutput {
+	return dto.UserOutput{ID: u.ID, Username: u.Username, Email: u.Email, Role: u.Role, Status: string(u.Status), DeletedAt: u.DeletedAt, CreatedAt: u.CreatedAt}
+}
+
+// userUpdateError уточняет для клиента конфликт уникальности при изменении профиля
+func userUpdateError(err error) error {
+	if errors.Is(err, custom.ErrConflict) {
+		return problem.WithDetail(err, "username or email already taken")
+	}
+	return err
+}
 
-	role, ok := auth.RoleFromContext(r.Context())
+func (h *UserHandler) UpdateUserByID(w http.ResponseWriter, r *http.Request) {
+	perms, ok := auth.PermissionsFromContext(r.Context())
 	if !ok {
-		h.logger.Warn("role not found in context")
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
 		return
 	}
 
 	requesterID, ok := auth.UserIDFromContext(r.Context())
 	if !ok {
-		h.logger.Warn("user id not found in context")
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
 		return
 	}
 
 	idStr := chi.URLParam(r, "id")
 	targetID, err := strconv.Atoi(idStr)
 	if err != nil {
-		h.logger.Warn("invalid user id", zap.String("id", idStr), zap.Error(err))
-		http.Error(w, "invalid user id", http.StatusBadRequest)
+		writeError(w, r, h.logger, "invalid user id", fmt.Errorf("%w: invalid user id %q", custom.ErrInvalidInput, idStr))
+		return
+	}
+
+	version, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, h.logger, "missing or invalid If-Match", err, zap.Int("user_id", targetID))
 		return
 	}
 
 	var input dto.UpdateUserInput
-	if err = json.NewDecoder(r.Body).Decode(&input); err != nil {
-		h.logger.Warn("failed to decode input", zap.Error(err))
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, h.logger, &input) {
+		return
+	}
+
+	if err = h.svc.UpdateUserByID(r.Context(), requesterID, targetID, version, &input, perms); err != nil {
+		writeError(w, r, h.logger, "failed to update user", userUpdateError(err), zap.Int("user_id", targetID))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PatchUserByID частично изменяет пользователя через merge patch или JSON Patch.
+// Документом служат username, email и role; password и current_password патч может добавить.
+func (h *UserHandler) PatchUserByID(w http.ResponseWriter, r *http.Request) {
+	perms, ok := auth.PermissionsFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
 		return
 	}
 
-	if role != auth.RoleAdmin {
-		input.Role = nil
+	requesterID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
+		return
 	}
 
-	err = h.svc.UpdateUserByID(r.Context(), requesterID, targetID, &input, role)
+	idStr := chi.URLParam(r, "id")
+	targetID, err := strconv.Atoi(idStr)
 	if err != nil {
-		h.logger.Error("failed to update user", zap.Error(err))
-		if errors.Is(err, custom.ErrForbidden) {
-			http.Error(w, "forbidden", http.StatusForbidden)
-			return
+		writeError(w, r, h.logger, "invalid user id", fmt.Errorf("%w: invalid user id %q", custom.ErrInvalidInput, idStr))
+		return
+	}
+
+	version, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, h.logger, "missing or invalid If-Match", err, zap.Int("user_id", targetID))
+		return
+	}
+
+	body, mediaType, ok := readPatch(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	err = h.svc.PatchUserByID(r.Context(), requesterID, targetID, perms, func(current *domain.User) (*dto.UpdateUserInput, error) {
+		if current.Version != version {
+			return nil, custom.ErrPreconditionFailed
 		}
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		doc := dto.PatchUserInput{Username: &current.Username, Email: &current.Email, Role: &current.Role}
+		var input dto.PatchUserInput
+		if err := applyPatch(mediaType, body, doc, &input); err != nil {
+			return nil, err
+		}
+		return userChanges(current, &input), nil
+	})
+	if err != nil {
+		writeError(w, r, h.logger, "failed to patch user", userUpdateError(err), zap.Int("user_id", targetID))
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	h.writeUser(w, r, targetID)
 }
 
-func (h *UserHandler) DeleteUserByID(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
-		h.logger.Warn("invalid method", zap.String("method", r.Method))
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+// userChanges оставляет только изменившиеся поля, чтобы неизменный email
+// не требовал подтверждения паролем, а неизменная роль — права role:manage
+func userChanges(current *domain.User, input *dto.PatchUserInput) *dto.UpdateUserInput {
+	changes := &dto.UpdateUserInput{Password: input.Password, CurrentPassword: input.CurrentPassword}
+	if *input.Username != current.Username {
+		changes.Username = input.Username
 	}
+	if *input.Email != current.Email {
+		changes.Email = input.Email
+	}
+	if *input.Role != current.Role {
+		changes.Role = input.Role
+	}
+	return changes
+}
 
-	role, ok := auth.RoleFromContext(r.Context())
+func (h *UserHandler) DeleteUserByID(w http.ResponseWriter, r *http.Request) {
+	perms, ok := auth.PermissionsFromContext(r.Context())
 	if !ok {
-		h.logger.Warn("role not found in context")
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
 		return
 	}
 
 	requesterID, ok := auth.UserIDFromContext(r.Context())
 	if !ok {
-		h.logger.Warn("user id not found in context")
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		writeError(w, r, h.logger, "user id not found in context", custom.ErrUnauthorized)
+		return
 	}
 
 	idStr := chi.URLParam(r, "id")
 	targetID, err := strconv.Atoi(idStr)
 	if err != nil {
-		h.logger.Warn("invalid user id", zap.String("id", idStr), zap.Error(err))
-		http.Error(w, "invalid user id", http.StatusBadRequest)
+		writeError(w, r, h.logger, "invalid user id", fmt.Errorf("%w: invalid user id %q", custom.ErrInvalidInput, idStr))
 		return
 	}
 
-	err = h.svc.DeleteUserByID(r.Context(), requesterID, targetID, role)
+	version, err := ifMatch(r)
 	if err != nil {
-		h.logger.Error("failed to delete user", zap.Error(err))
-		if errors.Is(err, custom.ErrForbidden) {
-			http.Error(w, "forbidden", http.StatusForbidden)
+		writeError(w, r, h.logger, "missing or invalid If-Match", err, zap.Int("user_id", targetID))
+		return
+	}
+
+	err = h.svc.DeleteUserByID(r.Context(), requesterID, targetID, version, perms)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to delete user", err, zap.Int("user_id", targetID))
+		return
+	}
+	h.logger.Info("user deleted", zap.Int("user_id", targetID), zap.Int("requester_id", requesterID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// changeStatus собирает хендлер для административных переходов статуса аккаунта.
+func (h *UserHandler) changeStatus(action string, fn func(ctx context.Context, targetID int, perms auth.Permissions) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		perms, ok := auth.PermissionsFromContext(r.Context())
+		if !ok {
+			writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
 			return
 		}
-		h.logger.Error("failed to delete user", zap.Error(err))
-		http.Error(w, "internal error", http.StatusInternalServerError)
+
+		idStr := chi.URLParam(r, "id")
+		targetID, err := strconv.Atoi(idStr)
+		if err != nil {
+			writeError(w, r, h.logger, "invalid user id", fmt.Errorf("%w: invalid user id %q", custom.ErrInvalidInput, idStr))
+			return
+		}
+
+		if err = fn(r.Context(), targetID, perms); err != nil {
+			writeError(w, r, h.logger, "failed to change user status", err, zap.String("action", action), zap.Int("user_id", targetID))
+			return
+		}
+
+		h.logger.Info("user status changed", zap.String("action", action), zap.Int("user_id", targetID))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (h *UserHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	perms, ok := auth.PermissionsFromContext(r.Context())
+	if !ok {
+		writeError(w, r, h.logger, "permissions not found in context", custom.ErrUnauthorized)
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	targetID, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeError(w, r, h.logger, "invalid user id", fmt.Errorf("%w: invalid user id %q", custom.ErrInvalidInput, idStr))
+		return
+	}
+
+	err = h.svc.UnlockUser(r.Context(), targetID, perms)
+	if err != nil {
+		writeError(w, r, h.logger, "failed to unlock user", err, zap.Int("user_id", targetID))
 		return
 	}
+
+	h.logger.Info("user unlocked", zap.Int("user_id", targetID))
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func clientInfo(r *http.Request) dto.ClientInfo {
+	return dto.ClientInfo{IP: auth.ClientIP(r), UserAgent: r.UserAgent()}
+}
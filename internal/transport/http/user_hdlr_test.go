@@ -0,0 +1,68 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"product-catalog/internal/auth"
+	"product-catalog/internal/dto"
+	custom "product-catalog/internal/errors"
+	h "product-catalog/internal/transport/http"
+)
+
+// fakeUsers хранит версию одного профиля; остальные методы сервиса тесты не вызывают
+type fakeUsers struct {
+	h.UserService
+	version int
+	updated int
+}
+
+func (s *fakeUsers) UpdateUserByID(_ context.Context, _, _, version int, _ *dto.UpdateUserInput, _ auth.Permissions) error {
+	if version != s.version {
+		return custom.ErrPreconditionFailed
+	}
+	s.version++
+	s.updated++
+	return nil
+}
+
+func TestUpdateMeRequiresIfMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		ifMatch     string
+		wantStatus  int
+		wantUpdated int
+	}{
+		{"missing If-Match", "", http.StatusPreconditionRequired, 0},
+		{"weak tag", `W/"3"`, http.StatusPreconditionFailed, 0},
+		{"stale version", `"2"`, http.StatusPreconditionFailed, 0},
+		{"current version", `"3"`, http.StatusNoContent, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeUsers{version: 3}
+			hdlr := h.NewUserHandler(svc, nil, zap.NewNop(), nil)
+
+			req := httptest.NewRequest(http.MethodPut, "/users/me", strings.NewReader(`{"username":"alicia"}`))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			req = req.WithContext(auth.WithUserContext(req.Context(), 7, auth.RoleUser, auth.Permissions{}))
+
+			rec := httptest.NewRecorder()
+			hdlr.UpdateMe(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, body %s; want %d", rec.Code, rec.Body, tt.wantStatus)
+			}
+			if svc.updated != tt.wantUpdated {
+				t.Errorf("updated %d times; want %d", svc.updated, tt.wantUpdated)
+			}
+		})
+	}
+}
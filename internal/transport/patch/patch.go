@@ -0,0 +1,367 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	custom "product-catalog/internal/errors"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MergePatchType — RFC 7396: тело повторяет документ, null удаляет поле
+	MergePatchType = "application/merge-patch+json"
+	// JSONPatchType — RFC 6902: тело — список операций над JSON Pointer
+	JSONPatchType = "application/json-patch+json"
+)
+
+// Operation — одна операция JSON Patch
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Apply применяет patch к документу doc по типу содержимого. Операции выполняются
+// над копией документа: при любой ошибке doc не меняется и результат не возвращается.
+// Неверный патч — ErrInvalidInput, несработавшая операция test — ErrConflict.
+func Apply(mediaType string, doc, patch []byte) ([]byte, error) {
+	var target any
+	if err := unmarshal(doc, &target); err != nil {
+		return nil, fmt.Errorf("failed to decode document: %w", err)
+	}
+
+	var (
+		result any
+		err    error
+	)
+	switch mediaType {
+	case MergePatchType:
+		result, err = mergePatch(target, patch)
+	case JSONPatchType:
+		result, err = jsonPatch(target, patch)
+	default:
+		return nil, fmt.Errorf("%w: expected %s or %s", custom.ErrUnsupportedMediaType, MergePatchType, JSONPatchType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(result)
+}
+
+func mergePatch(target any, patch []byte) (any, error) {
+	var p any
+	if err := unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("%w: invalid merge patch", custom.ErrInvalidInput)
+	}
+	return merge(target, p), nil
+}
+
+func merge(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = merge(t[k], v)
+	}
+	return t
+}
+
+func jsonPatch(doc any, patch []byte) (any, error) {
+	var ops []Operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: invalid json patch: expected an array of operations", custom.ErrInvalidInput)
+	}
+	for i, op := range ops {
+		var err error
+		if doc, err = applyOp(doc, op); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return doc, nil
+}
+
+func applyOp(doc any, op Operation) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		// Отсутствующее value и "value": null различаются: null — допустимое значение
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: value is required", custom.ErrInvalidInput)
+		}
+		var value any
+		if err = unmarshal(op.Value, &value); err != nil {
+			return nil, fmt.Errorf("%w: invalid value", custom.ErrInvalidInput)
+		}
+		switch op.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			return replace(doc, path, value)
+		default:
+			current, err := get(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !equal(current, value) {
+				return nil, fmt.Errorf("%w: test failed", custom.ErrConflict)
+			}
+			return doc, nil
+		}
+	case "remove":
+		return remove(doc, path)
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			return add(doc, path, deepCopy(value))
+		}
+		if len(path) > len(from) && slices.Equal(path[:len(from)], from) {
+			return nil, fmt.Errorf("%w: cannot move a value into its own child", custom.ErrInvalidInput)
+		}
+		if doc, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", custom.ErrInvalidInput, op.Op)
+	}
+}
+
+// parsePointer разбирает JSON Pointer (RFC 6901); пустой указатель — весь документ
+func parsePointer(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(s, "/") {
+		return nil, fmt.Errorf("%w: invalid json pointer %q", custom.ErrInvalidInput, s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(t)
+	}
+	return tokens, nil
+}
+
+func get(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			v, ok := node[token]
+			if !ok {
+				return nil, pathError(path)
+			}
+			doc = v
+		case []any:
+			i, err := index(token, len(node)-1)
+			if err != nil {
+				return nil, pathError(path)
+			}
+			doc = node[i]
+		default:
+			return nil, pathError(path)
+		}
+	}
+	return doc, nil
+}
+
+func add(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(doc, path, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			node[token] = value
+			return node, nil
+		case []any:
+			i := len(node)
+			if token != "-" {
+				var err error
+				if i, err = index(token, len(node)); err != nil {
+					return nil, pathError(path)
+				}
+			}
+			node = append(node[:i], append([]any{value}, node[i:]...)...)
+			return node, nil
+		default:
+			return nil, pathError(path)
+		}
+	})
+}
+
+func replace(doc any, path []string, value any) (any, error) {
+	if _, err := get(doc, path); err != nil {
+		return nil, err
+	}
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(doc, path, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			node[token] = value
+		case []any:
+			i, _ := index(token, len(node)-1)
+			node[i] = value
+		}
+		return parent, nil
+	})
+}
+
+func remove(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: cannot remove the whole document", custom.ErrInvalidInput)
+	}
+	if _, err := get(doc, path); err != nil {
+		return nil, err
+	}
+	return update(doc, path, func(parent any, token string) (any, error) {
+		switch node := parent.(type) {
+		case map[string]any:
+			delete(node, token)
+			return node, nil
+		case []any:
+			i, _ := index(token, len(node)-1)
+			return append(node[:i], node[i+1:]...), nil
+		}
+		return parent, nil
+	})
+}
+
+// update доходит до родителя последнего токена, вызывает fn и записывает
+// изменённый контейнер обратно: вставка в массив меняет сам срез
+func update(doc any, path []string, fn func(parent any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+	child, err := get(doc, path[:1])
+	if err != nil {
+		return nil, pathError(path)
+	}
+	child, err = update(child, path[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+	switch node := doc.(type) {
+	case map[string]any:
+		node[path[0]] = child
+	case []any:
+		i, _ := index(path[0], len(node)-1)
+		node[i] = child
+	}
+	return doc, nil
+}
+
+// index разбирает индекс массива; допустимы значения от 0 до max включительно
+func index(token string, max int) (int, error) {
+	// RFC 6901 запрещает ведущие нули и знак
+	if token == "" || (len(token) > 1 && token[0] == '0') || token[0] == '+' || token[0] == '-' {
+		return 0, fmt.Errorf("invalid index %q", token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i > max {
+		return 0, fmt.Errorf("invalid index %q", token)
+	}
+	return i, nil
+}
+
+func pathError(path []string) error {
+	escaped := make([]string, len(path))
+	for i, t := range path {
+		escaped[i] = strings.NewReplacer("~", "~0", "/", "~1").Replace(t)
+	}
+	return fmt.Errorf("%w: path /%s does not exist", custom.ErrInvalidInput, strings.Join(escaped, "/"))
+}
+
+// equal сравнивает значения по смыслу JSON: 1 и 1.0 равны
+func equal(a, b any) bool {
+	an, aok := a.(json.Number)
+	bn, bok := b.(json.Number)
+	if aok && bok {
+		af, aerr := an.Float64()
+		bf, berr := bn.Float64()
+		return aerr == nil && berr == nil && af == bf
+	}
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			if w, ok := bv[k]; !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+func deepCopy(v any) any {
+	switch node := v.(type) {
+	case map[string]any:
+		cp := make(map[string]any, len(node))
+		for k, val := range node {
+			cp[k] = deepCopy(val)
+		}
+		return cp
+	case []any:
+		cp := make([]any, len(node))
+		for i, val := range node {
+			cp[i] = deepCopy(val)
+		}
+		return cp
+	default:
+		return v
+	}
+}
+
+// unmarshal сохраняет числа как json.Number, чтобы не терять точность целых
+func unmarshal(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return fmt.Errorf("unexpected data after JSON value")
+	}
+	return nil
+}
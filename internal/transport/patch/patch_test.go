@@ -0,0 +1,103 @@
+package patch_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/transport/patch"
+)
+
+func TestMergePatch(t *testing.T) {
+	// Примеры из приложения A RFC 7396
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		got, err := patch.Apply(patch.MergePatchType, []byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Fatalf("%s + %s: %v", tt.doc, tt.patch, err)
+		}
+		assertJSON(t, got, tt.want)
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr error
+	}{
+		{name: "add member", doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz","value":"qux"}]`, want: `{"foo":"bar","baz":"qux"}`},
+		{name: "add array element", doc: `{"foo":["bar","baz"]}`, patch: `[{"op":"add","path":"/foo/1","value":"qux"}]`, want: `{"foo":["bar","qux","baz"]}`},
+		{name: "append", doc: `{"foo":[1]}`, patch: `[{"op":"add","path":"/foo/-","value":2}]`, want: `{"foo":[1,2]}`},
+		{name: "add null value", doc: `{}`, patch: `[{"op":"add","path":"/a","value":null}]`, want: `{"a":null}`},
+		{name: "remove", doc: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"remove","path":"/baz"}]`, want: `{"foo":"bar"}`},
+		{name: "remove array element", doc: `{"foo":["bar","qux","baz"]}`, patch: `[{"op":"remove","path":"/foo/1"}]`, want: `{"foo":["bar","baz"]}`},
+		{name: "replace", doc: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"replace","path":"/baz","value":"boo"}]`, want: `{"baz":"boo","foo":"bar"}`},
+		{name: "move", doc: `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, patch: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, want: `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{name: "copy", doc: `{"a":{"b":1}}`, patch: `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, want: `{"a":{"b":1},"c":{"b":2}}`},
+		{name: "escaped pointer", doc: `{"a/b":1,"m~n":2}`, patch: `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/m~0n"}]`, want: `{"a/b":3}`},
+		{name: "test numbers by value", doc: `{"price":10}`, patch: `[{"op":"test","path":"/price","value":10.0},{"op":"replace","path":"/price","value":12}]`, want: `{"price":12}`},
+		{name: "failed test rejects whole patch", doc: `{"price":10}`, patch: `[{"op":"replace","path":"/price","value":12},{"op":"test","path":"/price","value":10}]`, wantErr: custom.ErrConflict},
+		{name: "replace missing member", doc: `{}`, patch: `[{"op":"replace","path":"/a","value":1}]`, wantErr: custom.ErrInvalidInput},
+		{name: "add to missing parent", doc: `{}`, patch: `[{"op":"add","path":"/a/b","value":1}]`, wantErr: custom.ErrInvalidInput},
+		{name: "index out of range", doc: `{"a":[1]}`, patch: `[{"op":"add","path":"/a/2","value":1}]`, wantErr: custom.ErrInvalidInput},
+		{name: "leading zero index", doc: `{"a":[1,2]}`, patch: `[{"op":"remove","path":"/a/01"}]`, wantErr: custom.ErrInvalidInput},
+		{name: "move into own child", doc: `{"a":{"b":1}}`, patch: `[{"op":"move","from":"/a","path":"/a/c"}]`, wantErr: custom.ErrInvalidInput},
+		{name: "missing value", doc: `{}`, patch: `[{"op":"add","path":"/a"}]`, wantErr: custom.ErrInvalidInput},
+		{name: "unknown op", doc: `{}`, patch: `[{"op":"merge","path":"/a","value":1}]`, wantErr: custom.ErrInvalidInput},
+		{name: "not an array", doc: `{}`, patch: `{"op":"add","path":"/a","value":1}`, wantErr: custom.ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := patch.Apply(patch.JSONPatchType, []byte(tt.doc), []byte(tt.patch))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || got != nil {
+					t.Fatalf("got %s, %v; want error %v", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestUnsupportedMediaType(t *testing.T) {
+	_, err := patch.Apply("application/json", []byte(`{}`), []byte(`{}`))
+	if !errors.Is(err, custom.ErrUnsupportedMediaType) {
+		t.Fatalf("err = %v, want ErrUnsupportedMediaType", err)
+	}
+}
+
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var g, w any
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("result %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("want %s: %v", want, err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
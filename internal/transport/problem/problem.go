@@ -0,0 +1,156 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"math"
+	"net/http"
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/validation"
+	"strconv"
+)
+
+const ContentType = "application/problem+json"
+
+// Стабильные коды ошибок: клиенты опираются на них, а не на текст detail
+const (
+	CodeInvalidInput     = "invalid_input"
+	CodeValidationFailed = "validation_failed"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodeTooManyRequests  = "too_many_requests"
+	CodeRateLimited      = "rate_limited"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeUnsupportedMedia = "unsupported_media_type"
+	CodePreconditionFail = "precondition_failed"
+	CodePreconditionReq  = "precondition_required"
+	CodeIdempotencyKey   = "idempotency_key_reused"
+	CodeInternal         = "internal"
+)
+
+// Problem — тело ответа об ошибке по RFC 7807
+type Problem struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Code     string            `json:"code"`
+	TraceID  string            `json:"trace_id,omitempty"`
+	Errors   validation.Errors `json:"errors,omitempty"`
+}
+
+// mapping связывает сентинел из internal/errors со статусом и кодом; порядок важен,
+// первое совпадение побеждает
+var mapping = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{custom.ErrValidation, http.StatusUnprocessableEntity, CodeValidationFailed},
+	{custom.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity, CodeIdempotencyKey},
+	{custom.ErrInvalidInput, http.StatusBadRequest, CodeInvalidInput},
+	{custom.ErrUnauthorized, http.StatusUnauthorized, CodeUnauthorized},
+	{custom.ErrForbidden, http.StatusForbidden, CodeForbidden},
+	{custom.ErrNotFound, http.StatusNotFound, CodeNotFound},
+	{custom.ErrConflict, http.StatusConflict, CodeConflict},
+	{custom.ErrRateLimited, http.StatusTooManyRequests, CodeRateLimited},
+	{custom.ErrTooManyRequests, http.StatusTooManyRequests, CodeTooManyRequests},
+	{custom.ErrUnsupportedMediaType, http.StatusUnsupportedMediaType, CodeUnsupportedMedia},
+	{custom.ErrPreconditionFailed, http.StatusPreconditionFailed, CodePreconditionFail},
+	{custom.ErrPreconditionRequired, http.StatusPreconditionRequired, CodePreconditionReq},
+}
+
+// detailError несёт сообщение для клиента поверх ошибки сервиса
+type detailError struct {
+	err    error
+	detail string
+}
+
+func (e *detailError) Error() string { return e.detail + ": " + e.err.Error() }
+func (e *detailError) Unwrap() error { return e.err }
+
+// WithDetail задаёт текст detail, который увидит клиент вместо текста по умолчанию
+func WithDetail(err error, detail string) error {
+	return &detailError{err: err, detail: detail}
+}
+
+// Status возвращает HTTP-статус для ошибки; неизвестные ошибки — 500
+func Status(err error) int {
+	status, _ := classify(err)
+	return status
+}
+
+func classify(err error) (int, string) {
+	var fieldErrs validation.Errors
+	if errors.As(err, &fieldErrs) {
+		return http.StatusUnprocessableEntity, CodeValidationFailed
+	}
+	for _, m := range mapping {
+		if errors.Is(err, m.err) {
+			return m.status, m.code
+		}
+	}
+	return http.StatusInternalServerError, CodeInternal
+}
+
+// FromError строит Problem по ошибке. Текст внутренних ошибок клиенту не раскрывается.
+func FromError(r *http.Request, err error) *Problem {
+	status, code := classify(err)
+	p := New(r, status, code, "")
+
+	var de *detailError
+	switch {
+	case errors.As(err, &de):
+		p.Detail = de.detail
+	case status == http.StatusBadRequest || status == http.StatusForbidden || status == http.StatusConflict ||
+		status == http.StatusUnprocessableEntity || status == http.StatusUnsupportedMediaType ||
+		status == http.StatusPreconditionFailed || status == http.StatusPreconditionRequired:
+		// Эти ошибки сервисы формулируют для клиента: "invalid input: unknown role"
+		p.Detail = err.Error()
+	case status == http.StatusInternalServerError:
+		p.Detail = "internal error"
+	}
+	errors.As(err, &p.Errors)
+	return p
+}
+
+func New(r *http.Request, status int, code, detail string) *Problem {
+	return &Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Code:     code,
+		TraceID:  middleware.GetReqID(r.Context()),
+	}
+}
+
+func Write(w http.ResponseWriter, p *Problem) {
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// Error пишет ответ problem+json для ошибки; для блокировок выставляет Retry-After
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	var retryErr *custom.RetryAfterError
+	if errors.As(err, &retryErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryErr.RetryAfter.Seconds()))))
+	}
+	Write(w, FromError(r, err))
+}
+
+// NotFound и MethodNotAllowed заменяют текстовые ответы роутера по умолчанию
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	Error(w, r, custom.ErrNotFound)
+}
+
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	Write(w, New(r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, ""))
+}
@@ -0,0 +1,75 @@
+package problem_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	custom "product-catalog/internal/errors"
+	"product-catalog/internal/transport/problem"
+	"product-catalog/internal/validation"
+)
+
+func TestError(t *testing.T) {
+	fieldErrs := validation.Errors{{Field: "price", Rule: "min", Message: "must be at least 1"}}
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantDetail string
+	}{
+		{name: "not found", err: fmt.Errorf("failed to get product: %w", custom.ErrNotFound), wantStatus: http.StatusNotFound, wantCode: problem.CodeNotFound},
+		{name: "conflict with detail", err: problem.WithDetail(custom.ErrConflict, "username or email already taken"), wantStatus: http.StatusConflict, wantCode: problem.CodeConflict, wantDetail: "username or email already taken"},
+		{name: "invalid input keeps message", err: fmt.Errorf("%w: unknown role", custom.ErrInvalidInput), wantStatus: http.StatusBadRequest, wantCode: problem.CodeInvalidInput, wantDetail: "invalid input: unknown role"},
+		{name: "validation", err: fmt.Errorf("%w: %w", custom.ErrValidation, fieldErrs), wantStatus: http.StatusUnprocessableEntity, wantCode: problem.CodeValidationFailed, wantDetail: "validation failed: price: must be at least 1"},
+		{name: "unsupported media type", err: fmt.Errorf("%w: expected application/json", custom.ErrUnsupportedMediaType), wantStatus: http.StatusUnsupportedMediaType, wantCode: problem.CodeUnsupportedMedia, wantDetail: "unsupported media type: expected application/json"},
+		{name: "reused idempotency key", err: fmt.Errorf("%w: key was used with a different request", custom.ErrIdempotencyKeyReused), wantStatus: http.StatusUnprocessableEntity, wantCode: problem.CodeIdempotencyKey, wantDetail: "idempotency key reused: key was used with a different request"},
+		{name: "stale version", err: fmt.Errorf("failed to update product: %w", custom.ErrPreconditionFailed), wantStatus: http.StatusPreconditionFailed, wantCode: problem.CodePreconditionFail, wantDetail: "failed to update product: precondition failed"},
+		{name: "rate limited", err: custom.ErrRateLimited, wantStatus: http.StatusTooManyRequests, wantCode: problem.CodeRateLimited},
+		{name: "lockout", err: &custom.RetryAfterError{RetryAfter: 1500 * time.Millisecond}, wantStatus: http.StatusTooManyRequests, wantCode: problem.CodeTooManyRequests},
+		{name: "internal error is hidden", err: errors.New("pq: connection refused"), wantStatus: http.StatusInternalServerError, wantCode: problem.CodeInternal, wantDetail: "internal error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/products/7", nil)
+			r.Header.Set(middleware.RequestIDHeader, "req-1")
+			w := httptest.NewRecorder()
+
+			middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				problem.Error(w, r, tt.err)
+			})).ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != problem.ContentType {
+				t.Fatalf("content type = %q", ct)
+			}
+			var p problem.Problem
+			if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if p.Code != tt.wantCode || p.Status != tt.wantStatus || p.Detail != tt.wantDetail {
+				t.Fatalf("problem = %+v", p)
+			}
+			if p.Instance != "/products/7" || p.TraceID != "req-1" {
+				t.Fatalf("instance = %q, trace id = %q", p.Instance, p.TraceID)
+			}
+			if tt.wantCode == problem.CodeValidationFailed && len(p.Errors) != 1 {
+				t.Fatalf("field errors = %v", p.Errors)
+			}
+			if tt.wantCode == problem.CodeTooManyRequests && w.Header().Get("Retry-After") != "2" {
+				t.Fatalf("Retry-After = %q, want 2", w.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
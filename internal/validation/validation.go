@@ -0,0 +1,296 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Rule проверяет значение поля. param — часть правила после "=" (для min=3 это "3").
+// Возвращённая ошибка становится сообщением об ошибке поля.
+type Rule func(v reflect.Value, param string) error
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Errors — все ошибки полей, найденные при проверке одной структуры
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, 0, len(e))
+	for _, fe := range e {
+		parts = append(parts, fe.Field+": "+fe.Message)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// tagError — ошибка в самом теге (неверный параметр или тип поля), а не в данных клиента
+type tagError struct{ msg string }
+
+func (e *tagError) Error() string { return e.msg }
+
+// BadTag сообщает о неверном теге; Struct вернёт её как внутреннюю ошибку, а не ошибку поля
+func BadTag(format string, args ...any) error {
+	return &tagError{msg: fmt.Sprintf(format, args...)}
+}
+
+type Validator struct {
+	mu    sync.RWMutex
+	rules map[string]Rule
+}
+
+// New возвращает валидатор со встроенными правилами required, required_without,
+// omitempty, min, max, email, url и oneof
+func New() *Validator {
+	return &Validator{rules: map[string]Rule{
+		"min":   minRule,
+		"max":   maxRule,
+		"email": emailRule,
+		"url":   urlRule,
+		"oneof": oneofRule,
+	}}
+}
+
+// Register добавляет правило или заменяет встроенное с тем же именем.
+// Правило может вернуть BadTag, если тег к полю неприменим.
+func (v *Validator) Register(name string, rule Rule) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.rules[name] = rule
+}
+
+// Struct проверяет поля структуры по тегам validate. Ошибки полей возвращаются
+// как Errors; любая другая ошибка означает некорректный тег.
+func (v *Validator) Struct(s any) error {
+	rv := reflect.ValueOf(s)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return fmt.Errorf("validation: nil %s", rv.Type())
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("validation: %s is not a struct", rv.Type())
+	}
+
+	var errs Errors
+	if err := v.walk(rv, &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (v *Validator) walk(rv reflect.Value, errs *Errors) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if err := v.walk(rv.Field(i), errs); err != nil {
+				return err
+			}
+			continue
+		}
+		tag := f.Tag.Get("validate")
+		if !f.IsExported() || tag == "" || tag == "-" {
+			continue
+		}
+		if err := v.field(rv, FieldName(f), rv.Field(i), tag, errs); err != nil {
+			return fmt.Errorf("validation: %s.%s: %w", t.Name(), f.Name, err)
+		}
+	}
+	return nil
+}
+
+func (v *Validator) field(parent reflect.Value, name string, fv reflect.Value, tag string, errs *Errors) error {
+	rules := strings.Split(tag, ",")
+	required := slices.Contains(rules, "required")
+
+	// required_without=Other: поле обязательно, если соседнее поле Other не задано
+	for _, r := range rules {
+		ruleName, param, _ := strings.Cut(r, "=")
+		if ruleName != "required_without" {
+			continue
+		}
+		other, ok := parent.Type().FieldByName(param)
+		if !ok {
+			return fmt.Errorf("required_without: unknown field %q", param)
+		}
+		if isEmpty(parent.FieldByIndex(other.Index)) && isEmpty(fv) {
+			*errs = append(*errs, FieldError{Field: name, Rule: ruleName, Message: "is required when " + FieldName(other) + " is not set"})
+			return nil
+		}
+	}
+
+	// Отсутствующее необязательное поле не проверяется; для указателя required
+	// означает только присутствие, поэтому *bool со значением false допустим
+	present := false
+	if fv.Kind() == reflect.Pointer || fv.Kind() == reflect.Interface {
+		if fv.IsNil() {
+			if required {
+				*errs = append(*errs, FieldError{Field: name, Rule: "required", Message: "is required"})
+			}
+			return nil
+		}
+		present = true
+		if fv.Kind() == reflect.Pointer && fv.Elem().Kind() != reflect.Struct {
+			fv = fv.Elem()
+		}
+	}
+
+	for _, r := range rules {
+		ruleName, param, _ := strings.Cut(r, "=")
+		switch ruleName {
+		case "required":
+			if !present && isEmpty(fv) {
+				*errs = append(*errs, FieldError{Field: name, Rule: ruleName, Message: "is required"})
+				return nil
+			}
+			continue
+		case "omitempty":
+			if isEmpty(fv) {
+				return nil
+			}
+			continue
+		case "required_without":
+			continue
+		}
+
+		v.mu.RLock()
+		rule, ok := v.rules[ruleName]
+		v.mu.RUnlock()
+		if !ok {
+			return fmt.Errorf("unknown rule %q", ruleName)
+		}
+		if err := rule(fv, param); err != nil {
+			var te *tagError
+			if errors.As(err, &te) {
+				return err
+			}
+			*errs = append(*errs, FieldError{Field: name, Rule: ruleName, Message: err.Error()})
+			// Одной ошибки на поле достаточно: остальные правила обычно её повторяют
+			return nil
+		}
+	}
+	return nil
+}
+
+// FieldName возвращает имя поля, под которым оно приходит от клиента: из тега json,
+// затем form, иначе имя поля в нижнем регистре
+func FieldName(f reflect.StructField) string {
+	for _, key := range []string{"json", "form"} {
+		if name, _, _ := strings.Cut(f.Tag.Get(key), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return strings.ToLower(f.Name)
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(v.String()) == ""
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
+
+func minRule(v reflect.Value, param string) error {
+	return compare(v, param, "min", func(got, limit float64) bool { return got >= limit })
+}
+
+func maxRule(v reflect.Value, param string) error {
+	return compare(v, param, "max", func(got, limit float64) bool { return got <= limit })
+}
+
+func compare(v reflect.Value, param, rule string, ok func(got, limit float64) bool) error {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return BadTag("invalid %s parameter %q", rule, param)
+	}
+	bound := "at least"
+	if rule == "max" {
+		bound = "at most"
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if !ok(float64(utf8.RuneCountInString(v.String())), limit) {
+			return fmt.Errorf("must be %s %s characters long", bound, param)
+		}
+	case reflect.Slice, reflect.Map, reflect.Array:
+		if !ok(float64(v.Len()), limit) {
+			return fmt.Errorf("must contain %s %s items", bound, param)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !ok(float64(v.Int()), limit) {
+			return fmt.Errorf("must be %s %s", bound, param)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if !ok(float64(v.Uint()), limit) {
+			return fmt.Errorf("must be %s %s", bound, param)
+		}
+	case reflect.Float32, reflect.Float64:
+		if !ok(v.Float(), limit) {
+			return fmt.Errorf("must be %s %s", bound, param)
+		}
+	default:
+		return BadTag("%s is not applicable to %s", rule, v.Type())
+	}
+	return nil
+}
+
+func emailRule(v reflect.Value, _ string) error {
+	if v.Kind() != reflect.String {
+		return BadTag("email is not applicable to %s", v.Type())
+	}
+	s := strings.TrimSpace(v.String())
+	addr, err := mail.ParseAddress(s)
+	// ParseAddress принимает и "Имя <addr>", а нужен голый адрес
+	if err != nil || addr.Address != s {
+		return fmt.Errorf("must be a valid email address")
+	}
+	return nil
+}
+
+func urlRule(v reflect.Value, _ string) error {
+	if v.Kind() != reflect.String {
+		return BadTag("url is not applicable to %s", v.Type())
+	}
+	u, err := url.ParseRequestURI(v.String())
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("must be an absolute URL")
+	}
+	return nil
+}
+
+func oneofRule(v reflect.Value, param string) error {
+	allowed := strings.Fields(param)
+	var s string
+	switch v.Kind() {
+	case reflect.String:
+		s = v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(v.Int(), 10)
+	default:
+		return BadTag("oneof is not applicable to %s", v.Type())
+	}
+	if !slices.Contains(allowed, s) {
+		return fmt.Errorf("must be one of: %s", strings.Join(allowed, ", "))
+	}
+	return nil
+}
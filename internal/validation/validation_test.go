@@ -0,0 +1,142 @@
+package validation_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"product-catalog/internal/dto"
+	"product-catalog/internal/validation"
+)
+
+type sample struct {
+	Name    string   `json:"name" validate:"required,min=3,max=5"`
+	Email   *string  `json:"email" validate:"email"`
+	Age     int      `json:"age" validate:"omitempty,min=18"`
+	Kind    string   `form:"kind" validate:"oneof=a b"`
+	Tags    []string `json:"tags" validate:"max=2"`
+	Site    string   `json:"site" validate:"omitempty,url"`
+	Flag    *bool    `json:"flag" validate:"required"`
+	Phone   string   `json:"phone" validate:"required_without=Email"`
+	Skipped string
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestStruct(t *testing.T) {
+	valid := sample{Name: "ivan", Email: ptr("ivan@example.com"), Kind: "a", Flag: ptr(false)}
+
+	tests := []struct {
+		name   string
+		modify func(s *sample)
+		want   map[string]string // поле -> правило
+	}{
+		{name: "valid", modify: func(*sample) {}},
+		{name: "whitespace is not a value", modify: func(s *sample) { s.Name = "   " }, want: map[string]string{"name": "required"}},
+		{name: "runes not bytes", modify: func(s *sample) { s.Name = "ёжик" }},
+		{name: "too long", modify: func(s *sample) { s.Name = "abcdef" }, want: map[string]string{"name": "max"}},
+		{name: "nil optional pointer", modify: func(s *sample) { s.Email, s.Phone = nil, "+100" }},
+		{name: "required without", modify: func(s *sample) { s.Email, s.Phone = nil, "" }, want: map[string]string{"phone": "required_without"}},
+		{name: "invalid email", modify: func(s *sample) { s.Email = ptr("Ivan <ivan@example.com>") }, want: map[string]string{"email": "email"}},
+		{name: "omitempty skips zero", modify: func(s *sample) { s.Age = 0 }},
+		{name: "number below min", modify: func(s *sample) { s.Age = 17 }, want: map[string]string{"age": "min"}},
+		{name: "form tag name", modify: func(s *sample) { s.Kind = "c" }, want: map[string]string{"kind": "oneof"}},
+		{name: "slice length", modify: func(s *sample) { s.Tags = []string{"x", "y", "z"} }, want: map[string]string{"tags": "max"}},
+		{name: "relative url", modify: func(s *sample) { s.Site = "/path" }, want: map[string]string{"site": "url"}},
+		{name: "missing required pointer", modify: func(s *sample) { s.Flag = nil }, want: map[string]string{"flag": "required"}},
+		{
+			name:   "all errors reported",
+			modify: func(s *sample) { s.Name, s.Kind, s.Flag, s.Email = "", "", nil, nil },
+			want:   map[string]string{"name": "required", "kind": "oneof", "flag": "required", "phone": "required_without"},
+		},
+	}
+
+	v := validation.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid
+			tt.modify(&s)
+
+			err := v.Struct(&s)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("err = %v, want nil", err)
+				}
+				return
+			}
+			var errs validation.Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("err = %v, want validation.Errors", err)
+			}
+			got := map[string]string{}
+			for _, fe := range errs {
+				got[fe.Field] = fe.Rule
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("errors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomRule(t *testing.T) {
+	v := validation.New()
+	v.Register("even", func(fv reflect.Value, _ string) error {
+		if fv.Kind() != reflect.Int {
+			return validation.BadTag("even is not applicable to %s", fv.Type())
+		}
+		if fv.Int()%2 != 0 {
+			return errors.New("must be even")
+		}
+		return nil
+	})
+
+	type input struct {
+		N *int `json:"n" validate:"required,even"`
+	}
+	if err := v.Struct(input{N: ptr(4)}); err != nil {
+		t.Fatalf("even value: %v", err)
+	}
+	err := v.Struct(input{N: ptr(3)})
+	var errs validation.Errors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Message != "must be even" {
+		t.Fatalf("odd value err = %v", err)
+	}
+
+	type misused struct {
+		S string `validate:"even"`
+	}
+	if err = v.Struct(misused{S: "x"}); err == nil || errors.As(err, &errs) {
+		t.Fatalf("misused rule err = %v, want tag error", err)
+	}
+}
+
+func TestUnknownRule(t *testing.T) {
+	type input struct {
+		Name string `validate:"jsonrequired"`
+	}
+	err := validation.New().Struct(input{})
+	var errs validation.Errors
+	if err == nil || errors.As(err, &errs) || !strings.Contains(err.Error(), "jsonrequired") {
+		t.Fatalf("err = %v, want unknown rule error", err)
+	}
+}
+
+// TestDTOTags ловит опечатки в тегах DTO до первого запроса
+func TestDTOTags(t *testing.T) {
+	v := validation.New()
+	v.Register("filesize", func(reflect.Value, string) error { return nil })
+
+	for _, s := range []any{
+		dto.CreateUserInput{}, dto.LoginInput{}, dto.UpdateUserInput{Username: ptr("x"), Email: ptr("x"), Password: ptr("x")},
+		dto.ChallengeInput{}, dto.TwoFactorLoginInput{}, dto.TwoFactorCodeInput{}, dto.ImpersonateInput{},
+		dto.BulkUserActionInput{}, dto.CreateProductInput{}, dto.UpdateProductInput{ImageKey: ptr("x")}, dto.UploadImageInput{}, dto.SaveRoleInput{},
+		dto.CreateAPIKeyInput{ExpiresInDays: ptr(1)}, dto.CreateInvitationInput{}, dto.AcceptInvitationInput{},
+	} {
+		var errs validation.Errors
+		if err := v.Struct(s); err != nil && !errors.As(err, &errs) {
+			t.Errorf("%T: %v", s, err)
+		}
+	}
+}
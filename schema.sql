@@ -1,21 +1,351 @@
+-- Арендаторы: каталоги отдельных брендов в одной инсталляции
+CREATE TABLE tenants (
+    id SERIAL PRIMARY KEY,
+    slug VARCHAR(50) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    -- Значения заголовка Host, по которым запрос относится к арендатору
+    hosts TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Роли как настраиваемые наборы разрешений; у каждого арендатора свои
+CREATE TABLE roles (
+    tenant_id INTEGER NOT NULL DEFAULT current_setting('app.tenant_id')::int REFERENCES tenants(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (tenant_id, name)
+);
+
+CREATE TABLE role_permissions (
+    tenant_id INTEGER NOT NULL DEFAULT current_setting('app.tenant_id')::int,
+    role TEXT NOT NULL,
+    permission TEXT NOT NULL,
+    PRIMARY KEY (tenant_id, role, permission),
+    FOREIGN KEY (tenant_id, role) REFERENCES roles(tenant_id, name) ON DELETE CASCADE
+);
+
+-- Новый арендатор получает встроенные роли; дальше администратор каталога
+-- меняет их, не затрагивая другие каталоги
+CREATE FUNCTION seed_tenant_roles() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO roles (tenant_id, name, description) VALUES
+        (NEW.id, 'admin', 'Full access'),
+        (NEW.id, 'seller', 'Manages own products'),
+        (NEW.id, 'user', 'Read-only catalog access');
+
+    INSERT INTO role_permissions (tenant_id, role, permission)
+    SELECT NEW.id, p.role, p.permission FROM (VALUES
+        ('admin', 'product:write'),
+        ('admin', 'product:manage'),
+        ('admin', 'category:write'),
+        ('admin', 'user:read'),
+        ('admin', 'user:manage'),
+        ('admin', 'role:manage'),
+        ('admin', 'user:impersonate'),
+        ('seller', 'product:write')
+    ) AS p(role, permission);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tenants_seed_roles
+    AFTER INSERT ON tenants
+    FOR EACH ROW EXECUTE FUNCTION seed_tenant_roles();
+
+INSERT INTO tenants (slug, name) VALUES ('default', 'Default catalog');
+
+-- Таблица пользователей
+CREATE TABLE users (
+    id SERIAL PRIMARY KEY,
+    -- Арендатор берётся из app.tenant_id транзакции; без него вставка невозможна
+    tenant_id INTEGER NOT NULL DEFAULT current_setting('app.tenant_id')::int REFERENCES tenants(id),
+    username VARCHAR(50) NOT NULL,
+    email VARCHAR(100) NOT NULL,
+    password_hash TEXT NOT NULL,
+    role TEXT NOT NULL DEFAULT 'user',
+    -- active, deactivated, deleted (можно восстановить) или erased (обезличен)
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    deleted_at TIMESTAMP,
+    -- Растёт при каждом изменении; отдаётся как ETag для If-Match
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, username),
+    -- Роль ищется только среди ролей своего арендатора
+    FOREIGN KEY (tenant_id, role) REFERENCES roles(tenant_id, name)
+);
+
+-- Email сравнивается без учёта регистра, как при входе
+CREATE UNIQUE INDEX idx_users_email ON users(tenant_id, lower(email));
+CREATE INDEX idx_users_deleted_at ON users(deleted_at) WHERE status = 'deleted';
+-- Поиск по префиксу в справочнике пользователей
+CREATE INDEX idx_users_username_prefix ON users(tenant_id, lower(username) text_pattern_ops);
+CREATE INDEX idx_users_email_prefix ON users(tenant_id, lower(email) text_pattern_ops);
+CREATE INDEX idx_users_created_at ON users(tenant_id, created_at);
+
 -- Таблица карточки продукта
 CREATE TABLE products (
     id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL DEFAULT current_setting('app.tenant_id')::int REFERENCES tenants(id),
     title VARCHAR(100) NOT NULL,
     price INTEGER NOT NULL CHECK (price > 0),
     available BOOLEAN NOT NULL DEFAULT TRUE,
     description TEXT,
     image_url TEXT,
+    created_by INTEGER REFERENCES users(id),
+    -- Публичный каталог показывает только published; переходы проверяет сервис
+    status VARCHAR(20) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'in_review', 'published', 'archived')),
+    -- Расписание приходит от клиентов с часовым поясом, поэтому хранится как момент времени
+    publish_at TIMESTAMPTZ,
+    unpublish_at TIMESTAMPTZ,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    -- Last-Modified товара
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    -- Товар в корзине: скрыт из каталога до восстановления или очистки
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX idx_products_tenant_id ON products(tenant_id);
+CREATE INDEX idx_products_status ON products(tenant_id, status) WHERE deleted_at IS NULL;
+CREATE INDEX idx_products_deleted_at ON products(tenant_id, deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Ревизия каталога арендатора: ETag и Last-Modified списка товаров. Удаление не
+-- оставляет строки в products, поэтому ревизию ведёт триггер.
+CREATE TABLE catalog_revisions (
+    tenant_id INTEGER PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    revision BIGINT NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE FUNCTION bump_catalog_revision() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO catalog_revisions (tenant_id) VALUES (COALESCE(NEW.tenant_id, OLD.tenant_id))
+    ON CONFLICT (tenant_id) DO UPDATE SET revision = catalog_revisions.revision + 1, updated_at = NOW();
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Построчный триггер: изменение, не задевшее ни одной строки (устаревший If-Match),
+-- ревизию не двигает
+CREATE TRIGGER products_catalog_revision
+    AFTER INSERT OR UPDATE OR DELETE ON products
+    FOR EACH ROW EXECUTE FUNCTION bump_catalog_revision();
+
+-- Реплики API держат GetByID в памяти и сбрасывают товар по этому уведомлению.
+-- NOTIFY доставляется только после фиксации транзакции.
+CREATE FUNCTION notify_product_change() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('product_changes', COALESCE(NEW.tenant_id, OLD.tenant_id) || ':' || COALESCE(NEW.id, OLD.id));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER products_notify_change
+    AFTER INSERT OR UPDATE OR DELETE ON products
+    FOR EACH ROW EXECUTE FUNCTION notify_product_change();
+
+-- Неудачные попытки входа (по аккаунту и по IP)
+CREATE TABLE login_attempts (
+    scope TEXT NOT NULL,
+    key TEXT NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMP,
+    PRIMARY KEY (scope, key)
+);
+
+-- Для фоновой очистки устаревших записей
+CREATE INDEX idx_login_attempts_last_failure ON login_attempts(scope, last_failure_at);
+
+-- Двухфакторная аутентификация (TOTP)
+CREATE TABLE user_two_factor (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    enabled_at TIMESTAMP
+);
+
+-- Резервные коды для входа без TOTP
+CREATE TABLE user_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX idx_user_recovery_codes_user_id ON user_recovery_codes(user_id);
+
+-- API-ключи для межсервисного доступа (хранится только SHA-256 ключа)
+CREATE TABLE api_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    prefix VARCHAR(20) NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
     created_at TIMESTAMP NOT NULL DEFAULT NOW()
 );
 
--- Таблица пользователей
-CREATE TABLE users (
+CREATE INDEX idx_api_keys_user_id ON api_keys(user_id);
+
+-- Внешние учётные записи (OIDC), привязанные к пользователям
+CREATE TABLE user_identities (
     id SERIAL PRIMARY KEY,
-    username VARCHAR(50) NOT NULL UNIQUE,
-    email VARCHAR(100) NOT NULL UNIQUE,
-    password_hash TEXT NOT NULL,
-    role TEXT NOT NULL DEFAULT 'user',
+    tenant_id INTEGER NOT NULL DEFAULT current_setting('app.tenant_id')::int REFERENCES tenants(id),
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_login_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    -- Одна учётная запись IdP может входить в несколько каталогов
+    UNIQUE (tenant_id, provider, subject)
+);
+
+CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
+
+-- Сессии входа: access-токены ссылаются на них через claim sid
+CREATE TABLE sessions (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    device VARCHAR(100) NOT NULL,
+    ip VARCHAR(45) NOT NULL,
+    user_agent VARCHAR(512) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    impersonator_id INTEGER REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+
+-- Журнал имперсонации: начало каждой сессии и каждый запрос под ней
+CREATE TABLE impersonation_audit (
+    id SERIAL PRIMARY KEY,
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    session_id VARCHAR(64) NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    method VARCHAR(10) NOT NULL DEFAULT '',
+    path TEXT NOT NULL DEFAULT '',
+    status INTEGER,
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
     created_at TIMESTAMP NOT NULL DEFAULT NOW()
 );
 
+CREATE INDEX idx_impersonation_audit_user_id ON impersonation_audit(user_id);
+CREATE INDEX idx_impersonation_audit_actor_id ON impersonation_audit(actor_id);
+
+-- Выгрузки персональных данных (GDPR): архив лежит в объектном хранилище до expires_at
+CREATE TABLE data_exports (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    object_key TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP
+);
+
+CREATE INDEX idx_data_exports_user_id ON data_exports(user_id);
+-- Одновременно у пользователя готовится не больше одной выгрузки
+CREATE UNIQUE INDEX idx_data_exports_one_pending ON data_exports(user_id) WHERE status = 'pending';
+
+-- Ответы на запросы с заголовком Idempotency-Key: повтор с тем же ключом получает
+-- сохранённый ответ вместо повторного выполнения. Пока первый запрос выполняется,
+-- status_code равен NULL.
+CREATE TABLE idempotency_keys (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    fingerprint VARCHAR(64) NOT NULL,
+    status_code INTEGER,
+    headers JSONB NOT NULL DEFAULT '{}',
+    body BYTEA NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+-- Приглашения: администратор задаёт email и роль, приглашённый — логин и пароль
+CREATE TABLE invitations (
+    id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL DEFAULT current_setting('app.tenant_id')::int REFERENCES tenants(id),
+    email VARCHAR(100) NOT NULL,
+    role TEXT NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    invited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP,
+    accepted_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    revoked_at TIMESTAMP,
+    FOREIGN KEY (tenant_id, role) REFERENCES roles(tenant_id, name)
+);
+
+-- Не больше одного открытого приглашения на email
+CREATE UNIQUE INDEX idx_invitations_open_email ON invitations(tenant_id, lower(email)) WHERE accepted_at IS NULL AND revoked_at IS NULL;
+
+-- Изоляция арендаторов (RLS): строка видна и может быть записана, только если её
+-- tenant_id совпадает с app.tenant_id транзакции. Если app.tenant_id не задан,
+-- условие даёт NULL и запрос не видит ни одной строки.
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON users
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int);
+
+ALTER TABLE products ENABLE ROW LEVEL SECURITY;
+ALTER TABLE products FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON products
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int);
+
+ALTER TABLE catalog_revisions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE catalog_revisions FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON catalog_revisions
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int);
+
+ALTER TABLE roles ENABLE ROW LEVEL SECURITY;
+ALTER TABLE roles FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON roles
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int);
+
+ALTER TABLE role_permissions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE role_permissions FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON role_permissions
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int);
+
+ALTER TABLE user_identities ENABLE ROW LEVEL SECURITY;
+ALTER TABLE user_identities FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON user_identities
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int);
+
+ALTER TABLE invitations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE invitations FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON invitations
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::int);
+
+-- Суперпользователь обходит RLS даже с FORCE, поэтому приложение после подключения
+-- переключается на эту роль (database.app_role). Пользователь подключения должен
+-- быть её членом: GRANT catalog_app TO <user>.
+CREATE ROLE catalog_app NOLOGIN;
+GRANT USAGE ON SCHEMA public TO catalog_app;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO catalog_app;
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO catalog_app;
+-- Арендаторы заводятся миграциями, приложение их только читает
+REVOKE INSERT, UPDATE, DELETE ON tenants FROM catalog_app;